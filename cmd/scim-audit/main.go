@@ -0,0 +1,44 @@
+// scim-audit verifies audit logs written by scim.JSONLAuditSink .
+//
+//	scim-audit verify <audit log file>...
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/suzuki-shunsuke/go-slack-scimapi/scim"
+)
+
+const usage = `usage: scim-audit verify <audit log file>...`
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) < 2 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	code := 0
+	for _, path := range args[1:] {
+		n, err := verify(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s: OK (%d records)\n", path, n)
+	}
+	return code
+}
+
+func verify(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return scim.VerifyAuditLog(f)
+}
@@ -3,6 +3,7 @@ module github.com/suzuki-shunsuke/go-slack-scimapi
 go 1.14
 
 require (
+	github.com/prometheus/client_golang v1.12.2
 	github.com/stretchr/testify v1.7.0
 	gopkg.in/h2non/gock.v1 v1.1.2
 )
@@ -0,0 +1,389 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UserAttributes converts user to a map whose keys are SCIM attribute names.
+// The map is used to look up the user's attributes with LookupAttribute .
+func UserAttributes(user *User) (map[string]interface{}, error) {
+	b, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LookupAttribute returns the value of the attribute path in attrs.
+// attrs should be created by UserAttributes .
+//
+// path is a dot separated SCIM attribute name such as "userName", "name.givenName" and
+// "urn:scim:schemas:extension:enterprise:1.0.manager.managerId".
+// Attribute names are case-insensitive.
+// A multi-valued attribute can be followed by a selector in square brackets.
+// "emails[primary]" selects the primary value, "emails[work]" selects the value whose type is "work",
+// and "emails[0]" selects the first value.
+// If a multi-valued attribute has no selector, the sub attribute of all values are returned as a list.
+//
+// If the attribute isn't found, the second returned value is false.
+func LookupAttribute(attrs map[string]interface{}, path string) (interface{}, bool) {
+	segments, err := parseAttributePath(attrs, path)
+	if err != nil {
+		return nil, false
+	}
+	var v interface{} = attrs
+	for _, seg := range segments {
+		v = lookupSegment(v, seg)
+		if v == nil {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+type attributeSegment struct {
+	name     string
+	selector string
+	hasSel   bool
+}
+
+func parseAttributePath(attrs map[string]interface{}, path string) ([]attributeSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("attribute path is empty")
+	}
+	segments := []attributeSegment{}
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		// the schema urn includes dots, so it is matched with the attrs' keys.
+		urn := ""
+		for k := range attrs {
+			if !strings.HasPrefix(strings.ToLower(k), "urn:") {
+				continue
+			}
+			if len(path) >= len(k) && strings.EqualFold(path[:len(k)], k) && len(k) > len(urn) {
+				if len(path) == len(k) || path[len(k)] == '.' || path[len(k)] == ':' {
+					urn = k
+				}
+			}
+		}
+		if urn == "" {
+			return nil, fmt.Errorf("unknown schema: %s", path)
+		}
+		segments = append(segments, attributeSegment{name: urn})
+		path = path[len(urn):]
+		if path == "" {
+			return segments, nil
+		}
+		path = path[1:]
+	}
+	for _, s := range strings.Split(path, ".") {
+		seg := attributeSegment{name: s}
+		if i := strings.Index(s, "["); i != -1 {
+			if !strings.HasSuffix(s, "]") {
+				return nil, fmt.Errorf("invalid attribute path: %s", s)
+			}
+			seg.name = s[:i]
+			seg.selector = s[i+1 : len(s)-1]
+			seg.hasSel = true
+		}
+		if seg.name == "" {
+			return nil, fmt.Errorf("invalid attribute path: %s", path)
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+func lookupKey(m map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := m[name]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func lookupSegment(v interface{}, seg attributeSegment) interface{} {
+	switch a := v.(type) {
+	case map[string]interface{}:
+		child, ok := lookupKey(a, seg.name)
+		if !ok {
+			return nil
+		}
+		if !seg.hasSel {
+			return child
+		}
+		list, ok := child.([]interface{})
+		if !ok {
+			return nil
+		}
+		return selectValue(list, seg.selector)
+	case []interface{}:
+		// get the sub attribute of all values of the multi-valued attribute
+		ret := []interface{}{}
+		for _, elem := range a {
+			if child := lookupSegment(elem, seg); child != nil {
+				ret = append(ret, child)
+			}
+		}
+		if len(ret) == 0 {
+			return nil
+		}
+		return ret
+	default:
+		return nil
+	}
+}
+
+func selectValue(list []interface{}, selector string) interface{} {
+	i := selectIndex(list, selector)
+	if i == -1 {
+		return nil
+	}
+	return list[i]
+}
+
+// selectIndex returns the index of the value selected by selector, or -1 if no value is selected.
+func selectIndex(list []interface{}, selector string) int {
+	if i, err := strconv.Atoi(selector); err == nil {
+		if i < 0 || i >= len(list) {
+			return -1
+		}
+		return i
+	}
+	for i, elem := range list {
+		m, ok := elem.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(selector, "primary") {
+			if p, ok := lookupKey(m, "primary"); ok && p == true {
+				return i
+			}
+			continue
+		}
+		if t, ok := lookupKey(m, "type"); ok {
+			if s, ok := t.(string); ok && strings.EqualFold(s, selector) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// SetAttribute sets value to the attribute path in attrs.
+// path is the same as LookupAttribute, and missing attributes are created.
+// "emails[primary]" selects or creates the primary value, "emails[work]" selects or creates the value whose type is "work",
+// and "emails[1]" selects the second value or appends a value if the list has only one value.
+// A multi-valued attribute without a selector can't be set except the last segment of path.
+//
+// The schema urn of path is matched with the attrs' keys, SchemaEnterpriseUser, SchemaSlackGuest and
+// urns registered by RegisterUserExtension .
+// The returned attrs can be converted to a user by UserFromAttributes .
+func SetAttribute(attrs map[string]interface{}, path string, value interface{}) error {
+	if attrs == nil {
+		return fmt.Errorf("attrs is nil")
+	}
+	urns := map[string]interface{}{
+		SchemaEnterpriseUser: nil,
+		SchemaSlackGuest:     nil,
+	}
+	for _, urn := range userExtensions.urns() {
+		urns[urn] = nil
+	}
+	for k := range attrs {
+		urns[k] = nil
+	}
+	segments, err := parseAttributePath(urns, path)
+	if err != nil {
+		return err
+	}
+	m := attrs
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		key := seg.name
+		for k := range m {
+			if strings.EqualFold(k, seg.name) {
+				key = k
+				break
+			}
+		}
+		if last && !seg.hasSel {
+			m[key] = value
+			return nil
+		}
+		if !seg.hasSel {
+			child, ok := m[key].(map[string]interface{})
+			if !ok {
+				if m[key] != nil {
+					return fmt.Errorf("%s isn't a complex attribute: %s", seg.name, path)
+				}
+				child = map[string]interface{}{}
+				m[key] = child
+			}
+			m = child
+			continue
+		}
+		list, ok := m[key].([]interface{})
+		if !ok && m[key] != nil {
+			return fmt.Errorf("%s isn't a multi-valued attribute: %s", seg.name, path)
+		}
+		index, err := selectOrAppend(&list, seg.selector)
+		m[key] = list
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, path)
+		}
+		if last {
+			list[index] = value
+			return nil
+		}
+		child, ok := list[index].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s[%s] isn't a complex attribute: %s", seg.name, seg.selector, path)
+		}
+		m = child
+	}
+	return nil
+}
+
+// selectOrAppend returns the index of the value selected by selector, and appends a new value if no value is selected.
+func selectOrAppend(list *[]interface{}, selector string) (int, error) {
+	if i, err := strconv.Atoi(selector); err == nil {
+		switch {
+		case i >= 0 && i < len(*list):
+			return i, nil
+		case i == len(*list):
+			*list = append(*list, map[string]interface{}{})
+			return i, nil
+		default:
+			return 0, fmt.Errorf("index %d is out of range", i)
+		}
+	}
+	if i := selectIndex(*list, selector); i != -1 {
+		return i, nil
+	}
+	elem := map[string]interface{}{}
+	if strings.EqualFold(selector, "primary") {
+		elem["primary"] = true
+	} else {
+		elem["type"] = selector
+	}
+	*list = append(*list, elem)
+	return len(*list) - 1, nil
+}
+
+// isStringAttribute returns true if the attribute of the path is a string field of User
+// or a typed schema extension registered by RegisterUserExtension .
+// If the type of the attribute is unknown, isStringAttribute returns false.
+func isStringAttribute(path string) bool {
+	segments, err := parseUserAttributePath(path)
+	if err != nil {
+		return false
+	}
+	types := userAttributeTypes(segments)
+	if types == nil {
+		return false
+	}
+	t := types[len(types)-1]
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+// parseUserAttributePath parses the attribute path of a user.
+// The schema urn of path is matched with SchemaEnterpriseUser, SchemaSlackGuest and urns registered by RegisterUserExtension .
+func parseUserAttributePath(path string) ([]attributeSegment, error) {
+	urns := map[string]interface{}{
+		SchemaEnterpriseUser: nil,
+		SchemaSlackGuest:     nil,
+	}
+	for _, urn := range userExtensions.urns() {
+		urns[urn] = nil
+	}
+	return parseAttributePath(urns, path)
+}
+
+// userAttributeTypes returns the Go types of segments of a user's attribute path.
+// If the type of a segment is unknown, userAttributeTypes returns nil.
+func userAttributeTypes(segments []attributeSegment) []reflect.Type {
+	types := make([]reflect.Type, len(segments))
+	t := reflect.TypeOf(User{})
+	start := 0
+	if factory, ok := userExtensions.get(segments[0].name); ok {
+		t = reflect.TypeOf(factory())
+		types[0] = t
+		start = 1
+	}
+	for i := start; i < len(segments); i++ {
+		seg := segments[i]
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		field, ok := jsonField(t, seg.name)
+		if !ok {
+			return nil
+		}
+		t = field.Type
+		types[i] = t
+	}
+	return types
+}
+
+// jsonField returns the field of the struct type whose JSON key is name. Keys are compared case-insensitively.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		key := strings.Split(tag, ",")[0]
+		if key == "" {
+			key = field.Name
+		}
+		if strings.EqualFold(key, name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// UserFromAttributes converts attrs created by UserAttributes or SetAttribute to a user.
+// The schema urns of extensions in attrs are added to Schemas.
+func UserFromAttributes(attrs map[string]interface{}) (*User, error) {
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, err
+	}
+	user := &User{}
+	if err := json.Unmarshal(b, user); err != nil {
+		return nil, err
+	}
+	if user.EnterpriseUserSchemaExtension != nil {
+		user.Schemas = appendSchema(user.Schemas, SchemaEnterpriseUser)
+	}
+	if user.SlackGuestUserSchemaExtension != nil {
+		user.Schemas = appendSchema(user.Schemas, SchemaSlackGuest)
+	}
+	for urn := range user.Extensions {
+		user.Schemas = appendSchema(user.Schemas, urn)
+	}
+	if len(user.Schemas) == 0 {
+		user.Schemas = []string{SchemaCore}
+	}
+	return user, nil
+}
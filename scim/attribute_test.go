@@ -0,0 +1,76 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupAttribute(t *testing.T) {
+	attrs, err := UserAttributes(&testUser)
+	require.Nil(t, err)
+	data := []struct {
+		path string
+		exp  interface{}
+		ok   bool
+	}{
+		{path: "userName", exp: "other_username", ok: true},
+		{path: "USERNAME", exp: "other_username", ok: true},
+		{path: "active", exp: true, ok: true},
+		{path: "name.givenName", exp: "First", ok: true},
+		{path: "emails[primary].value", exp: "some@example.com", ok: true},
+		{path: "emails[home].value", exp: "some_other@example.com", ok: true},
+		{path: "emails[1].type", exp: "home", ok: true},
+		{path: "emails.value", exp: []interface{}{"some@example.com", "some_other@example.com"}, ok: true},
+		{path: "urn:scim:schemas:extension:enterprise:1.0.department", exp: "Tour Operations", ok: true},
+		{path: "urn:scim:schemas:extension:enterprise:1.0.manager.managerId", exp: "U0XE15NHQ", ok: true},
+		{path: "urn:scim:schemas:extension:enterprise:1.0:division", exp: "Wrigley Field", ok: true},
+		{path: "groups[0].value", exp: "YYYYYYYYY", ok: true},
+		{path: ""},
+		{path: "foo"},
+		{path: "name.foo"},
+		{path: "emails[2].value"},
+		{path: "emails[fax].value"},
+		{path: "emails[primary"},
+		{path: "urn:foo.bar"},
+		{path: "userName.foo"},
+	}
+	for _, d := range data {
+		v, ok := LookupAttribute(attrs, d.path)
+		require.Equal(t, d.ok, ok, d.path)
+		require.Equal(t, d.exp, v, d.path)
+	}
+}
+
+func TestSetAttribute(t *testing.T) {
+	attrs := map[string]interface{}{}
+	require.Nil(t, SetAttribute(attrs, "userName", "alice"))
+	require.Nil(t, SetAttribute(attrs, "name.givenName", "Alice"))
+	require.Nil(t, SetAttribute(attrs, "emails[primary].value", "alice@example.com"))
+	require.Nil(t, SetAttribute(attrs, "emails[home].value", "alice@example.net"))
+	require.Nil(t, SetAttribute(attrs, "emails[PRIMARY].type", "work"))
+	require.Nil(t, SetAttribute(attrs, "phoneNumbers[0].value", "000-0000"))
+	require.Nil(t, SetAttribute(attrs, "urn:scim:schemas:extension:enterprise:1.0.department", "Sales"))
+	require.Nil(t, SetAttribute(attrs, "urn:scim:schemas:extension:enterprise:1.0.manager.managerId", "U1"))
+	require.NotNil(t, SetAttribute(attrs, "phoneNumbers[2].value", "111-1111"))
+	require.NotNil(t, SetAttribute(attrs, "userName.foo", "bar"))
+	require.NotNil(t, SetAttribute(attrs, "urn:foo.bar", "baz"))
+	require.NotNil(t, SetAttribute(attrs, "", "baz"))
+
+	user, err := UserFromAttributes(attrs)
+	require.Nil(t, err)
+	require.Equal(t, &User{
+		UserName: "alice",
+		Name:     &Name{GivenName: "Alice"},
+		Emails: []Email{
+			{Value: "alice@example.com", Primary: true, Type: "work"},
+			{Value: "alice@example.net", Type: "home"},
+		},
+		PhoneNumbers: []PhoneNumber{{Value: "000-0000"}},
+		Schemas:      []string{SchemaCore, SchemaEnterpriseUser},
+		EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+			Department: "Sales",
+			Manager:    &Manager{ManagerID: "U1"},
+		},
+	}, user)
+}
@@ -0,0 +1,362 @@
+package scim
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AuditRedacted replaces values of redacted fields.
+	AuditRedacted = "REDACTED"
+)
+
+type (
+	// AuditRecord is a record of a mutation.
+	AuditRecord struct {
+		// Seq is the sequence number of the record in the log. Seq starts with 1.
+		Seq          int64     `json:"seq"`
+		Time         time.Time `json:"time"`
+		Actor        string    `json:"actor"`
+		Operation    string    `json:"operation"`
+		ResourceType string    `json:"resourceType"`
+		ResourceID   string    `json:"resourceId,omitempty"`
+		// Request is the redacted request body.
+		Request json.RawMessage `json:"request,omitempty"`
+		// PreImage is the redacted user or group before the mutation.
+		PreImage   json.RawMessage `json:"preImage,omitempty"`
+		StatusCode int             `json:"statusCode,omitempty"`
+		Error      string          `json:"error,omitempty"`
+		// Refused is true if the mutation was refused by MutationGuard and the request wasn't sent.
+		Refused bool `json:"refused,omitempty"`
+		// PrevHash is the hash of the previous record. PrevHash of the first record is empty.
+		PrevHash string `json:"prevHash"`
+		// Hash is the SHA-256 hash of PrevHash and the record without Hash.
+		Hash string `json:"hash,omitempty"`
+	}
+
+	// AuditSink stores audit records.
+	AuditSink interface {
+		Write(ctx context.Context, record *AuditRecord) error
+	}
+
+	// AuditOption is an option of NewAuditLogger .
+	AuditOption struct {
+		// Actor is the default actor of records.
+		// The actor can be set for each mutation by WithAuditActor .
+		Actor string
+		// RedactFields is names of fields which are redacted in Request and PreImage.
+		// Names are compared case-insensitively at any depth.
+		// If RedactFields is nil, DefaultAuditRedactFields is used.
+		RedactFields []string
+	}
+
+	// AuditLogger records mutations to the sink.
+	// AuditLogger should be created by the function NewAuditLogger,
+	// and AuditLogger.Guard and AuditLogger.Hook should be set to the client.
+	AuditLogger struct {
+		sink   AuditSink
+		actor  string
+		redact map[string]struct{}
+		now    func() time.Time
+		err    error
+		mutex  sync.Mutex
+	}
+
+	// JSONLAuditSink is an append-only AuditSink which writes records as JSON lines.
+	// Records are hash-chained, so tampering is detected by VerifyAuditLog .
+	// JSONLAuditSink is safe for concurrent use.
+	JSONLAuditSink struct {
+		w        io.Writer
+		seq      int64
+		prevHash string
+		mutex    sync.Mutex
+	}
+
+	// AuditVerifyError is returned by VerifyAuditLog when the log is broken.
+	AuditVerifyError struct {
+		// Line is the line number of the broken record. Line starts with 1.
+		Line   int
+		Reason string
+	}
+
+	auditActorKey struct{}
+)
+
+var (
+	// DefaultAuditRedactFields is the default names of fields which are redacted in audit records.
+	// Audit records should show what was changed, so only password is redacted by default.
+	// To redact contact information too, add ContactRedactFields to AuditOption.RedactFields .
+	DefaultAuditRedactFields = []string{"password"}
+
+	// ContactRedactFields is names of fields of contact information, that is, emails, phone numbers, and addresses.
+	ContactRedactFields = []string{"emails", "phoneNumbers", "addresses"}
+)
+
+// Error returns the error message.
+func (e *AuditVerifyError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// WithAuditActor returns a copy of ctx with the actor.
+// Mutations with the returned context are recorded with the actor.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// NewAuditLogger returns an audit logger which writes records to sink.
+func NewAuditLogger(sink AuditSink, opts *AuditOption) *AuditLogger {
+	if opts == nil {
+		opts = &AuditOption{}
+	}
+	fields := opts.RedactFields
+	if fields == nil {
+		fields = DefaultAuditRedactFields
+	}
+	return &AuditLogger{
+		sink:   sink,
+		actor:  opts.Actor,
+		redact: newRedactFields(fields),
+		now:    time.Now,
+	}
+}
+
+// Guard gets the pre-image of the mutation. Guard is a MutationGuard .
+// If the logger failed to write a record, Guard refuses further mutations and returns the error.
+func (logger *AuditLogger) Guard(ctx context.Context, mutation *Mutation) error {
+	if err := logger.Err(); err != nil {
+		return fmt.Errorf("the audit log is unavailable: %w", err)
+	}
+	if _, err := mutation.PreImage(ctx); err != nil {
+		return fmt.Errorf("failed to get the pre-image for the audit log: %w", err)
+	}
+	return nil
+}
+
+// Hook writes a record of the mutation to the sink. Hook is a MutationHook .
+// The error of the sink can be got by AuditLogger.Err .
+func (logger *AuditLogger) Hook(ctx context.Context, mutation *Mutation) {
+	record, err := logger.newRecord(ctx, mutation)
+	if err == nil {
+		err = logger.sink.Write(ctx, record)
+	}
+	if err != nil {
+		logger.mutex.Lock()
+		if logger.err == nil {
+			logger.err = err
+		}
+		logger.mutex.Unlock()
+	}
+}
+
+// Err returns the first error which occurred in writing records.
+func (logger *AuditLogger) Err() error {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	return logger.err
+}
+
+func (logger *AuditLogger) newRecord(ctx context.Context, mutation *Mutation) (*AuditRecord, error) {
+	actor, ok := ctx.Value(auditActorKey{}).(string)
+	if !ok {
+		actor = logger.actor
+	}
+	record := &AuditRecord{
+		Time:         logger.now().UTC(),
+		Actor:        actor,
+		Operation:    mutation.Operation,
+		ResourceType: mutation.ResourceType,
+		ResourceID:   mutation.ResourceID,
+		StatusCode:   mutation.StatusCode,
+		Refused:      mutation.Refused,
+	}
+	if mutation.Err != nil {
+		record.Error = mutation.Err.Error()
+	}
+	request, err := logger.redactJSON(mutation.Request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the request: %w", err)
+	}
+	record.Request = request
+	preImage, err := mutation.PreImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	record.PreImage, err = logger.redactJSON(preImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the pre-image: %w", err)
+	}
+	return record, nil
+}
+
+// redactJSON encodes v and replaces values of redacted fields.
+func (logger *AuditLogger) redactJSON(v interface{}) (json.RawMessage, error) {
+	if isNil(v) {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactValue(data, logger.redact))
+}
+
+// newRedactFields returns the set of names of fields which are redacted by redactValue.
+func newRedactFields(fields []string) map[string]struct{} {
+	redact := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		redact[strings.ToLower(field)] = struct{}{}
+	}
+	return redact
+}
+
+// redactValue replaces values of fields at any depth of v with AuditRedacted.
+// Names of fields are compared case-insensitively.
+func redactValue(v interface{}, fields map[string]struct{}) interface{} {
+	switch a := v.(type) {
+	case map[string]interface{}:
+		for k, val := range a {
+			if _, ok := fields[strings.ToLower(k)]; ok {
+				a[k] = AuditRedacted
+				continue
+			}
+			a[k] = redactValue(val, fields)
+		}
+	case []interface{}:
+		for i, val := range a {
+			a[i] = redactValue(val, fields)
+		}
+	}
+	return v
+}
+
+// NewJSONLAuditSink returns a sink which writes records to w.
+// w should be empty. To append records to an existing log file, use OpenJSONLAuditSink instead.
+func NewJSONLAuditSink(w io.Writer) *JSONLAuditSink {
+	return &JSONLAuditSink{
+		w: w,
+	}
+}
+
+// OpenJSONLAuditSink opens the log file in the append mode and returns a sink which continues the hash chain.
+// If the file doesn't exist, the file is created.
+// The existing records are verified, and an error is returned if the log is broken.
+// The returned io.Closer closes the file.
+func OpenJSONLAuditSink(path string) (*JSONLAuditSink, io.Closer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	last, err := verifyAuditLog(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("the audit log %s is broken: %w", path, err)
+	}
+	sink := NewJSONLAuditSink(f)
+	if last != nil {
+		sink.seq = last.Seq
+		sink.prevHash = last.Hash
+	}
+	return sink, f, nil
+}
+
+// Write sets Seq, PrevHash, and Hash to record and writes the record as a JSON line.
+func (sink *JSONLAuditSink) Write(ctx context.Context, record *AuditRecord) error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	record.Seq = sink.seq + 1
+	record.PrevHash = sink.prevHash
+	hash, err := hashAuditRecord(record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := sink.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	sink.seq = record.Seq
+	sink.prevHash = hash
+	return nil
+}
+
+// hashAuditRecord returns the hex encoded SHA-256 hash of the record without Hash.
+// PrevHash is included in the record, so the hash depends on all previous records.
+func hashAuditRecord(record *AuditRecord) (string, error) {
+	r := *record
+	r.Hash = ""
+	b, err := json.Marshal(&r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyAuditLog verifies the hash chain of the log written by JSONLAuditSink and returns the number of records.
+// If a record is modified, removed, inserted, or reordered, *AuditVerifyError is returned.
+// Note that removing records from the end of the log can't be detected by the log itself,
+// so compare the returned number or the last hash with a trusted copy.
+func VerifyAuditLog(r io.Reader) (int64, error) {
+	last, err := verifyAuditLog(r)
+	if err != nil || last == nil {
+		return 0, err
+	}
+	return last.Seq, nil
+}
+
+func verifyAuditLog(r io.Reader) (*AuditRecord, error) {
+	reader := bufio.NewReader(r)
+	var last *AuditRecord
+	prevHash := ""
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(b)) == 0 {
+			if err == io.EOF {
+				return last, nil
+			}
+			return nil, &AuditVerifyError{Line: line, Reason: "empty line"}
+		}
+		record := &AuditRecord{}
+		if e := json.Unmarshal(b, record); e != nil {
+			return nil, &AuditVerifyError{Line: line, Reason: fmt.Sprintf("invalid JSON: %v", e)}
+		}
+		if record.Seq != int64(line) {
+			return nil, &AuditVerifyError{Line: line, Reason: fmt.Sprintf("seq is %d", record.Seq)}
+		}
+		if record.PrevHash != prevHash {
+			return nil, &AuditVerifyError{Line: line, Reason: "prevHash doesn't match the hash of the previous record"}
+		}
+		hash, e := hashAuditRecord(record)
+		if e != nil {
+			return nil, e
+		}
+		if record.Hash != hash {
+			return nil, &AuditVerifyError{Line: line, Reason: "hash doesn't match the record"}
+		}
+		prevHash = hash
+		last = record
+		if err == io.EOF {
+			return last, nil
+		}
+	}
+}
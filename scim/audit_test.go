@@ -0,0 +1,253 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogger(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	buf := &bytes.Buffer{}
+	logger := NewAuditLogger(NewJSONLAuditSink(buf), &AuditOption{Actor: "bot"})
+	logger.now = func() time.Time {
+		return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	client := NewClient("XXX").
+		WithMutationGuard(logger.Guard).
+		WithMutationHook(logger.Hook)
+
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(201).
+		BodyString(`{"id": "U1", "userName": "foo"}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U1").
+		Reply(200).
+		BodyString(`{"id": "U1", "userName": "foo", "active": true}`)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U1").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+
+	_, _, err := client.CreateUser(ctx, &User{UserName: "foo", Password: "secret"})
+	require.Nil(t, err)
+	_, err = client.DeleteUser(WithAuditActor(ctx, "alice"), "U1")
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+	require.Nil(t, logger.Err())
+
+	require.NotContains(t, buf.String(), "secret")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	records := make([]AuditRecord, len(lines))
+	for i, line := range lines {
+		require.Nil(t, json.Unmarshal([]byte(line), &records[i]))
+	}
+	require.Equal(t, int64(1), records[0].Seq)
+	require.Equal(t, "bot", records[0].Actor)
+	require.Equal(t, MutationCreate, records[0].Operation)
+	require.Equal(t, "U1", records[0].ResourceID)
+	require.Equal(t, 201, records[0].StatusCode)
+	require.JSONEq(t, `{"schemas": null, "userName": "foo", "password": "REDACTED", "groups": []}`, string(records[0].Request))
+	require.Nil(t, records[0].PreImage)
+	require.Equal(t, "", records[0].PrevHash)
+
+	require.Equal(t, int64(2), records[1].Seq)
+	require.Equal(t, "alice", records[1].Actor)
+	require.Equal(t, 500, records[1].StatusCode)
+	require.NotEqual(t, "", records[1].Error)
+	require.JSONEq(t, `{"schemas": null, "id": "U1", "userName": "foo", "active": true, "groups": []}`, string(records[1].PreImage))
+	require.Equal(t, records[0].Hash, records[1].PrevHash)
+
+	n, err := VerifyAuditLog(bytes.NewReader(buf.Bytes()))
+	require.Nil(t, err)
+	require.Equal(t, int64(2), n)
+}
+
+func TestAuditLogger_rawResponseAndRefused(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	buf := &bytes.Buffer{}
+	logger := NewAuditLogger(NewJSONLAuditSink(buf), &AuditOption{
+		RedactFields: append(append([]string{}, DefaultAuditRedactFields...), ContactRedactFields...),
+	})
+	policy := NewSafetyPolicy(&SafetyConfig{ProtectedUserIDs: []string{"U0"}})
+	client := NewClient("XXX").
+		WithMutationGuard(MutationGuards(policy.Guard, logger.Guard)).
+		WithMutationHook(logger.Hook)
+
+	// refused mutations are recorded
+	_, err := client.DeleteUser(ctx, "U0")
+	require.True(t, errors.Is(err, ErrSafetyViolation))
+
+	// mutations by methods which return the raw response are recorded
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U1").
+		Reply(200).
+		BodyString(`{"id": "U1", "emails": [{"value": "old@example.com"}], "phoneNumbers": [{"value": "555-0100"}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U1").
+		Reply(409).
+		BodyString(`{"Errors": {"description": "email_taken", "code": 409}}`)
+	resp, err := client.PatchUserResp(ctx, "U1", &UserPatch{Emails: []Email{{Value: "new@example.com"}}})
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	// the response body can be read after the hook
+	b, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Contains(t, string(b), "email_taken")
+	require.True(t, gock.IsDone())
+	require.Nil(t, logger.Err())
+
+	// emails and phone numbers are redacted if ContactRedactFields is added
+	require.NotContains(t, buf.String(), "@example.com")
+	require.NotContains(t, buf.String(), "555-0100")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	records := make([]AuditRecord, len(lines))
+	for i, line := range lines {
+		require.Nil(t, json.Unmarshal([]byte(line), &records[i]))
+	}
+	require.True(t, records[0].Refused)
+	require.Equal(t, MutationDelete, records[0].Operation)
+	require.Equal(t, "U0", records[0].ResourceID)
+	require.Contains(t, records[0].Error, "protected")
+	require.False(t, records[1].Refused)
+	require.Equal(t, MutationPatch, records[1].Operation)
+	require.Equal(t, 409, records[1].StatusCode)
+	require.Contains(t, records[1].Error, "email_taken")
+	require.JSONEq(t, `{"schemas": null, "emails": "REDACTED"}`, string(records[1].Request))
+}
+
+func TestAuditLogger_redactJSON(t *testing.T) {
+	// only password is redacted by default
+	logger := NewAuditLogger(nil, nil)
+	b, err := logger.redactJSON(&User{
+		UserName: "foo",
+		Password: "secret",
+		Emails:   []Email{{Value: "foo@example.com"}},
+	})
+	require.Nil(t, err)
+	require.NotContains(t, string(b), "secret")
+	require.Contains(t, string(b), "foo@example.com")
+}
+
+type errAuditSink struct{}
+
+func (sink *errAuditSink) Write(ctx context.Context, record *AuditRecord) error {
+	return errors.New("disk full")
+}
+
+func TestAuditLogger_Err(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	logger := NewAuditLogger(&errAuditSink{}, nil)
+	client := NewClient("XXX").
+		WithMutationGuard(logger.Guard).
+		WithMutationHook(logger.Hook)
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(201).
+		BodyString(`{"id": "U1"}`)
+	_, _, err := client.CreateUser(ctx, &User{UserName: "foo"})
+	require.Nil(t, err)
+	require.NotNil(t, logger.Err())
+
+	// further mutations are refused
+	_, _, err = client.CreateUser(ctx, &User{UserName: "bar"})
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	ctx := context.Background()
+	buf := &bytes.Buffer{}
+	sink := NewJSONLAuditSink(buf)
+	for _, id := range []string{"U1", "U2", "U3"} {
+		require.Nil(t, sink.Write(ctx, &AuditRecord{
+			Operation:    MutationDelete,
+			ResourceType: ResourceTypeUser,
+			ResourceID:   id,
+		}))
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	n, err := VerifyAuditLog(strings.NewReader(""))
+	require.Nil(t, err)
+	require.Equal(t, int64(0), n)
+
+	data := []struct {
+		title string
+		log   string
+		line  int
+	}{
+		{
+			title: "modified",
+			log:   lines[0] + strings.Replace(lines[1], "U2", "U4", 1) + lines[2],
+			line:  2,
+		},
+		{
+			title: "removed",
+			log:   lines[0] + lines[2],
+			line:  2,
+		},
+		{
+			title: "reordered",
+			log:   lines[1] + lines[0] + lines[2],
+			line:  1,
+		},
+		{
+			title: "invalid JSON",
+			log:   lines[0] + "foo\n",
+			line:  2,
+		},
+	}
+	for _, d := range data {
+		t.Run(d.title, func(t *testing.T) {
+			_, err := VerifyAuditLog(strings.NewReader(d.log))
+			verifyErr := &AuditVerifyError{}
+			require.True(t, errors.As(err, &verifyErr))
+			require.Equal(t, d.line, verifyErr.Line)
+		})
+	}
+}
+
+func TestOpenJSONLAuditSink(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.jsonl")
+
+	for i := 0; i < 2; i++ {
+		sink, closer, err := OpenJSONLAuditSink(path)
+		require.Nil(t, err)
+		require.Nil(t, sink.Write(ctx, &AuditRecord{Operation: MutationCreate}))
+		require.Nil(t, closer.Close())
+	}
+	b, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	n, err := VerifyAuditLog(bytes.NewReader(b))
+	require.Nil(t, err)
+	require.Equal(t, int64(2), n)
+
+	require.Nil(t, ioutil.WriteFile(path, append(b, "foo\n"...), 0600))
+	_, _, err = OpenJSONLAuditSink(path)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,147 @@
+package scim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type (
+	// Checkpoint persists progress of a long-running operation to a local file so that the operation can be resumed.
+	// The file is append-only JSON lines, so progress recorded before a crash isn't lost.
+	// Checkpoint should be created by the function OpenCheckpoint .
+	// Checkpoint is safe for concurrent use.
+	Checkpoint struct {
+		file       *os.File
+		processed  map[string]struct{}
+		startIndex int
+		mutex      sync.Mutex
+	}
+
+	checkpointRecord struct {
+		ID         string `json:"id,omitempty"`
+		StartIndex int    `json:"startIndex,omitempty"`
+	}
+)
+
+// OpenCheckpoint opens the checkpoint file and loads the recorded progress.
+// If the file doesn't exist, the file is created.
+// The checkpoint should be closed by Checkpoint.Close .
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{
+		file:      f,
+		processed: map[string]struct{}{},
+	}
+	if err := cp.load(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("the checkpoint %s is broken: %w", path, err)
+	}
+	return cp, nil
+}
+
+// load reads records from f.
+// If the last line is partially written by a crash, the line is truncated.
+func (cp *Checkpoint) load(f *os.File) error {
+	reader := bufio.NewReader(f)
+	var size int64
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF {
+			if len(b) == 0 {
+				return nil
+			}
+			return f.Truncate(size)
+		}
+		size += int64(len(b))
+		if len(bytes.TrimSpace(b)) == 0 {
+			continue
+		}
+		record := &checkpointRecord{}
+		if err := json.Unmarshal(b, record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		cp.apply(record)
+	}
+}
+
+func (cp *Checkpoint) apply(record *checkpointRecord) {
+	if record.ID != "" {
+		cp.processed[record.ID] = struct{}{}
+	}
+	if record.StartIndex != 0 {
+		cp.startIndex = record.StartIndex
+	}
+}
+
+// StartIndex returns the recorded start index of the next page.
+// If no start index is recorded, 1 is returned.
+func (cp *Checkpoint) StartIndex() int {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	if cp.startIndex == 0 {
+		return 1
+	}
+	return cp.startIndex
+}
+
+// SetStartIndex records the start index of the next page.
+// The start index should be recorded after all resources of the previous pages are processed.
+func (cp *Checkpoint) SetStartIndex(startIndex int) error {
+	if startIndex < 1 {
+		return fmt.Errorf("startIndex must be positive")
+	}
+	return cp.write(&checkpointRecord{StartIndex: startIndex})
+}
+
+// Done returns true if the id is recorded as processed.
+func (cp *Checkpoint) Done(id string) bool {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	_, ok := cp.processed[id]
+	return ok
+}
+
+// Processed returns the number of processed ids.
+func (cp *Checkpoint) Processed() int {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	return len(cp.processed)
+}
+
+// MarkDone records the id as processed.
+func (cp *Checkpoint) MarkDone(id string) error {
+	if id == "" {
+		return fmt.Errorf("id is required")
+	}
+	return cp.write(&checkpointRecord{ID: id})
+}
+
+func (cp *Checkpoint) write(record *checkpointRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	if _, err := cp.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	cp.apply(record)
+	return nil
+}
+
+// Close closes the checkpoint file.
+func (cp *Checkpoint) Close() error {
+	return cp.file.Close()
+}
@@ -0,0 +1,53 @@
+package scim
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint.jsonl")
+
+	cp, err := OpenCheckpoint(path)
+	require.Nil(t, err)
+	require.False(t, cp.Done("U1"))
+	require.Nil(t, cp.MarkDone("U1"))
+	require.Nil(t, cp.MarkDone("U2"))
+	require.NotNil(t, cp.MarkDone(""))
+	require.True(t, cp.Done("U1"))
+	require.Equal(t, 2, cp.Processed())
+	require.Equal(t, 1, cp.StartIndex())
+	require.Nil(t, cp.SetStartIndex(3))
+	require.NotNil(t, cp.SetStartIndex(0))
+	require.Nil(t, cp.Close())
+
+	// simulate a crash while writing the last line
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.Nil(t, err)
+	_, err = f.WriteString(`{"id":"U`)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	cp, err = OpenCheckpoint(path)
+	require.Nil(t, err)
+	require.True(t, cp.Done("U1"))
+	require.True(t, cp.Done("U2"))
+	require.Equal(t, 3, cp.StartIndex())
+	require.Nil(t, cp.MarkDone("U3"))
+	require.Nil(t, cp.Close())
+
+	b, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, "{\"id\":\"U1\"}\n{\"id\":\"U2\"}\n{\"startIndex\":3}\n{\"id\":\"U3\"}\n", string(b))
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("foo\n{\"id\":\"U1\"}\n"), 0600))
+	_, err = OpenCheckpoint(path)
+	require.NotNil(t, err)
+}
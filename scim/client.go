@@ -15,11 +15,16 @@ type (
 	// Client should be created by the function NewClient .
 	Client struct {
 		endpoint       string
-		token          string
+		tokenSource    TokenSource
 		httpClient     *http.Client
 		isError        IsError
 		parseResp      ParseResp
 		parseErrorResp ParseErrorResp
+		mutationHook   MutationHook
+		mutationGuard  MutationGuard
+		metricsHook    MetricsHook
+		tracer         Tracer
+		requestLogger  *RequestLogger
 	}
 
 	// ParseResp parses a succeeded API response.
@@ -36,9 +41,19 @@ var (
 )
 
 // NewClient returns a new client.
+// To rotate the token without recreating the client, use NewClientWithTokenSource instead.
 func NewClient(token string) *Client {
+	return NewClientWithTokenSource(NewStaticTokenSource(token))
+}
+
+// NewClientWithTokenSource returns a new client which gets a token from src at every request.
+// If src is nil, an empty token is used.
+func NewClientWithTokenSource(src TokenSource) *Client {
+	if src == nil {
+		src = NewStaticTokenSource("")
+	}
 	return &Client{
-		token:          token,
+		tokenSource:    src,
 		endpoint:       DefaultEndpoint,
 		httpClient:     http.DefaultClient,
 		isError:        IsErrorDefault,
@@ -47,9 +62,19 @@ func NewClient(token string) *Client {
 	}
 }
 
+// getResp sends the request.
+// operation is the name of the method such as "GetUsers", which is used as a label of metrics and the name of the span.
 func (c *Client) getResp(
-	ctx context.Context, method, path string, body interface{}, query url.Values,
-) (*http.Response, error) {
+	ctx context.Context, operation, method, path string, body interface{}, query url.Values,
+) (resp *http.Response, err error) {
+	ctx, span, owned := c.startOperationSpan(ctx, operation, method, path, query)
+	defer func() {
+		setSpanResult(span, resp, err, c.isError)
+		if owned {
+			span.End()
+		}
+	}()
+
 	endpoint, err := url.Parse(c.endpoint)
 	if err != nil {
 		return nil, err
@@ -57,20 +82,54 @@ func (c *Client) getResp(
 
 	endpoint.Path = filepath.Join(endpoint.Path, path)
 	endpoint.RawQuery = query.Encode()
-	var req *http.Request
-	if body == nil {
-		req, err = http.NewRequest(method, endpoint.String(), nil)
-	} else {
-		reqBody := &bytes.Buffer{}
-		if err := json.NewEncoder(reqBody).Encode(body); err != nil {
+	var reqBody []byte
+	if body != nil {
+		buf := &bytes.Buffer{}
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
 			return nil, err
 		}
-		req, err = http.NewRequest(method, endpoint.String(), reqBody)
+		reqBody = buf.Bytes()
+	}
+	token, err := c.tokenSource.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = c.tracedDo(ctx, operation, method, endpoint.String(), reqBody, token, 1, "")
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// the token may be rotated, so refresh the token and retry the request once
+	newToken, err := c.tokenSource.Refresh(ctx)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if newToken == token {
+		return resp, nil
+	}
+	resp.Body.Close()
+	if c.metricsHook != nil {
+		c.metricsHook.ObserveRetry(ctx, operation, RetryReasonTokenRefreshed)
+	}
+	return c.tracedDo(ctx, operation, method, endpoint.String(), reqBody, newToken, 2, RetryReasonTokenRefreshed)
+}
+
+func (c *Client) do(
+	ctx context.Context, method, endpoint string, body []byte, token string,
+) (*http.Response, error) {
+	var (
+		req *http.Request
+		err error
+	)
+	if body == nil {
+		req, err = http.NewRequest(method, endpoint, nil)
+	} else {
+		req, err = http.NewRequest(method, endpoint, bytes.NewReader(body))
 	}
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Add("Content-Type", "application/json")
 	req = req.WithContext(ctx)
 	return c.httpClient.Do(req)
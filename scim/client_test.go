@@ -1,11 +1,66 @@
 package scim
 
 import (
+	"context"
 	"testing"
 
+	"gopkg.in/h2non/gock.v1"
+
 	"github.com/stretchr/testify/require"
 )
 
+type testTokenSource struct {
+	tokens []string
+}
+
+func (src *testTokenSource) Token(ctx context.Context) (string, error) {
+	return src.tokens[0], nil
+}
+
+func (src *testTokenSource) Refresh(ctx context.Context) (string, error) {
+	if len(src.tokens) > 1 {
+		src.tokens = src.tokens[1:]
+	}
+	return src.tokens[0], nil
+}
+
 func TestNewClient(t *testing.T) {
 	require.NotNil(t, NewClient("XXX"))
 }
+
+func TestNewClientWithTokenSource(t *testing.T) {
+	require.NotNil(t, NewClientWithTokenSource(nil))
+	require.NotNil(t, NewClientWithTokenSource(NewStaticTokenSource("XXX")))
+}
+
+func TestClient_getResp(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClientWithTokenSource(&testTokenSource{tokens: []string{"old", "new"}})
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/XXXX").
+		MatchHeader("Authorization", "Bearer old").
+		Reply(401).
+		BodyString(`{"Errors": {"description": "invalid_authentication", "code": 401}}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/XXXX").
+		MatchHeader("Authorization", "Bearer new").
+		Reply(200).
+		BodyString(`{"id": "XXXX"}`)
+	user, resp, err := client.GetUser(ctx, "XXXX")
+	require.Nil(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	require.Equal(t, "XXXX", user.ID)
+
+	// the request isn't retried if the token isn't changed
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/XXXX").
+		MatchHeader("Authorization", "Bearer new").
+		Reply(401).
+		BodyString(`{"Errors": {"description": "invalid_authentication", "code": 401}}`)
+	_, resp, err = client.GetUser(ctx, "XXXX")
+	require.NotNil(t, err)
+	require.Equal(t, 401, resp.StatusCode)
+	require.True(t, gock.IsDone())
+}
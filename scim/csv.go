@@ -0,0 +1,338 @@
+package scim
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type (
+	// CSVColumn maps a CSV column to a SCIM attribute path.
+	// See LookupAttribute for the syntax of Attribute.
+	CSVColumn struct {
+		Header    string `json:"header"`
+		Attribute string `json:"attribute"`
+	}
+
+	// CSVMapping is a definition of CSV columns.
+	// CSVMapping can be decoded from JSON.
+	CSVMapping struct {
+		Columns []CSVColumn `json:"columns"`
+		// MultiValueSeparator joins values of a multi-valued attribute in a cell on export,
+		// and splits a cell of a multi-valued attribute without a selector, such as "emails.value", on import.
+		// The n-th values of the columns of the same multi-valued attribute, such as "emails.value" and "emails.type",
+		// are imported to the n-th value of the attribute.
+		// If MultiValueSeparator is empty, ";" is used.
+		MultiValueSeparator string `json:"multiValueSeparator,omitempty"`
+	}
+
+	// CSVRowError is a validation error of a row of an imported CSV.
+	CSVRowError struct {
+		// Row is the row number. The header is row 1, so the first user is row 2.
+		Row int
+		// Header is the header of the invalid column. Header is empty if the error isn't of a column.
+		Header string
+		Err    error
+	}
+)
+
+// DefaultCSVMapping is the default mapping of CSV columns.
+var DefaultCSVMapping = &CSVMapping{
+	Columns: []CSVColumn{
+		{Header: "id", Attribute: "id"},
+		{Header: "userName", Attribute: "userName"},
+		{Header: "displayName", Attribute: "displayName"},
+		{Header: "givenName", Attribute: "name.givenName"},
+		{Header: "familyName", Attribute: "name.familyName"},
+		{Header: "email", Attribute: "emails[primary].value"},
+		{Header: "title", Attribute: "title"},
+		{Header: "department", Attribute: SchemaEnterpriseUser + ".department"},
+		{Header: "managerId", Attribute: SchemaEnterpriseUser + ".manager.managerId"},
+		{Header: "active", Attribute: "active"},
+	},
+}
+
+// csvBoolAttributes is names of boolean attributes whose cells are parsed as booleans on import.
+var csvBoolAttributes = map[string]struct{}{
+	"active":  {},
+	"primary": {},
+}
+
+// Error returns the error message.
+func (e *CSVRowError) Error() string {
+	if e.Header == "" {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d: %s: %v", e.Row, e.Header, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *CSVRowError) Unwrap() error {
+	return e.Err
+}
+
+// ReadCSVMapping reads a JSON mapping of CSV columns from r.
+func ReadCSVMapping(r io.Reader) (*CSVMapping, error) {
+	mapping := &CSVMapping{}
+	if err := json.NewDecoder(r).Decode(mapping); err != nil {
+		return nil, err
+	}
+	if err := mapping.validate(); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+func (mapping *CSVMapping) validate() error {
+	if len(mapping.Columns) == 0 {
+		return fmt.Errorf("columns are required")
+	}
+	headers := make(map[string]struct{}, len(mapping.Columns))
+	for _, column := range mapping.Columns {
+		if column.Header == "" {
+			return fmt.Errorf("header is required")
+		}
+		if column.Attribute == "" {
+			return fmt.Errorf("attribute of the column %s is required", column.Header)
+		}
+		if _, ok := headers[column.Header]; ok {
+			return fmt.Errorf("the column %s is duplicated", column.Header)
+		}
+		headers[column.Header] = struct{}{}
+	}
+	return nil
+}
+
+// WriteUsersCSV writes users to w as CSV with the header row.
+// If mapping is nil, DefaultCSVMapping is used.
+// Missing attributes are written as empty cells, and values of multi-valued attributes are joined by MultiValueSeparator.
+func WriteUsersCSV(w io.Writer, users []User, mapping *CSVMapping) error {
+	if mapping == nil {
+		mapping = DefaultCSVMapping
+	}
+	if err := mapping.validate(); err != nil {
+		return err
+	}
+	sep := mapping.MultiValueSeparator
+	if sep == "" {
+		sep = ";"
+	}
+	writer := csv.NewWriter(w)
+	record := make([]string, len(mapping.Columns))
+	for i, column := range mapping.Columns {
+		record[i] = column.Header
+	}
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+	for i := range users {
+		attrs, err := UserAttributes(&users[i])
+		if err != nil {
+			return fmt.Errorf("failed to convert the user %s: %w", users[i].ID, err)
+		}
+		for j, column := range mapping.Columns {
+			v, _ := LookupAttribute(attrs, column.Attribute)
+			record[j] = formatCSVValue(v, sep)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatCSVValue(v interface{}, sep string) string {
+	switch a := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return a
+	case bool:
+		return strconv.FormatBool(a)
+	case float64:
+		return strconv.FormatFloat(a, 'f', -1, 64)
+	case []interface{}:
+		values := make([]string, len(a))
+		for i, elem := range a {
+			values[i] = formatCSVValue(elem, sep)
+		}
+		return strings.Join(values, sep)
+	default:
+		b, err := json.Marshal(a)
+		if err != nil {
+			return fmt.Sprint(a)
+		}
+		return string(b)
+	}
+}
+
+// ReadUsersCSV reads users from CSV whose first row is the header.
+// Columns are matched with mapping by their headers, and columns which aren't in mapping are ignored.
+// If mapping is nil, each header is used as the attribute path.
+// Empty cells are skipped, and cells of boolean attributes such as "active" are parsed as booleans.
+// Cells of multi-valued attributes without a selector are split by mapping.MultiValueSeparator,
+// so that CSV written by WriteUsersCSV can be read with the same mapping.
+//
+// Invalid rows are reported as []*CSVRowError and aren't included in the returned users,
+// so the valid users can be created by Client.CreateUser while the invalid rows are fixed.
+// userName is required because Slack requires it to create a user.
+// An error is returned only when the CSV itself can't be read.
+func ReadUsersCSV(r io.Reader, mapping *CSVMapping) ([]User, []*CSVRowError, error) {
+	if mapping != nil {
+		if err := mapping.validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	reader := csv.NewReader(r)
+	// the number of fields is checked for each row to report it as a row error
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("the header is required")
+		}
+		return nil, nil, err
+	}
+	columns, err := csvColumns(header, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	sep := ""
+	if mapping != nil {
+		sep = mapping.MultiValueSeparator
+	}
+	if sep == "" {
+		sep = ";"
+	}
+	users := []User{}
+	rowErrors := []*CSVRowError{}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		user, rowErr := csvUser(record, columns, sep)
+		if rowErr != nil {
+			rowErr.Row = row
+			rowErrors = append(rowErrors, rowErr)
+			continue
+		}
+		users = append(users, *user)
+	}
+	return users, rowErrors, nil
+}
+
+// csvColumns returns columns of the header. Columns which aren't in mapping are nil.
+func csvColumns(header []string, mapping *CSVMapping) ([]*CSVColumn, error) {
+	columns := make([]*CSVColumn, len(header))
+	if mapping == nil {
+		for i, h := range header {
+			columns[i] = &CSVColumn{Header: h, Attribute: h}
+		}
+		return columns, nil
+	}
+	found := false
+	for i, h := range header {
+		for j := range mapping.Columns {
+			if mapping.Columns[j].Header == h {
+				columns[i] = &mapping.Columns[j]
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no column of the header is in the mapping")
+	}
+	return columns, nil
+}
+
+func csvUser(record []string, columns []*CSVColumn, sep string) (*User, *CSVRowError) {
+	if len(record) != len(columns) {
+		return nil, &CSVRowError{Err: fmt.Errorf("the row has %d columns but the header has %d columns", len(record), len(columns))}
+	}
+	attrs := map[string]interface{}{}
+	for i, cell := range record {
+		column := columns[i]
+		if column == nil || cell == "" {
+			continue
+		}
+		paths, cells := csvCellPaths(column.Attribute, cell, sep)
+		for j, path := range paths {
+			var v interface{} = cells[j]
+			if _, ok := csvBoolAttributes[strings.ToLower(lastAttributeName(column.Attribute))]; ok {
+				b, err := strconv.ParseBool(cells[j])
+				if err != nil {
+					return nil, &CSVRowError{Header: column.Header, Err: fmt.Errorf("invalid boolean: %s", cells[j])}
+				}
+				v = b
+			}
+			if err := SetAttribute(attrs, path, v); err != nil {
+				return nil, &CSVRowError{Header: column.Header, Err: err}
+			}
+		}
+	}
+	user, err := UserFromAttributes(attrs)
+	if err != nil {
+		return nil, &CSVRowError{Err: err}
+	}
+	if user.UserName == "" {
+		return nil, &CSVRowError{Err: fmt.Errorf("userName is required")}
+	}
+	return user, nil
+}
+
+// csvCellPaths returns attribute paths and values of the cell.
+// If the path has a multi-valued attribute without a selector, such as "emails" of "emails.value",
+// the cell is split by sep and the n-th value is set to the path whose selector is n, such as "emails[0].value".
+// Otherwise, the cell is set to the path as is.
+func csvCellPaths(path, cell, sep string) ([]string, []string) {
+	segments, err := parseUserAttributePath(path)
+	if err != nil {
+		return []string{path}, []string{cell}
+	}
+	types := userAttributeTypes(segments)
+	index := -1
+	for i, t := range types {
+		if t != nil && t.Kind() == reflect.Slice && !segments[i].hasSel {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return []string{path}, []string{cell}
+	}
+	cells := strings.Split(cell, sep)
+	paths := make([]string, len(cells))
+	for i := range cells {
+		segs := make([]string, len(segments))
+		for j, seg := range segments {
+			segs[j] = seg.name
+			switch {
+			case j == index:
+				segs[j] += "[" + strconv.Itoa(i) + "]"
+			case seg.hasSel:
+				segs[j] += "[" + seg.selector + "]"
+			}
+		}
+		paths[i] = strings.Join(segs, ".")
+	}
+	return paths, cells
+}
+
+// lastAttributeName returns the last attribute name of path without the selector.
+func lastAttributeName(path string) string {
+	name := path[strings.LastIndexAny(path, ".:")+1:]
+	if i := strings.Index(name, "["); i != -1 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,136 @@
+package scim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteUsersCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.Nil(t, WriteUsersCSV(buf, []User{testUser, {ID: "U2", UserName: "bob, jr."}}, nil))
+	require.Equal(t, `id,userName,displayName,givenName,familyName,email,title,department,managerId,active
+XXXXXXXXX,other_username,First Last,First,Last,some@example.com,Tour Guide,Tour Operations,U0XE15NHQ,true
+U2,"bob, jr.",,,,,,,,
+`, buf.String())
+
+	buf.Reset()
+	mapping := &CSVMapping{
+		Columns: []CSVColumn{
+			{Header: "id", Attribute: "id"},
+			{Header: "emails", Attribute: "emails.value"},
+		},
+		MultiValueSeparator: "|",
+	}
+	require.Nil(t, WriteUsersCSV(buf, []User{testUser}, mapping))
+	require.Equal(t, "id,emails\nXXXXXXXXX,some@example.com|some_other@example.com\n", buf.String())
+
+	require.NotNil(t, WriteUsersCSV(buf, nil, &CSVMapping{}))
+}
+
+func TestReadUsersCSV(t *testing.T) {
+	mapping, err := ReadCSVMapping(strings.NewReader(`{
+  "columns": [
+    {"header": "Login", "attribute": "userName"},
+    {"header": "First", "attribute": "name.givenName"},
+    {"header": "Email", "attribute": "emails[work].value"},
+    {"header": "Primary", "attribute": "emails[work].primary"},
+    {"header": "Dept", "attribute": "urn:scim:schemas:extension:enterprise:1.0.department"},
+    {"header": "Active", "attribute": "active"}
+  ]
+}`))
+	require.Nil(t, err)
+
+	users, rowErrors, err := ReadUsersCSV(strings.NewReader(`Login,First,Email,Primary,Dept,Active,Note
+alice,Alice,alice@example.com,true,Sales,true,ignored
+,Bob,bob@example.com,true,Sales,true,
+carol,Carol,carol@example.com,yes,Sales,true,
+dave,,,,,false,
+eve,Eve
+`), mapping)
+	require.Nil(t, err)
+	require.Equal(t, []User{
+		{
+			UserName: "alice",
+			Name:     &Name{GivenName: "Alice"},
+			Emails:   []Email{{Value: "alice@example.com", Type: "work", Primary: true}},
+			Active:   true,
+			Schemas:  []string{SchemaCore, SchemaEnterpriseUser},
+			EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+				Department: "Sales",
+			},
+		},
+		{
+			UserName: "dave",
+			Schemas:  []string{SchemaCore},
+		},
+	}, users)
+	require.Len(t, rowErrors, 3)
+	require.Equal(t, "row 3: userName is required", rowErrors[0].Error())
+	require.Equal(t, "row 4: Primary: invalid boolean: yes", rowErrors[1].Error())
+	require.Equal(t, "row 6: the row has 2 columns but the header has 7 columns", rowErrors[2].Error())
+
+	// headers are attribute paths without mapping
+	users, rowErrors, err = ReadUsersCSV(strings.NewReader("userName,emails[primary].value\nalice,alice@example.com\n"), nil)
+	require.Nil(t, err)
+	require.Empty(t, rowErrors)
+	require.Equal(t, []User{{
+		UserName: "alice",
+		Emails:   []Email{{Value: "alice@example.com", Primary: true}},
+		Schemas:  []string{SchemaCore},
+	}}, users)
+
+	_, _, err = ReadUsersCSV(strings.NewReader(""), nil)
+	require.NotNil(t, err)
+	_, _, err = ReadUsersCSV(strings.NewReader("foo\nbar\n"), mapping)
+	require.NotNil(t, err)
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.Nil(t, WriteUsersCSV(buf, []User{testUser}, nil))
+	users, rowErrors, err := ReadUsersCSV(buf, DefaultCSVMapping)
+	require.Nil(t, err)
+	require.Empty(t, rowErrors)
+	require.Len(t, users, 1)
+	user := users[0]
+	require.Equal(t, testUser.ID, user.ID)
+	require.Equal(t, testUser.UserName, user.UserName)
+	require.Equal(t, testUser.PrimaryEmail(), user.PrimaryEmail())
+	require.Equal(t, testUser.EnterpriseUserSchemaExtension.Department, user.EnterpriseUserSchemaExtension.Department)
+	require.Equal(t, testUser.EnterpriseUserSchemaExtension.Manager.ManagerID, user.EnterpriseUserSchemaExtension.Manager.ManagerID)
+	require.True(t, user.Active)
+}
+
+func TestCSVRoundTrip_multiValued(t *testing.T) {
+	mapping := &CSVMapping{
+		Columns: []CSVColumn{
+			{Header: "userName", Attribute: "userName"},
+			{Header: "emails", Attribute: "emails.value"},
+			{Header: "emailTypes", Attribute: "emails.type"},
+		},
+		MultiValueSeparator: "|",
+	}
+	buf := &bytes.Buffer{}
+	require.Nil(t, WriteUsersCSV(buf, []User{testUser}, mapping))
+	require.Equal(t, "userName,emails,emailTypes\nother_username,some@example.com|some_other@example.com,work|home\n", buf.String())
+	users, rowErrors, err := ReadUsersCSV(buf, mapping)
+	require.Nil(t, err)
+	require.Empty(t, rowErrors)
+	require.Len(t, users, 1)
+	require.Equal(t, []Email{
+		{Value: "some@example.com", Type: "work"},
+		{Value: "some_other@example.com", Type: "home"},
+	}, users[0].Emails)
+
+	// the default separator is ";", and booleans are parsed for each value
+	users, rowErrors, err = ReadUsersCSV(strings.NewReader("userName,emails.value,emails.primary\nalice,a@example.com;b@example.com,true;false\n"), nil)
+	require.Nil(t, err)
+	require.Empty(t, rowErrors)
+	require.Equal(t, []Email{
+		{Value: "a@example.com", Primary: true},
+		{Value: "b@example.com"},
+	}, users[0].Emails)
+}
@@ -0,0 +1,225 @@
+package scim
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DiffUser returns a patch to update current to desired.
+// Only attributes set in desired are compared, so attributes desired doesn't have are kept as is.
+// Active is compared only if desired.Active is true, that is, DiffUser doesn't deactivate the user.
+// Password and Groups aren't compared because Password can't be read and Groups is read-only.
+// Emails, addresses, and phone numbers are compared as sets, so only the order of them isn't a difference.
+// If there is no difference, nil is returned.
+func DiffUser(current, desired *User) *UserPatch {
+	patch := &UserPatch{}
+	changed := false
+	diffString := func(cur, des string) *string {
+		if des == "" || cur == des {
+			return nil
+		}
+		changed = true
+		return &des
+	}
+	if desired.UserName != "" && current.UserName != desired.UserName {
+		patch.UserName = desired.UserName
+		changed = true
+	}
+	if desired.Active && !current.Active {
+		active := true
+		patch.Active = &active
+		changed = true
+	}
+	patch.ExternalID = diffString(current.ExternalID, desired.ExternalID)
+	patch.NickName = diffString(current.NickName, desired.NickName)
+	patch.ProfileURL = diffString(current.ProfileURL, desired.ProfileURL)
+	patch.DisplayName = diffString(current.DisplayName, desired.DisplayName)
+	patch.UserType = diffString(current.UserType, desired.UserType)
+	patch.Title = diffString(current.Title, desired.Title)
+	patch.PreferredLanguage = diffString(current.PreferredLanguage, desired.PreferredLanguage)
+	patch.Locale = diffString(current.Locale, desired.Locale)
+	patch.Timezone = diffString(current.Timezone, desired.Timezone)
+
+	if desired.Name != nil {
+		cur := current.Name
+		if cur == nil {
+			cur = &Name{}
+		}
+		name := &NamePatch{
+			FamilyName:      diffString(cur.FamilyName, desired.Name.FamilyName),
+			GivenName:       diffString(cur.GivenName, desired.Name.GivenName),
+			HonorificPrefix: diffString(cur.HonorificPrefix, desired.Name.HonorificPrefix),
+		}
+		if !reflect.DeepEqual(name, &NamePatch{}) {
+			patch.Name = name
+		}
+	}
+
+	if len(desired.Emails) != 0 && !equalStringSets(emailKeys(current.Emails), emailKeys(desired.Emails)) {
+		patch.Emails = desired.Emails
+		changed = true
+	}
+	if len(desired.Addresses) != 0 && !equalStringSets(addressKeys(current.Addresses), addressKeys(desired.Addresses)) {
+		patch.Addresses = &desired.Addresses
+		changed = true
+	}
+	if len(desired.PhoneNumbers) != 0 &&
+		!equalStringSets(phoneNumberKeys(current.PhoneNumbers), phoneNumberKeys(desired.PhoneNumbers)) {
+		patch.PhoneNumbers = &desired.PhoneNumbers
+		changed = true
+	}
+	if len(desired.Roles) != 0 && !reflect.DeepEqual(current.Roles, desired.Roles) {
+		patch.Roles = &desired.Roles
+		changed = true
+	}
+	if len(desired.Photos) != 0 && !reflect.DeepEqual(current.Photos, desired.Photos) {
+		patch.Photos = &desired.Photos
+		changed = true
+	}
+
+	if ext := desired.EnterpriseUserSchemaExtension; ext != nil {
+		cur := current.EnterpriseUserSchemaExtension
+		if cur == nil {
+			cur = &EnterpriseUserSchemaExtension{}
+		}
+		extPatch := &EnterpriseUserSchemaExtensionPatch{
+			EmployeeNumber: diffString(cur.EmployeeNumber, ext.EmployeeNumber),
+			CostCenter:     diffString(cur.CostCenter, ext.CostCenter),
+			Organization:   diffString(cur.Organization, ext.Organization),
+			Division:       diffString(cur.Division, ext.Division),
+			Department:     diffString(cur.Department, ext.Department),
+		}
+		if ext.Manager != nil && ext.Manager.ManagerID != "" && managerID(current) != ext.Manager.ManagerID {
+			extPatch.Manager = &Manager{ManagerID: ext.Manager.ManagerID}
+			changed = true
+		}
+		if !reflect.DeepEqual(extPatch, &EnterpriseUserSchemaExtensionPatch{}) {
+			patch.EnterpriseUserSchemaExtension = extPatch
+		}
+	}
+
+	if ext := desired.SlackGuestUserSchemaExtension; ext != nil {
+		cur := current.SlackGuestUserSchemaExtension
+		if cur == nil {
+			cur = &SlackGuestUserSchemaExtension{}
+		}
+		extPatch := &SlackGuestUserSchemaExtensionPatch{
+			Type:       diffString(cur.Type, ext.Type),
+			Expiration: diffString(cur.Expiration, ext.Expiration),
+		}
+		if !reflect.DeepEqual(extPatch, &SlackGuestUserSchemaExtensionPatch{}) {
+			patch.SlackGuestUserSchemaExtension = extPatch
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	patch.Schemas = []string{SchemaCore}
+	if patch.EnterpriseUserSchemaExtension != nil {
+		patch.Schemas = append(patch.Schemas, SchemaEnterpriseUser)
+	}
+	if patch.SlackGuestUserSchemaExtension != nil {
+		patch.Schemas = append(patch.Schemas, SchemaSlackGuest)
+	}
+	return patch
+}
+
+// equalStringSets returns true if a and b have the same elements regardless of the order.
+func equalStringSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, k := range a {
+		counts[k]++
+	}
+	for _, k := range b {
+		if counts[k] == 0 {
+			return false
+		}
+		counts[k]--
+	}
+	return true
+}
+
+// emailKeys returns keys of emails, which are the type, the value, and primary.
+func emailKeys(emails []Email) []string {
+	keys := make([]string, len(emails))
+	for i, email := range emails {
+		keys[i] = strings.Join([]string{email.Type, email.Value, strconv.FormatBool(email.Primary)}, "\x00")
+	}
+	return keys
+}
+
+// phoneNumberKeys returns keys of phone numbers, which are the type, the value, and primary.
+func phoneNumberKeys(phoneNumbers []PhoneNumber) []string {
+	keys := make([]string, len(phoneNumbers))
+	for i, phoneNumber := range phoneNumbers {
+		keys[i] = strings.Join([]string{phoneNumber.Type, phoneNumber.Value, strconv.FormatBool(phoneNumber.Primary)}, "\x00")
+	}
+	return keys
+}
+
+// addressKeys returns keys of addresses, which are all fields of the addresses.
+func addressKeys(addresses []Address) []string {
+	keys := make([]string, len(addresses))
+	for i, address := range addresses {
+		keys[i] = strings.Join([]string{
+			address.StreetAddress, address.Locality, address.Region, address.PostalCode, address.Country,
+			strconv.FormatBool(address.Primary),
+		}, "\x00")
+	}
+	return keys
+}
+
+// DiffGroup returns a patch to update current to desired.
+// displayName is compared if desired.DisplayName isn't empty,
+// and members are compared if desired.Members isn't nil.
+// Members which current has and desired doesn't have are removed.
+// If there is no difference, nil is returned.
+func DiffGroup(current, desired *Group) *Group {
+	patch := &Group{
+		Schemas: []string{SchemaCore},
+		// an empty list doesn't change members
+		Members: []Member{},
+	}
+	changed := false
+	if desired.DisplayName != "" && current.DisplayName != desired.DisplayName {
+		patch.DisplayName = desired.DisplayName
+		changed = true
+	}
+	if desired.Members != nil {
+		cur := make(map[string]struct{}, len(current.Members))
+		for _, member := range current.Members {
+			cur[member.Value] = struct{}{}
+		}
+		des := make(map[string]struct{}, len(desired.Members))
+		for _, member := range desired.Members {
+			des[member.Value] = struct{}{}
+			if _, ok := cur[member.Value]; !ok {
+				patch.Members = append(patch.Members, Member{
+					Value:   member.Value,
+					Display: member.Display,
+				})
+				cur[member.Value] = struct{}{}
+				changed = true
+			}
+		}
+		for _, member := range current.Members {
+			if _, ok := des[member.Value]; !ok {
+				patch.Members = append(patch.Members, Member{
+					Value:     member.Value,
+					Display:   member.Display,
+					Operation: MemberOperationDelete,
+				})
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return patch
+}
@@ -0,0 +1,221 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffUser(t *testing.T) {
+	strP := func(s string) *string {
+		return &s
+	}
+	active := true
+	current := &User{
+		UserName: "foo",
+		Title:    "Engineer",
+		Name:     &Name{GivenName: "Foo", FamilyName: "Bar"},
+		Emails:   []Email{{Value: "foo@example.com", Primary: true}},
+		EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+			Department: "Engineering",
+			Manager:    &Manager{ManagerID: "U1"},
+		},
+	}
+	data := []struct {
+		title   string
+		desired *User
+		exp     *UserPatch
+	}{
+		{
+			title:   "empty desired user",
+			desired: &User{},
+		},
+		{
+			title: "no difference",
+			desired: &User{
+				UserName: "foo",
+				Title:    "Engineer",
+				Name:     &Name{GivenName: "Foo"},
+				Emails:   []Email{{Value: "foo@example.com", Primary: true}},
+				EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+					Department: "Engineering",
+					Manager:    &Manager{ManagerID: "U1"},
+				},
+			},
+		},
+		{
+			title: "core attributes",
+			desired: &User{
+				UserName: "bar",
+				Active:   true,
+				Title:    "Manager",
+				Name:     &Name{GivenName: "Zoo"},
+				Emails:   []Email{{Value: "bar@example.com", Primary: true}},
+				PhoneNumbers: []PhoneNumber{
+					{Value: "555-555-5555"},
+				},
+			},
+			exp: &UserPatch{
+				Schemas:  []string{SchemaCore},
+				UserName: "bar",
+				Active:   &active,
+				Title:    strP("Manager"),
+				Name:     &NamePatch{GivenName: strP("Zoo")},
+				Emails:   []Email{{Value: "bar@example.com", Primary: true}},
+				PhoneNumbers: &[]PhoneNumber{
+					{Value: "555-555-5555"},
+				},
+			},
+		},
+		{
+			title: "extensions",
+			desired: &User{
+				EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+					Division: "Platform",
+					Manager:  &Manager{ManagerID: "U2"},
+				},
+				SlackGuestUserSchemaExtension: &SlackGuestUserSchemaExtension{
+					Type: GuestTypeMultiChannel,
+				},
+			},
+			exp: &UserPatch{
+				Schemas: []string{SchemaCore, SchemaEnterpriseUser, SchemaSlackGuest},
+				EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtensionPatch{
+					Division: strP("Platform"),
+					Manager:  &Manager{ManagerID: "U2"},
+				},
+				SlackGuestUserSchemaExtension: &SlackGuestUserSchemaExtensionPatch{
+					Type: strP(GuestTypeMultiChannel),
+				},
+			},
+		},
+	}
+	for _, d := range data {
+		require.Equal(t, d.exp, DiffUser(current, d.desired), d.title)
+	}
+	require.Equal(t, &UserPatch{
+		Schemas: []string{SchemaCore},
+		Name:    &NamePatch{FamilyName: strP("Bar")},
+	}, DiffUser(&User{}, &User{Name: &Name{FamilyName: "Bar"}}))
+}
+
+func TestDiffUser_multiValuedAttributes(t *testing.T) {
+	current := &User{
+		Emails: []Email{
+			{Value: "foo@example.com", Type: "work", Primary: true},
+			{Value: "foo@home.example.com", Type: "home"},
+		},
+		PhoneNumbers: []PhoneNumber{
+			{Value: "555-0100", Type: "work"},
+			{Value: "555-0101", Type: "mobile"},
+		},
+		Addresses: []Address{
+			{Locality: "Tokyo", Primary: true},
+			{Locality: "Osaka"},
+		},
+	}
+	// the order doesn't matter
+	require.Nil(t, DiffUser(current, &User{
+		Emails: []Email{
+			{Value: "foo@home.example.com", Type: "home"},
+			{Value: "foo@example.com", Type: "work", Primary: true},
+		},
+		PhoneNumbers: []PhoneNumber{
+			{Value: "555-0101", Type: "mobile"},
+			{Value: "555-0100", Type: "work"},
+		},
+		Addresses: []Address{
+			{Locality: "Osaka"},
+			{Locality: "Tokyo", Primary: true},
+		},
+	}))
+	// the type and primary are compared
+	emails := []Email{
+		{Value: "foo@home.example.com", Type: "work"},
+		{Value: "foo@example.com", Type: "work", Primary: true},
+	}
+	require.Equal(t, &UserPatch{
+		Schemas: []string{SchemaCore},
+		Emails:  emails,
+	}, DiffUser(current, &User{Emails: emails}))
+	phoneNumbers := []PhoneNumber{
+		{Value: "555-0100", Type: "work", Primary: true},
+		{Value: "555-0101", Type: "mobile"},
+	}
+	require.Equal(t, &UserPatch{
+		Schemas:      []string{SchemaCore},
+		PhoneNumbers: &phoneNumbers,
+	}, DiffUser(current, &User{PhoneNumbers: phoneNumbers}))
+	// duplicated elements are counted
+	addresses := []Address{
+		{Locality: "Tokyo", Primary: true},
+		{Locality: "Tokyo", Primary: true},
+	}
+	require.Equal(t, &UserPatch{
+		Schemas:   []string{SchemaCore},
+		Addresses: &addresses,
+	}, DiffUser(current, &User{Addresses: addresses}))
+}
+
+func TestDiffGroup(t *testing.T) {
+	current := &Group{
+		DisplayName: "foo",
+		Members: []Member{
+			{Value: "U1", Display: "one"},
+			{Value: "U2", Display: "two"},
+		},
+	}
+	data := []struct {
+		title   string
+		desired *Group
+		exp     *Group
+	}{
+		{
+			title:   "empty desired group",
+			desired: &Group{},
+		},
+		{
+			title: "no difference",
+			desired: &Group{
+				DisplayName: "foo",
+				Members:     []Member{{Value: "U2"}, {Value: "U1"}},
+			},
+		},
+		{
+			title:   "rename",
+			desired: &Group{DisplayName: "bar"},
+			exp: &Group{
+				Schemas:     []string{SchemaCore},
+				DisplayName: "bar",
+				Members:     []Member{},
+			},
+		},
+		{
+			title: "members",
+			desired: &Group{
+				Members: []Member{{Value: "U2"}, {Value: "U3", Display: "three"}, {Value: "U3"}},
+			},
+			exp: &Group{
+				Schemas: []string{SchemaCore},
+				Members: []Member{
+					{Value: "U3", Display: "three"},
+					{Value: "U1", Display: "one", Operation: MemberOperationDelete},
+				},
+			},
+		},
+		{
+			title:   "remove all members",
+			desired: &Group{Members: []Member{}},
+			exp: &Group{
+				Schemas: []string{SchemaCore},
+				Members: []Member{
+					{Value: "U1", Display: "one", Operation: MemberOperationDelete},
+					{Value: "U2", Display: "two", Operation: MemberOperationDelete},
+				},
+			},
+		},
+	}
+	for _, d := range data {
+		require.Equal(t, d.exp, DiffGroup(current, d.desired), d.title)
+	}
+}
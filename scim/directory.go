@@ -0,0 +1,322 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type (
+	// Directory is an in-memory index of users and groups of a workspace.
+	// User.Groups is read-only and is often empty or stale in list responses,
+	// so Directory computes users' groups from groups' members instead.
+	// Directory can be updated incrementally by passing Directory.ApplyMutation to Client.SetMutationHook .
+	// Directory is safe for concurrent use.
+	// Users and groups returned by Directory are shared, so they shouldn't be modified.
+	Directory struct {
+		users      map[string]*User
+		groups     map[string]*Group
+		userGroups map[string]map[string]struct{}
+		emails     map[string]string
+		userNames  map[string]string
+		mutex      sync.RWMutex
+	}
+)
+
+// NewDirectory returns a directory of users and groups.
+func NewDirectory(users []User, groups []Group) *Directory {
+	dir := &Directory{
+		users:      make(map[string]*User, len(users)),
+		groups:     make(map[string]*Group, len(groups)),
+		userGroups: map[string]map[string]struct{}{},
+		emails:     map[string]string{},
+		userNames:  map[string]string{},
+	}
+	for i := range users {
+		dir.putUser(&users[i])
+	}
+	for i := range groups {
+		dir.putGroup(&groups[i])
+	}
+	return dir
+}
+
+// LoadDirectory gets all users and groups and returns a directory of them.
+func (c *Client) LoadDirectory(ctx context.Context) (_ *Directory, err error) {
+	ctx, span := c.startMethodSpan(ctx, "LoadDirectory")
+	defer func() {
+		span.end(err)
+	}()
+	users, err := c.GetAllUsers(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	groups, err := c.GetAllGroups(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	return NewDirectory(users, groups), nil
+}
+
+// User returns the user whose id is id.
+func (dir *Directory) User(id string) (*User, bool) {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	user, ok := dir.users[id]
+	return user, ok
+}
+
+// Group returns the group whose id is id.
+func (dir *Directory) Group(id string) (*Group, bool) {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	group, ok := dir.groups[id]
+	return group, ok
+}
+
+// UserByEmail returns the user who has the email.
+// All emails of users are indexed, and emails are compared case-insensitively.
+func (dir *Directory) UserByEmail(email string) (*User, bool) {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	id, ok := dir.emails[strings.ToLower(email)]
+	if !ok {
+		return nil, false
+	}
+	user, ok := dir.users[id]
+	return user, ok
+}
+
+// UserByUserName returns the user whose userName is userName.
+// userNames are compared case-insensitively.
+func (dir *Directory) UserByUserName(userName string) (*User, bool) {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	id, ok := dir.userNames[strings.ToLower(userName)]
+	if !ok {
+		return nil, false
+	}
+	user, ok := dir.users[id]
+	return user, ok
+}
+
+// Users returns all users sorted by id.
+func (dir *Directory) Users() []*User {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	users := make([]*User, 0, len(dir.users))
+	for _, user := range dir.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users
+}
+
+// Groups returns all groups sorted by id.
+func (dir *Directory) Groups() []*Group {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	groups := make([]*Group, 0, len(dir.groups))
+	for _, group := range dir.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
+
+// GroupIDsOf returns ids of groups the user belongs to.
+// The returned ids are sorted.
+func (dir *Directory) GroupIDsOf(userID string) []string {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	ids := make([]string, 0, len(dir.userGroups[userID]))
+	for id := range dir.userGroups[userID] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// GroupsOf returns groups the user belongs to.
+// The returned groups are sorted by id.
+func (dir *Directory) GroupsOf(userID string) []*Group {
+	ids := dir.GroupIDsOf(userID)
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	groups := make([]*Group, 0, len(ids))
+	for _, id := range ids {
+		if group, ok := dir.groups[id]; ok {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// MemberIDsOf returns ids of the group's members.
+// The returned ids are sorted.
+func (dir *Directory) MemberIDsOf(groupID string) []string {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	group, ok := dir.groups[groupID]
+	if !ok {
+		return []string{}
+	}
+	ids := make([]string, len(group.Members))
+	for i, member := range group.Members {
+		ids[i] = member.Value
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// IsMember returns true if the user belongs to the group.
+func (dir *Directory) IsMember(userID, groupID string) bool {
+	dir.mutex.RLock()
+	defer dir.mutex.RUnlock()
+	_, ok := dir.userGroups[userID][groupID]
+	return ok
+}
+
+// PutUser adds or replaces the user.
+// user.Groups is ignored, because memberships are computed from groups' members.
+func (dir *Directory) PutUser(user *User) {
+	if user == nil || user.ID == "" {
+		return
+	}
+	dir.mutex.Lock()
+	defer dir.mutex.Unlock()
+	dir.putUser(user)
+}
+
+// PutGroup adds or replaces the group and its members.
+func (dir *Directory) PutGroup(group *Group) {
+	if group == nil || group.ID == "" {
+		return
+	}
+	dir.mutex.Lock()
+	defer dir.mutex.Unlock()
+	dir.putGroup(group)
+}
+
+// DeactivateUser marks the user inactive and removes the user from all groups.
+// Slack doesn't delete the user by DELETE /Users/{id} API but deactivates the user.
+func (dir *Directory) DeactivateUser(id string) {
+	dir.mutex.Lock()
+	defer dir.mutex.Unlock()
+	if user, ok := dir.users[id]; ok {
+		u := *user
+		u.Active = false
+		dir.users[id] = &u
+	}
+	for groupID := range dir.userGroups[id] {
+		group, ok := dir.groups[groupID]
+		if !ok {
+			continue
+		}
+		g := *group
+		g.Members = make([]Member, 0, len(group.Members))
+		for _, member := range group.Members {
+			if member.Value != id {
+				g.Members = append(g.Members, member)
+			}
+		}
+		dir.groups[groupID] = &g
+	}
+	delete(dir.userGroups, id)
+}
+
+// RemoveGroup removes the group.
+func (dir *Directory) RemoveGroup(id string) {
+	dir.mutex.Lock()
+	defer dir.mutex.Unlock()
+	dir.removeGroup(id)
+}
+
+// ApplyMutation updates the directory with the mutation.
+// ApplyMutation is a MutationHook, so the directory is kept up to date by Client.SetMutationHook(dir.ApplyMutation) .
+// Failed mutations are ignored.
+func (dir *Directory) ApplyMutation(ctx context.Context, mutation *Mutation) {
+	if mutation.Err != nil {
+		return
+	}
+	switch mutation.ResourceType {
+	case ResourceTypeUser:
+		if mutation.Operation == MutationDelete {
+			dir.DeactivateUser(mutation.ResourceID)
+			return
+		}
+		if user, ok := mutation.Result.(*User); ok {
+			dir.PutUser(user)
+		}
+	case ResourceTypeGroup:
+		if mutation.Operation == MutationDelete {
+			dir.RemoveGroup(mutation.ResourceID)
+			return
+		}
+		if group, ok := mutation.Result.(*Group); ok {
+			dir.PutGroup(group)
+		}
+	}
+}
+
+func (dir *Directory) putUser(user *User) {
+	if old, ok := dir.users[user.ID]; ok {
+		dir.unindexUser(old)
+	}
+	dir.users[user.ID] = user
+	for _, email := range user.Emails {
+		if email.Value != "" {
+			dir.emails[strings.ToLower(email.Value)] = user.ID
+		}
+	}
+	if user.UserName != "" {
+		dir.userNames[strings.ToLower(user.UserName)] = user.ID
+	}
+}
+
+func (dir *Directory) unindexUser(user *User) {
+	for _, email := range user.Emails {
+		key := strings.ToLower(email.Value)
+		if dir.emails[key] == user.ID {
+			delete(dir.emails, key)
+		}
+	}
+	key := strings.ToLower(user.UserName)
+	if dir.userNames[key] == user.ID {
+		delete(dir.userNames, key)
+	}
+}
+
+func (dir *Directory) putGroup(group *Group) {
+	dir.removeGroup(group.ID)
+	dir.groups[group.ID] = group
+	for _, member := range group.Members {
+		groups, ok := dir.userGroups[member.Value]
+		if !ok {
+			groups = map[string]struct{}{}
+			dir.userGroups[member.Value] = groups
+		}
+		groups[group.ID] = struct{}{}
+	}
+}
+
+func (dir *Directory) removeGroup(id string) {
+	group, ok := dir.groups[id]
+	if !ok {
+		return
+	}
+	for _, member := range group.Members {
+		groups := dir.userGroups[member.Value]
+		delete(groups, id)
+		if len(groups) == 0 {
+			delete(dir.userGroups, member.Value)
+		}
+	}
+	delete(dir.groups, id)
+}
@@ -0,0 +1,160 @@
+package scim
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testDirectory() *Directory {
+	return NewDirectory([]User{
+		{ID: "U1", UserName: "foo", Active: true, Emails: []Email{{Value: "foo@example.com", Primary: true}}},
+		{ID: "U2", UserName: "bar", Active: true, Emails: []Email{{Value: "bar@example.com"}, {Value: "bar2@example.com"}}},
+		{ID: "U3", UserName: "baz", Active: true},
+	}, []Group{
+		{ID: "G1", Members: []Member{{Value: "U1"}, {Value: "U2"}}},
+		{ID: "G2", Members: []Member{{Value: "U1"}}},
+	})
+}
+
+func TestDirectory(t *testing.T) {
+	dir := testDirectory()
+
+	user, ok := dir.User("U1")
+	require.True(t, ok)
+	require.Equal(t, "foo", user.UserName)
+	_, ok = dir.User("U4")
+	require.False(t, ok)
+
+	group, ok := dir.Group("G2")
+	require.True(t, ok)
+	require.Equal(t, "G2", group.ID)
+
+	user, ok = dir.UserByEmail("BAR2@example.com")
+	require.True(t, ok)
+	require.Equal(t, "U2", user.ID)
+	_, ok = dir.UserByEmail("qux@example.com")
+	require.False(t, ok)
+
+	user, ok = dir.UserByUserName("Baz")
+	require.True(t, ok)
+	require.Equal(t, "U3", user.ID)
+
+	require.Equal(t, []string{"G1", "G2"}, dir.GroupIDsOf("U1"))
+	require.Equal(t, []string{}, dir.GroupIDsOf("U3"))
+	require.Len(t, dir.GroupsOf("U2"), 1)
+	require.Equal(t, []string{"U1", "U2"}, dir.MemberIDsOf("G1"))
+	require.Equal(t, []string{}, dir.MemberIDsOf("G3"))
+	require.True(t, dir.IsMember("U2", "G1"))
+	require.False(t, dir.IsMember("U2", "G2"))
+	require.Len(t, dir.Users(), 3)
+	require.Len(t, dir.Groups(), 2)
+}
+
+func TestDirectory_ApplyMutation(t *testing.T) {
+	ctx := context.Background()
+	dir := testDirectory()
+
+	// the userName and the email are changed
+	dir.ApplyMutation(ctx, &Mutation{
+		Operation:    MutationPatch,
+		ResourceType: ResourceTypeUser,
+		ResourceID:   "U1",
+		Result:       &User{ID: "U1", UserName: "foo2", Active: true, Emails: []Email{{Value: "foo2@example.com"}}},
+	})
+	_, ok := dir.UserByUserName("foo")
+	require.False(t, ok)
+	_, ok = dir.UserByEmail("foo@example.com")
+	require.False(t, ok)
+	user, ok := dir.UserByEmail("foo2@example.com")
+	require.True(t, ok)
+	require.Equal(t, "foo2", user.UserName)
+	require.Equal(t, []string{"G1", "G2"}, dir.GroupIDsOf("U1"))
+
+	// the members are replaced
+	dir.ApplyMutation(ctx, &Mutation{
+		Operation:    MutationPatch,
+		ResourceType: ResourceTypeGroup,
+		ResourceID:   "G2",
+		Result:       &Group{ID: "G2", Members: []Member{{Value: "U3"}}},
+	})
+	require.Equal(t, []string{"G1"}, dir.GroupIDsOf("U1"))
+	require.Equal(t, []string{"G2"}, dir.GroupIDsOf("U3"))
+
+	// the user is deactivated
+	dir.ApplyMutation(ctx, &Mutation{
+		Operation:    MutationDelete,
+		ResourceType: ResourceTypeUser,
+		ResourceID:   "U2",
+	})
+	user, ok = dir.User("U2")
+	require.True(t, ok)
+	require.False(t, user.Active)
+	require.Equal(t, []string{}, dir.GroupIDsOf("U2"))
+	require.Equal(t, []string{"U1"}, dir.MemberIDsOf("G1"))
+
+	// the group is deleted
+	dir.ApplyMutation(ctx, &Mutation{
+		Operation:    MutationDelete,
+		ResourceType: ResourceTypeGroup,
+		ResourceID:   "G1",
+	})
+	_, ok = dir.Group("G1")
+	require.False(t, ok)
+	require.Equal(t, []string{}, dir.GroupIDsOf("U1"))
+
+	// a new user is created
+	dir.ApplyMutation(ctx, &Mutation{
+		Operation:    MutationCreate,
+		ResourceType: ResourceTypeUser,
+		ResourceID:   "U4",
+		Result:       &User{ID: "U4", UserName: "qux"},
+	})
+	user, ok = dir.UserByUserName("qux")
+	require.True(t, ok)
+	require.Equal(t, "U4", user.ID)
+}
+
+func TestClient_LoadDirectory(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1", "userName": "foo"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "G1", "members": [{"value": "U1"}]}]}`)
+	dir, err := client.LoadDirectory(ctx)
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Equal(t, []string{"G1"}, dir.GroupIDsOf("U1"))
+
+	// the directory is updated by the client's mutations
+	client.SetMutationHook(dir.ApplyMutation)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": []}`)
+	_, _, err = client.RemoveGroupMembers(ctx, "G1", "U1")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Equal(t, []string{}, dir.GroupIDsOf("U1"))
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+	_, err = client.LoadDirectory(ctx)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,263 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	// DomainMigrationOption is an option of PlanDomainMigration .
+	DomainMigrationOption struct {
+		// OldDomain and NewDomain are email domains such as "old.com" and "new.com".
+		OldDomain string `json:"oldDomain"`
+		NewDomain string `json:"newDomain"`
+		// If MigrateUserName is true, userNames which are emails of OldDomain are migrated too.
+		MigrateUserName bool `json:"migrateUserName,omitempty"`
+		// If KeepOldEmail is true, old addresses are kept as secondary emails.
+		// An email isn't rewritten if the user already has the new address, so the migration can be planned again safely.
+		KeepOldEmail bool `json:"keepOldEmail,omitempty"`
+		// If IncludeInactive is false, deactivated users aren't migrated.
+		IncludeInactive bool `json:"includeInactive,omitempty"`
+	}
+
+	// DomainMigrationPlan is a plan of email domain migration.
+	DomainMigrationPlan struct {
+		Changes []DomainMigrationChange `json:"changes"`
+		// Conflicts are changes which can't be applied because the new values are used by other users.
+		Conflicts []DomainMigrationConflict `json:"conflicts"`
+	}
+
+	// DomainMigrationChange is a change of a user.
+	DomainMigrationChange struct {
+		UserID   string `json:"userId"`
+		UserName string `json:"userName"`
+		// NewUserName is empty if userName isn't changed.
+		NewUserName string  `json:"newUserName,omitempty"`
+		OldEmails   []Email `json:"oldEmails"`
+		NewEmails   []Email `json:"newEmails"`
+	}
+
+	// DomainMigrationConflict is a user whose new email or userName is used by another user.
+	DomainMigrationConflict struct {
+		UserID string `json:"userId"`
+		// Value is the new email or userName.
+		Value string `json:"value"`
+		// ConflictUserID is the id of the user who uses Value.
+		ConflictUserID string `json:"conflictUserId"`
+	}
+
+	// DomainMigrationReport is a result of Client.ApplyDomainMigration .
+	DomainMigrationReport struct {
+		// Migrated is ids of users who are migrated.
+		Migrated []string
+		// Skipped is ids of users who have already been migrated according to the checkpoint.
+		Skipped []string
+		Failed  []UserError
+	}
+
+	// UserError is an error which occurred in updating a user.
+	UserError struct {
+		UserID string
+		Err    error
+	}
+)
+
+// Error returns the error message with the user id.
+func (e *UserError) Error() string {
+	return fmt.Sprintf("user %s: %v", e.UserID, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *UserError) Unwrap() error {
+	return e.Err
+}
+
+// Patch returns a patch to apply the change.
+func (change *DomainMigrationChange) Patch() *UserPatch {
+	return &UserPatch{
+		Schemas:  []string{SchemaCore},
+		UserName: change.NewUserName,
+		Emails:   change.NewEmails,
+	}
+}
+
+// PlanDomainMigration plans rewrites of emails of OldDomain to NewDomain.
+// Type and Primary of emails are preserved.
+// Users who have no email of OldDomain aren't changed, so the plan can be computed again after a partial migration.
+// If a new email or userName is used by another user or by another change, the change is reported as a conflict.
+func PlanDomainMigration(users []User, opts *DomainMigrationOption) (*DomainMigrationPlan, error) {
+	if opts == nil || opts.OldDomain == "" || opts.NewDomain == "" {
+		return nil, fmt.Errorf("OldDomain and NewDomain are required")
+	}
+	if strings.EqualFold(opts.OldDomain, opts.NewDomain) {
+		return nil, fmt.Errorf("OldDomain and NewDomain are the same")
+	}
+	// owners of emails and userNames, which are compared case-insensitively
+	owners := map[string]string{}
+	for _, user := range users {
+		for _, email := range user.Emails {
+			owners[strings.ToLower(email.Value)] = user.ID
+		}
+		if user.UserName != "" {
+			owners[strings.ToLower(user.UserName)] = user.ID
+		}
+	}
+	plan := &DomainMigrationPlan{
+		Changes:   []DomainMigrationChange{},
+		Conflicts: []DomainMigrationConflict{},
+	}
+	for _, user := range users {
+		if !user.Active && !opts.IncludeInactive {
+			continue
+		}
+		change, ok := planUserDomainMigration(&user, opts)
+		if !ok {
+			continue
+		}
+		values := []string{}
+		for _, email := range change.NewEmails {
+			values = append(values, email.Value)
+		}
+		if change.NewUserName != "" {
+			values = append(values, change.NewUserName)
+		}
+		conflict := false
+		for _, value := range values {
+			key := strings.ToLower(value)
+			if owner, ok := owners[key]; ok && owner != user.ID {
+				plan.Conflicts = append(plan.Conflicts, DomainMigrationConflict{
+					UserID:         user.ID,
+					Value:          value,
+					ConflictUserID: owner,
+				})
+				conflict = true
+				break
+			}
+		}
+		if conflict {
+			continue
+		}
+		for _, value := range values {
+			owners[strings.ToLower(value)] = user.ID
+		}
+		plan.Changes = append(plan.Changes, *change)
+	}
+	sort.Slice(plan.Changes, func(i, j int) bool {
+		return plan.Changes[i].UserID < plan.Changes[j].UserID
+	})
+	return plan, nil
+}
+
+func planUserDomainMigration(user *User, opts *DomainMigrationOption) (*DomainMigrationChange, bool) {
+	change := &DomainMigrationChange{
+		UserID:    user.ID,
+		UserName:  user.UserName,
+		OldEmails: user.Emails,
+		NewEmails: make([]Email, 0, len(user.Emails)),
+	}
+	// emails of the user, which are compared case-insensitively
+	emails := make(map[string]struct{}, len(user.Emails))
+	for _, email := range user.Emails {
+		emails[strings.ToLower(email.Value)] = struct{}{}
+	}
+	kept := []Email{}
+	changed := false
+	for _, email := range user.Emails {
+		value, ok := replaceEmailDomain(email.Value, opts.OldDomain, opts.NewDomain)
+		if _, found := emails[strings.ToLower(value)]; ok && found {
+			// the email has been migrated, for example the old email kept by KeepOldEmail,
+			// so the email isn't rewritten to avoid the duplicated email.
+			ok = false
+		}
+		if !ok {
+			change.NewEmails = append(change.NewEmails, email)
+			continue
+		}
+		changed = true
+		emails[strings.ToLower(value)] = struct{}{}
+		change.NewEmails = append(change.NewEmails, Email{
+			Value:   value,
+			Type:    email.Type,
+			Primary: email.Primary,
+		})
+		if opts.KeepOldEmail {
+			kept = append(kept, Email{
+				Value: email.Value,
+				Type:  email.Type,
+			})
+		}
+	}
+	change.NewEmails = append(change.NewEmails, kept...)
+	if opts.MigrateUserName {
+		if userName, ok := replaceEmailDomain(user.UserName, opts.OldDomain, opts.NewDomain); ok {
+			change.NewUserName = userName
+			changed = true
+		}
+	}
+	return change, changed
+}
+
+// replaceEmailDomain replaces the domain of email if the domain is oldDomain.
+func replaceEmailDomain(email, oldDomain, newDomain string) (string, bool) {
+	i := strings.LastIndex(email, "@")
+	if i == -1 || !strings.EqualFold(email[i+1:], oldDomain) {
+		return "", false
+	}
+	return email[:i+1] + newDomain, true
+}
+
+// PlanDomainMigration gets all users and plans email domain migration by the function PlanDomainMigration .
+func (c *Client) PlanDomainMigration(ctx context.Context, opts *DomainMigrationOption) (_ *DomainMigrationPlan, err error) {
+	ctx, span := c.startMethodSpan(ctx, "PlanDomainMigration")
+	defer func() {
+		span.end(err)
+	}()
+	users, err := c.GetAllUsers(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return PlanDomainMigration(users, opts)
+}
+
+// ApplyDomainMigration applies changes of the plan by PATCH /Users/{id} API.
+// If checkpoint isn't nil, migrated users are recorded to the checkpoint,
+// and users who have already been recorded are skipped, so an interrupted migration can be resumed with the same plan.
+// A user who can't be patched isn't recorded to the checkpoint, so the user is retried when the plan is applied again,
+// and the user is recorded in the report's Failed while the remaining users are migrated.
+// The migration stops only when the checkpoint can't be recorded.
+func (c *Client) ApplyDomainMigration(
+	ctx context.Context, plan *DomainMigrationPlan, checkpoint *Checkpoint,
+) (_ *DomainMigrationReport, err error) {
+	ctx, span := c.startMethodSpan(ctx, "ApplyDomainMigration")
+	defer func() {
+		span.end(err)
+	}()
+	report := &DomainMigrationReport{
+		Migrated: []string{},
+		Skipped:  []string{},
+	}
+	if plan == nil {
+		return report, fmt.Errorf("plan is required")
+	}
+	for _, change := range plan.Changes {
+		if checkpoint != nil && checkpoint.Done(change.UserID) {
+			report.Skipped = append(report.Skipped, change.UserID)
+			continue
+		}
+		if _, _, err := c.PatchUser(ctx, change.UserID, change.Patch()); err != nil {
+			report.Failed = append(report.Failed, UserError{UserID: change.UserID, Err: err})
+			continue
+		}
+		if checkpoint != nil {
+			if err := checkpoint.MarkDone(change.UserID); err != nil {
+				return report, fmt.Errorf("the user %s is migrated but failed to record the checkpoint: %w", change.UserID, err)
+			}
+		}
+		report.Migrated = append(report.Migrated, change.UserID)
+	}
+	return report, joinErrors("failed to migrate users", len(report.Failed), func(i int) error {
+		return &report.Failed[i]
+	})
+}
@@ -0,0 +1,207 @@
+package scim
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanDomainMigration(t *testing.T) {
+	users := []User{
+		{
+			ID:       "U1",
+			UserName: "alice@old.com",
+			Active:   true,
+			Emails: []Email{
+				{Value: "alice@OLD.com", Type: "work", Primary: true},
+				{Value: "alice@home.example.com", Type: "home"},
+			},
+		},
+		{
+			ID:       "U2",
+			UserName: "bob",
+			Active:   true,
+			Emails:   []Email{{Value: "bob@old.com", Type: "work", Primary: true}},
+		},
+		{
+			// conflicts with U4
+			ID:       "U3",
+			UserName: "carol",
+			Active:   true,
+			Emails:   []Email{{Value: "carol@old.com", Primary: true}},
+		},
+		{
+			ID:       "U4",
+			UserName: "carol2",
+			Active:   true,
+			Emails:   []Email{{Value: "carol@new.com", Primary: true}},
+		},
+		{
+			ID:       "U5",
+			UserName: "dave",
+			Emails:   []Email{{Value: "dave@old.com", Primary: true}},
+		},
+		{
+			ID:       "U6",
+			UserName: "eve",
+			Active:   true,
+			Emails:   []Email{{Value: "eve@other.com", Primary: true}},
+		},
+	}
+	plan, err := PlanDomainMigration(users, &DomainMigrationOption{
+		OldDomain:       "old.com",
+		NewDomain:       "new.com",
+		MigrateUserName: true,
+		KeepOldEmail:    true,
+	})
+	require.Nil(t, err)
+	require.Equal(t, &DomainMigrationPlan{
+		Changes: []DomainMigrationChange{
+			{
+				UserID:      "U1",
+				UserName:    "alice@old.com",
+				NewUserName: "alice@new.com",
+				OldEmails:   users[0].Emails,
+				NewEmails: []Email{
+					{Value: "alice@new.com", Type: "work", Primary: true},
+					{Value: "alice@home.example.com", Type: "home"},
+					{Value: "alice@OLD.com", Type: "work"},
+				},
+			},
+			{
+				UserID:    "U2",
+				UserName:  "bob",
+				OldEmails: users[1].Emails,
+				NewEmails: []Email{
+					{Value: "bob@new.com", Type: "work", Primary: true},
+					{Value: "bob@old.com", Type: "work"},
+				},
+			},
+		},
+		Conflicts: []DomainMigrationConflict{
+			{UserID: "U3", Value: "carol@new.com", ConflictUserID: "U4"},
+		},
+	}, plan)
+	require.Equal(t, &UserPatch{
+		Schemas:  []string{SchemaCore},
+		UserName: "alice@new.com",
+		Emails:   plan.Changes[0].NewEmails,
+	}, plan.Changes[0].Patch())
+
+	plan, err = PlanDomainMigration(users, &DomainMigrationOption{
+		OldDomain:       "old.com",
+		NewDomain:       "new.com",
+		IncludeInactive: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, plan.Changes, 3)
+	require.Equal(t, "", plan.Changes[0].NewUserName)
+	require.Equal(t, []Email{{Value: "dave@new.com", Primary: true}}, plan.Changes[2].NewEmails)
+
+	_, err = PlanDomainMigration(users, nil)
+	require.NotNil(t, err)
+	_, err = PlanDomainMigration(users, &DomainMigrationOption{OldDomain: "old.com", NewDomain: "OLD.com"})
+	require.NotNil(t, err)
+}
+
+func TestPlanDomainMigration_replan(t *testing.T) {
+	opts := &DomainMigrationOption{
+		OldDomain:       "old.com",
+		NewDomain:       "new.com",
+		MigrateUserName: true,
+		KeepOldEmail:    true,
+	}
+	users := []User{
+		{
+			ID:       "U1",
+			UserName: "alice@old.com",
+			Active:   true,
+			Emails: []Email{
+				{Value: "alice@old.com", Type: "work", Primary: true},
+				{Value: "alice@home.example.com", Type: "home"},
+			},
+		},
+	}
+	plan, err := PlanDomainMigration(users, opts)
+	require.Nil(t, err)
+	require.Len(t, plan.Changes, 1)
+	// apply the first plan and plan again
+	users[0].UserName = plan.Changes[0].NewUserName
+	users[0].Emails = plan.Changes[0].NewEmails
+	plan, err = PlanDomainMigration(users, opts)
+	require.Nil(t, err)
+	require.Empty(t, plan.Changes)
+	require.Empty(t, plan.Conflicts)
+
+	// the new address already exists
+	users[0].Emails = []Email{
+		{Value: "alice@old.com", Type: "work", Primary: true},
+		{Value: "ALICE@new.com", Type: "work"},
+	}
+	users[0].UserName = "alice@old.com"
+	plan, err = PlanDomainMigration(users, opts)
+	require.Nil(t, err)
+	require.Len(t, plan.Changes, 1)
+	require.Equal(t, "alice@new.com", plan.Changes[0].NewUserName)
+	require.Equal(t, users[0].Emails, plan.Changes[0].NewEmails)
+}
+
+func TestClient_ApplyDomainMigration(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cp, err := OpenCheckpoint(filepath.Join(dir, "checkpoint.jsonl"))
+	require.Nil(t, err)
+	defer cp.Close()
+	require.Nil(t, cp.MarkDone("U1"))
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	plan := &DomainMigrationPlan{
+		Changes: []DomainMigrationChange{
+			{UserID: "U1", NewEmails: []Email{{Value: "alice@new.com", Primary: true}}},
+			{UserID: "U2", NewEmails: []Email{{Value: "bob@new.com", Primary: true}}},
+			{UserID: "U3", NewEmails: []Email{{Value: "carol@new.com", Primary: true}}},
+		},
+	}
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U2").
+		BodyString(`{"emails":[{"value":"bob@new.com","primary":true}],"schemas":["urn:scim:schemas:core:1.0"]}`).
+		Reply(200).
+		JSON(&User{ID: "U2"})
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U3").
+		Reply(500)
+
+	report, err := client.ApplyDomainMigration(ctx, plan, cp)
+	require.NotNil(t, err)
+	require.Equal(t, []string{"U2"}, report.Migrated)
+	require.Equal(t, []string{"U1"}, report.Skipped)
+	require.Len(t, report.Failed, 1)
+	require.Equal(t, "U3", report.Failed[0].UserID)
+	require.True(t, cp.Done("U2"))
+	require.False(t, cp.Done("U3"))
+	require.True(t, gock.IsDone())
+
+	// resume
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U3").
+		Reply(200).
+		JSON(&User{ID: "U3"})
+	report, err = client.ApplyDomainMigration(ctx, plan, cp)
+	require.Nil(t, err)
+	require.Equal(t, []string{"U3"}, report.Migrated)
+	require.Equal(t, []string{"U1", "U2"}, report.Skipped)
+	require.True(t, gock.IsDone())
+
+	_, err = client.ApplyDomainMigration(ctx, nil, nil)
+	require.NotNil(t, err)
+}
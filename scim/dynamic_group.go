@@ -0,0 +1,243 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+)
+
+type (
+	// DynamicGroup is a group whose members are computed by the rule instead of maintained by hand.
+	// Rule is an expression parsed by ParseExpr, and it is evaluated for each user.
+	// In the rule, user's attributes are referred by SCIM attribute paths (see LookupAttribute),
+	// and managerChain is a list of ids of the user's manager, the manager's manager, and so on.
+	// See Expr for the semantics of comparisons with lists and missing attributes.
+	//
+	//   active && urn:scim:schemas:extension:enterprise:1.0.department == "Engineering"
+	//   active && managerChain == "U0XXXXXXX"
+	DynamicGroup struct {
+		GroupID string `json:"groupId"`
+		Rule    string `json:"rule"`
+	}
+
+	// DynamicGroupOption is an option to sync dynamic groups.
+	DynamicGroupOption struct {
+		// If Preview is true, the plan is computed but the group isn't updated.
+		Preview bool
+		// MaxChanges is the maximum number of members added to and removed from a group.
+		// If the plan exceeds MaxChanges, the group isn't updated and TooManyChangesError is returned.
+		// If MaxChanges is zero, the number of changes isn't limited.
+		MaxChanges int
+	}
+
+	// DynamicGroupPlan is members which should be added to and removed from a dynamic group.
+	DynamicGroupPlan struct {
+		GroupID string
+		Add     []Member
+		Remove  []Member
+		// Applied is true if the plan is applied to the group.
+		Applied bool
+	}
+
+	// TooManyChangesError is returned when a dynamic group's plan exceeds DynamicGroupOption.MaxChanges .
+	TooManyChangesError struct {
+		GroupID    string
+		Changes    int
+		MaxChanges int
+	}
+)
+
+// Error returns the error message.
+func (e *TooManyChangesError) Error() string {
+	return fmt.Sprintf("group %s: the number of changes %d exceeds the limit %d", e.GroupID, e.Changes, e.MaxChanges)
+}
+
+// Changes returns the number of members added and removed.
+func (plan *DynamicGroupPlan) Changes() int {
+	return len(plan.Add) + len(plan.Remove)
+}
+
+// PlanDynamicGroup computes members which should be added to and removed from group.
+// users should be all users of the workspace.
+// Current members which aren't included in users are removed from the group.
+func PlanDynamicGroup(rule *Expr, group *Group, users []User) (*DynamicGroupPlan, error) {
+	if rule == nil {
+		return nil, fmt.Errorf("rule is required")
+	}
+	if group == nil {
+		return nil, fmt.Errorf("group is required")
+	}
+	index := make(map[string]*User, len(users))
+	for i := range users {
+		index[users[i].ID] = &users[i]
+	}
+	desired := map[string]struct{}{}
+	plan := &DynamicGroupPlan{
+		GroupID: group.ID,
+	}
+	current := make(map[string]struct{}, len(group.Members))
+	for _, member := range group.Members {
+		current[member.Value] = struct{}{}
+	}
+	for i := range users {
+		user := &users[i]
+		env, err := newUserExprEnv(user, index)
+		if err != nil {
+			return nil, err
+		}
+		matched, err := rule.EvalBool(env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate the rule for the user %s: %w", user.ID, err)
+		}
+		if !matched {
+			continue
+		}
+		desired[user.ID] = struct{}{}
+		if _, ok := current[user.ID]; !ok {
+			plan.Add = append(plan.Add, Member{
+				Value:   user.ID,
+				Display: user.DisplayName,
+			})
+		}
+	}
+	for _, member := range group.Members {
+		if _, ok := desired[member.Value]; !ok {
+			plan.Remove = append(plan.Remove, Member{
+				Value:     member.Value,
+				Display:   member.Display,
+				Operation: MemberOperationDelete,
+			})
+		}
+	}
+	return plan, nil
+}
+
+// SyncDynamicGroup computes the plan of the dynamic group and applies it to the group by PATCH /Groups/{id} API.
+// users should be all users of the workspace.
+func (c *Client) SyncDynamicGroup(
+	ctx context.Context, dg *DynamicGroup, users []User, opts *DynamicGroupOption,
+) (_ *DynamicGroupPlan, err error) {
+	ctx, span := c.startMethodSpan(ctx, "SyncDynamicGroup")
+	defer func() {
+		span.end(err)
+	}()
+	if dg == nil {
+		return nil, fmt.Errorf("dynamic group is required")
+	}
+	if opts == nil {
+		opts = &DynamicGroupOption{}
+	}
+	rule, err := ParseExpr(dg.Rule)
+	if err != nil {
+		return nil, err
+	}
+	group, _, err := c.GetGroup(ctx, dg.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the group %s: %w", dg.GroupID, err)
+	}
+	plan, err := PlanDynamicGroup(rule, group, users)
+	if err != nil {
+		return nil, err
+	}
+	plan.GroupID = dg.GroupID
+	if opts.MaxChanges > 0 && plan.Changes() > opts.MaxChanges {
+		return plan, &TooManyChangesError{
+			GroupID:    dg.GroupID,
+			Changes:    plan.Changes(),
+			MaxChanges: opts.MaxChanges,
+		}
+	}
+	if opts.Preview || plan.Changes() == 0 {
+		return plan, nil
+	}
+	members := make([]Member, 0, plan.Changes())
+	members = append(members, plan.Add...)
+	members = append(members, plan.Remove...)
+	if _, _, err := c.PatchGroupMembers(ctx, dg.GroupID, members); err != nil && !isPatchedGroupError(err) {
+		return plan, fmt.Errorf("failed to update the group %s: %w", dg.GroupID, err)
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+// SyncDynamicGroups gets all users and syncs dynamic groups.
+// A group whose rule can't be evaluated or whose plan exceeds opts.MaxChanges doesn't stop syncing the other groups,
+// and the errors of such groups are joined into the returned error.
+// The returned plans are sorted in the order of dgs, and the plan of the failed group may be nil.
+func (c *Client) SyncDynamicGroups(
+	ctx context.Context, dgs []DynamicGroup, opts *DynamicGroupOption,
+) (_ []*DynamicGroupPlan, err error) {
+	ctx, span := c.startMethodSpan(ctx, "SyncDynamicGroups")
+	defer func() {
+		span.end(err)
+	}()
+	users, err := c.GetAllUsers(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	plans := make([]*DynamicGroupPlan, len(dgs))
+	errs := []GroupError{}
+	for i := range dgs {
+		plan, err := c.SyncDynamicGroup(ctx, &dgs[i], users, opts)
+		plans[i] = plan
+		if err != nil {
+			errs = append(errs, GroupError{
+				GroupID: dgs[i].GroupID,
+				Err:     err,
+			})
+		}
+	}
+	return plans, groupErrorsToError(errs)
+}
+
+// newUserExprEnv returns an environment to evaluate rules against user.
+// index is a map of all users whose keys are user ids, and it is used to compute managerChain.
+func newUserExprEnv(user *User, index map[string]*User) (*ExprEnv, error) {
+	attrs, err := UserAttributes(user)
+	if err != nil {
+		return nil, err
+	}
+	return &ExprEnv{
+		Resolve: func(name string) interface{} {
+			if name == "managerChain" {
+				chain := managerChain(user, index)
+				list := make([]interface{}, len(chain))
+				for i, id := range chain {
+					list[i] = id
+				}
+				return list
+			}
+			v, _ := LookupAttribute(attrs, name)
+			return v
+		},
+	}, nil
+}
+
+// managerChain returns ids of the user's manager, the manager's manager, and so on.
+// If the chain has a cycle, the chain is cut before the cycle.
+func managerChain(user *User, index map[string]*User) []string {
+	chain := []string{}
+	visited := map[string]struct{}{user.ID: {}}
+	for {
+		id := managerID(user)
+		if id == "" {
+			return chain
+		}
+		if _, ok := visited[id]; ok {
+			return chain
+		}
+		visited[id] = struct{}{}
+		chain = append(chain, id)
+		manager, ok := index[id]
+		if !ok {
+			return chain
+		}
+		user = manager
+	}
+}
+
+func managerID(user *User) string {
+	if user.EnterpriseUserSchemaExtension == nil || user.EnterpriseUserSchemaExtension.Manager == nil {
+		return ""
+	}
+	return user.EnterpriseUserSchemaExtension.Manager.ManagerID
+}
@@ -0,0 +1,176 @@
+package scim
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testDynamicGroupUsers() []User {
+	return []User{
+		{
+			ID: "U1", Active: true, DisplayName: "boss",
+			EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{Department: "Engineering"},
+		},
+		{
+			ID: "U2", Active: true, DisplayName: "foo",
+			EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+				Department: "Engineering",
+				Manager:    &Manager{ManagerID: "U1"},
+			},
+		},
+		{
+			ID: "U3", DisplayName: "bar",
+			EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+				Department: "Engineering",
+				Manager:    &Manager{ManagerID: "U2"},
+			},
+		},
+		{
+			ID: "U4", Active: true, DisplayName: "zoo",
+			EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+				Department: "Sales",
+				Manager:    &Manager{ManagerID: "U4"},
+			},
+		},
+	}
+}
+
+func TestPlanDynamicGroup(t *testing.T) {
+	users := testDynamicGroupUsers()
+	group := &Group{
+		ID: "G1",
+		Members: []Member{
+			{Value: "U1", Display: "boss"},
+			{Value: "U3", Display: "bar"},
+			{Value: "U9", Display: "unknown"},
+		},
+	}
+	data := []struct {
+		rule    string
+		add     []Member
+		remove  []Member
+		isError bool
+	}{
+		{
+			rule: `active && urn:scim:schemas:extension:enterprise:1.0.department == "Engineering"`,
+			add:  []Member{{Value: "U2", Display: "foo"}},
+			remove: []Member{
+				{Value: "U3", Display: "bar", Operation: "delete"},
+				{Value: "U9", Display: "unknown", Operation: "delete"},
+			},
+		},
+		{
+			rule: `managerChain == "U1"`,
+			add:  []Member{{Value: "U2", Display: "foo"}},
+			remove: []Member{
+				{Value: "U1", Display: "boss", Operation: "delete"},
+				{Value: "U9", Display: "unknown", Operation: "delete"},
+			},
+		},
+		{
+			rule:    `lower(title, title)`,
+			isError: true,
+		},
+	}
+	for _, d := range data {
+		rule, err := ParseExpr(d.rule)
+		require.Nil(t, err, d.rule)
+		plan, err := PlanDynamicGroup(rule, group, users)
+		if d.isError {
+			require.NotNil(t, err, d.rule)
+			continue
+		}
+		require.Nil(t, err, d.rule)
+		require.Equal(t, "G1", plan.GroupID)
+		require.Equal(t, d.add, plan.Add, d.rule)
+		require.Equal(t, d.remove, plan.Remove, d.rule)
+	}
+
+	_, err := PlanDynamicGroup(nil, group, users)
+	require.NotNil(t, err)
+	rule, err := ParseExpr("active")
+	require.Nil(t, err)
+	_, err = PlanDynamicGroup(rule, nil, users)
+	require.NotNil(t, err)
+}
+
+func Test_managerChain(t *testing.T) {
+	users := testDynamicGroupUsers()
+	index := map[string]*User{}
+	for i := range users {
+		index[users[i].ID] = &users[i]
+	}
+	require.Equal(t, []string{}, managerChain(&users[0], index))
+	require.Equal(t, []string{"U2", "U1"}, managerChain(&users[2], index))
+	require.Equal(t, []string{}, managerChain(&users[3], index))
+}
+
+func TestClient_SyncDynamicGroups(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	dgs := []DynamicGroup{
+		{GroupID: "G1", Rule: `active && urn:scim:schemas:extension:enterprise:1.0.department == "Engineering"`},
+		{GroupID: "G2", Rule: `active`},
+	}
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("startIndex", "1").
+		Reply(200).
+		JSON(&Users{TotalResults: 4, Resources: testDynamicGroupUsers()})
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}, {"value": "U3"}]}`)
+	// the group before the patch
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}, {"value": "U3"}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore},
+			"members": []map[string]string{
+				{"value": "U2", "display": "foo"},
+				{"value": "U3", "display": "", "operation": "delete"},
+			},
+		}).
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}, {"value": "U2"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G2").
+		Reply(200).
+		BodyString(`{"id": "G2", "members": []}`)
+
+	plans, err := client.SyncDynamicGroups(ctx, dgs, &DynamicGroupOption{MaxChanges: 2})
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+	require.Len(t, plans, 2)
+	require.True(t, plans[0].Applied)
+	require.False(t, plans[1].Applied)
+	require.Equal(t, 3, plans[1].Changes())
+
+	// preview
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": []}`)
+	plan, err := client.SyncDynamicGroup(ctx, &dgs[0], testDynamicGroupUsers(), &DynamicGroupOption{Preview: true})
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.False(t, plan.Applied)
+	require.Equal(t, 2, plan.Changes())
+
+	_, err = client.SyncDynamicGroup(ctx, nil, nil, nil)
+	require.NotNil(t, err)
+	_, err = client.SyncDynamicGroup(ctx, &DynamicGroup{GroupID: "G1", Rule: "("}, nil, nil)
+	require.NotNil(t, err)
+}
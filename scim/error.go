@@ -1,5 +1,10 @@
 package scim
 
+import (
+	"fmt"
+	"strings"
+)
+
 type (
 	// Error is Slack SCIM API's error response body.
 	// https://api.slack.com/scim#errors
@@ -13,3 +18,17 @@ type (
 func (e *Error) Error() string {
 	return e.Description
 }
+
+// joinErrors returns an error whose message is msg followed by messages of errors which are got by errorAt,
+// where i is from 0 to n - 1.
+// If n is zero, joinErrors returns nil.
+func joinErrors(msg string, n int, errorAt func(i int) error) error {
+	if n == 0 {
+		return nil
+	}
+	msgs := make([]string, n)
+	for i := range msgs {
+		msgs[i] = errorAt(i).Error()
+	}
+	return fmt.Errorf("%s: %s", msg, strings.Join(msgs, ", "))
+}
@@ -0,0 +1,654 @@
+package scim
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type (
+	// Expr is a parsed rule expression.
+	// Expr should be created by the function ParseExpr .
+	//
+	// The grammar of the expression is the following.
+	//
+	//   expr    = and { "||" and }
+	//   and     = unary { "&&" unary }
+	//   unary   = "!" unary | compare
+	//   compare = operand [ ( "==" | "!=" | "=~" ) operand ]
+	//   operand = "string" | number | true | false | null | name | name "(" [ expr { "," expr } ] ")" | "(" expr ")"
+	//
+	// name is an attribute such as userName, name.givenName, emails[primary].value and
+	// urn:scim:schemas:extension:enterprise:1.0.department , and it is resolved by ExprEnv.Resolve .
+	// =~ is a regular expression match. Use !(a =~ b) and !(a == b) for negative matches.
+	//
+	// Comparisons are evaluated as the following.
+	//
+	//   - An attribute without values, that is, a missing attribute, null or an empty list, doesn't satisfy any comparison
+	//     except "== null", so both a == "x" and a != "x" are false. "a == null" is true and "a != null" is false.
+	//   - If the left operand is a list such as a multi-valued attribute, a == b and a =~ b are true if any value satisfies them,
+	//     and a != b is true if no value equals b.
+	//   - Values of different types aren't equal, for example "1" == 1 is false.
+	//
+	// The following functions are built in.
+	//
+	//   lower(s), upper(s), trim(s)
+	//   concat(a, b, ...)               concatenates values. null is ignored.
+	//   join(list, sep)                 joins values of list with sep.
+	//   replace(s, pattern, repl)       replaces matches of the regular expression pattern with repl.
+	//   if(cond, then, else)            returns then if cond is truthy, otherwise else. else is optional.
+	//   coalesce(a, b, ...)             returns the first value which isn't null or empty.
+	//
+	// Arguments of if and coalesce are evaluated lazily, so unused arguments aren't resolved.
+	// null, false, empty string, zero and empty list are falsy.
+	Expr struct {
+		src  string
+		root exprNode
+	}
+
+	// ExprEnv is an environment to evaluate Expr.
+	ExprEnv struct {
+		// Resolve returns the value of the identifier.
+		// If Resolve returns nil, the identifier is evaluated as null.
+		Resolve func(name string) interface{}
+		// Functions are functions which can be called in the expression in addition to the built-in functions.
+		Functions map[string]ExprFunc
+	}
+
+	// ExprFunc is a function called in Expr.
+	ExprFunc func(args []interface{}) (interface{}, error)
+
+	exprNode interface {
+		eval(env *ExprEnv) (interface{}, error)
+	}
+
+	literalNode struct {
+		value interface{}
+	}
+
+	identNode struct {
+		name string
+	}
+
+	notNode struct {
+		operand exprNode
+	}
+
+	logicalNode struct {
+		op          string
+		left, right exprNode
+	}
+
+	compareNode struct {
+		op          string
+		left, right exprNode
+		re          *regexp.Regexp
+	}
+
+	callNode struct {
+		name string
+		args []exprNode
+	}
+
+	exprToken struct {
+		kind  string
+		value string
+		pos   int
+	}
+
+	exprParser struct {
+		tokens []exprToken
+		pos    int
+	}
+)
+
+const (
+	tokenIdent  = "ident"
+	tokenString = "string"
+	tokenNumber = "number"
+	tokenOp     = "op"
+	tokenEOF    = "eof"
+)
+
+var builtinExprFuncs = map[string]ExprFunc{
+	"lower": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("lower requires one argument")
+		}
+		return strings.ToLower(toString(args[0])), nil
+	},
+	"upper": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("upper requires one argument")
+		}
+		return strings.ToUpper(toString(args[0])), nil
+	},
+	"trim": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("trim requires one argument")
+		}
+		return strings.TrimSpace(toString(args[0])), nil
+	},
+	"concat": func(args []interface{}) (interface{}, error) {
+		b := strings.Builder{}
+		for _, arg := range args {
+			b.WriteString(toString(arg))
+		}
+		return b.String(), nil
+	},
+	"join": func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("join requires two arguments")
+		}
+		list, ok := args[0].([]interface{})
+		if !ok {
+			return toString(args[0]), nil
+		}
+		values := make([]string, len(list))
+		for i, v := range list {
+			values[i] = toString(v)
+		}
+		return strings.Join(values, toString(args[1])), nil
+	},
+	"replace": func(args []interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("replace requires three arguments")
+		}
+		if args[0] == nil {
+			return nil, nil
+		}
+		re, err := regexp.Compile(toString(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		return re.ReplaceAllString(toString(args[0]), toString(args[2])), nil
+	},
+}
+
+// lazyExprFuncs are built-in functions whose arguments are evaluated lazily.
+var lazyExprFuncs = map[string]func(env *ExprEnv, args []exprNode) (interface{}, error){
+	"if": func(env *ExprEnv, args []exprNode) (interface{}, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("if requires two or three arguments")
+		}
+		cond, err := args[0].eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return args[1].eval(env)
+		}
+		if len(args) == 3 {
+			return args[2].eval(env)
+		}
+		return nil, nil
+	},
+	"coalesce": func(env *ExprEnv, args []exprNode) (interface{}, error) {
+		for _, arg := range args {
+			v, err := arg.eval(env)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil && v != "" {
+				return v, nil
+			}
+		}
+		return nil, nil
+	},
+}
+
+// ParseExpr parses a rule expression.
+func ParseExpr(src string) (*Expr, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the expression %s: %w", src, err)
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("failed to parse the expression %s: unexpected token %s at %d", src, tok.value, tok.pos)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression and returns the value.
+func (e *Expr) Eval(env *ExprEnv) (interface{}, error) {
+	if env == nil {
+		env = &ExprEnv{}
+	}
+	return e.root.eval(env)
+}
+
+// EvalBool evaluates the expression and returns whether the value is truthy.
+// null, false, empty string, zero and empty list are falsy.
+func (e *Expr) EvalBool(env *ExprEnv) (bool, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+func tokenizeExpr(src string) ([]exprToken, error) {
+	tokens := []exprToken{}
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			start := i
+			b := strings.Builder{}
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: tokenString, value: b.String(), pos: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, value: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) {
+				c := runes[i]
+				if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' || c == ':' || c == '-' {
+					i++
+					continue
+				}
+				if c == '[' {
+					// selector of a multi-valued attribute
+					end := i
+					for end < len(runes) && runes[end] != ']' {
+						end++
+					}
+					if end >= len(runes) {
+						return nil, fmt.Errorf("unterminated selector at %d", i)
+					}
+					i = end + 1
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, value: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			op := ""
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", "=~", "&&", "||":
+					op = two
+				}
+			}
+			if op == "" {
+				switch r {
+				case '(', ')', ',', '!':
+					op = string(r)
+				default:
+					return nil, fmt.Errorf("unexpected character %c at %d", r, i)
+				}
+			}
+			i += len([]rune(op))
+			tokens = append(tokens, exprToken{kind: tokenOp, value: op, pos: start})
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) isOp(tok exprToken, ops ...string) bool {
+	for _, op := range ops {
+		if tok.kind == tokenOp && tok.value == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) expect(op string) error {
+	tok := p.next()
+	if !p.isOp(tok, op) {
+		return fmt.Errorf("%s is expected at %d", op, tok.pos)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(p.peek(), "||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(p.peek(), "&&") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.isOp(p.peek(), "!") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	if !p.isOp(tok, "==", "!=", "=~") {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	node := &compareNode{op: tok.value, left: left, right: right}
+	if lit, ok := right.(*literalNode); ok && node.op == "=~" {
+		re, err := regexp.Compile(toString(lit.value))
+		if err != nil {
+			return nil, err
+		}
+		node.re = re
+	}
+	return node, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{value: tok.value}, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at %d", tok.value, tok.pos)
+		}
+		return &literalNode{value: f}, nil
+	case tokenIdent:
+		switch tok.value {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if !p.isOp(p.peek(), "(") {
+			return &identNode{name: tok.value}, nil
+		}
+		p.next()
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return &callNode{name: tok.value, args: args}, nil
+	case tokenOp:
+		if tok.value == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	if tok.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of the expression")
+	}
+	return nil, fmt.Errorf("unexpected token %s at %d", tok.value, tok.pos)
+}
+
+// parseArgs parses arguments of a function call after "(".
+func (p *exprParser) parseArgs() ([]exprNode, error) {
+	args := []exprNode{}
+	if p.isOp(p.peek(), ")") {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		tok := p.next()
+		if p.isOp(tok, ")") {
+			return args, nil
+		}
+		if !p.isOp(tok, ",") {
+			return nil, fmt.Errorf(") or , is expected at %d", tok.pos)
+		}
+	}
+}
+
+func (node *literalNode) eval(env *ExprEnv) (interface{}, error) {
+	return node.value, nil
+}
+
+func (node *identNode) eval(env *ExprEnv) (interface{}, error) {
+	if env.Resolve == nil {
+		return nil, nil
+	}
+	return env.Resolve(node.name), nil
+}
+
+func (node *notNode) eval(env *ExprEnv) (interface{}, error) {
+	v, err := node.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+func (node *logicalNode) eval(env *ExprEnv) (interface{}, error) {
+	left, err := node.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if node.op == "&&" && !truthy(left) {
+		return false, nil
+	}
+	if node.op == "||" && truthy(left) {
+		return true, nil
+	}
+	right, err := node.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right), nil
+}
+
+func (node *compareNode) eval(env *ExprEnv) (interface{}, error) {
+	left, err := node.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := node.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	values := exprValues(left)
+	if right == nil && (node.op == "==" || node.op == "!=") {
+		// comparisons with null test whether the attribute has values
+		return (len(values) == 0) == (node.op == "=="), nil
+	}
+	switch node.op {
+	case "==":
+		return anyValue(values, func(v interface{}) bool { return equalValues(v, right) }), nil
+	case "!=":
+		return len(values) != 0 && !anyValue(values, func(v interface{}) bool { return equalValues(v, right) }), nil
+	case "=~":
+		re := node.re
+		if re == nil {
+			re, err = regexp.Compile(toString(right))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return anyValue(values, func(v interface{}) bool { return re.MatchString(toString(v)) }), nil
+	}
+	return nil, fmt.Errorf("unknown operator: %s", node.op)
+}
+
+func (node *callNode) eval(env *ExprEnv) (interface{}, error) {
+	fn, ok := env.Functions[node.name]
+	if !ok {
+		if lazy, ok := lazyExprFuncs[node.name]; ok {
+			v, err := lazy(env, node.args)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", node.name, err)
+			}
+			return v, nil
+		}
+		fn, ok = builtinExprFuncs[node.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function: %s", node.name)
+		}
+	}
+	args := make([]interface{}, len(node.args))
+	for i, arg := range node.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", node.name, err)
+	}
+	return v, nil
+}
+
+// exprValues returns values of v as a list.
+// null and an empty list have no values, and other values than a list are a list with one element.
+func exprValues(v interface{}) []interface{} {
+	if v == nil {
+		return nil
+	}
+	if list, ok := v.([]interface{}); ok {
+		values := make([]interface{}, 0, len(list))
+		for _, elem := range list {
+			if elem != nil {
+				values = append(values, elem)
+			}
+		}
+		return values
+	}
+	return []interface{}{v}
+}
+
+func anyValue(values []interface{}, fn func(v interface{}) bool) bool {
+	for _, v := range values {
+		if fn(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(a, b interface{}) bool {
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case float64:
+		y, ok := b.(float64)
+		return ok && x == y
+	}
+	return false
+}
+
+func truthy(v interface{}) bool {
+	switch a := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return a
+	case string:
+		return a != ""
+	case float64:
+		return a != 0
+	case []interface{}:
+		return len(a) != 0
+	}
+	return true
+}
+
+func toString(v interface{}) string {
+	switch a := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return a
+	case float64:
+		return strconv.FormatFloat(a, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,191 @@
+package scim
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpr(t *testing.T) {
+	data := []struct {
+		src     string
+		isError bool
+	}{
+		{src: `active`},
+		{src: `title == "Engineer" && (userType == "Employee" || !active)`},
+		{src: `emails[primary].value =~ "@example\\.com$"`},
+		{src: `!(lower(title) =~ "eng")`},
+		{src: ``, isError: true},
+		{src: `title ==`, isError: true},
+		{src: `"foo`, isError: true},
+		{src: `emails[primary.value`, isError: true},
+		{src: `(active`, isError: true},
+		{src: `active active`, isError: true},
+		{src: `title =~ "("`, isError: true},
+		{src: `title # "a"`, isError: true},
+		{src: `concat(title title)`, isError: true},
+		// removed syntax
+		{src: `title == 'Engineer'`, isError: true},
+		{src: `title !~ "a"`, isError: true},
+		{src: `not active`, isError: true},
+		{src: `title in ["a", "b"]`, isError: true},
+		{src: `title contains "a"`, isError: true},
+	}
+	for _, d := range data {
+		expr, err := ParseExpr(d.src)
+		if d.isError {
+			require.NotNil(t, err, d.src)
+			continue
+		}
+		require.Nil(t, err, d.src)
+		require.Equal(t, d.src, expr.String())
+	}
+}
+
+func TestExpr_Eval(t *testing.T) {
+	vars := map[string]interface{}{
+		"title":  "Senior Engineer",
+		"active": true,
+		"count":  float64(3),
+		"emails": []interface{}{"foo@example.com", "bar@example.org"},
+	}
+	env := &ExprEnv{
+		Resolve: func(name string) interface{} {
+			return vars[name]
+		},
+		Functions: map[string]ExprFunc{
+			"fail": func(args []interface{}) (interface{}, error) {
+				return nil, fmt.Errorf("fail")
+			},
+		},
+	}
+	data := []struct {
+		src     string
+		exp     interface{}
+		isError bool
+	}{
+		{src: `title`, exp: "Senior Engineer"},
+		{src: `title == "Senior Engineer"`, exp: true},
+		{src: `title != "Senior Engineer"`, exp: false},
+		{src: `unknown == null`, exp: true},
+		{src: `count == 3`, exp: true},
+		{src: `active && title =~ "Engineer"`, exp: true},
+		{src: `!active || false`, exp: false},
+		{src: `!active || count`, exp: true},
+		{src: `title =~ "^senior"`, exp: false},
+		{src: `title =~ "(?i)^senior"`, exp: true},
+		{src: `!(title =~ "Manager")`, exp: true},
+		{src: `emails =~ "@example\\.org$"`, exp: true},
+		{src: `emails == "bar@example.org"`, exp: true},
+		{src: `lower(title) == "senior engineer"`, exp: true},
+		{src: `upper(trim(" a ")) == "A"`, exp: true},
+		{src: `title =~ title`, exp: true},
+		{src: `concat(lower(title), "-", count, unknown)`, exp: "senior engineer-3"},
+		{src: `join(emails, ", ")`, exp: "foo@example.com, bar@example.org"},
+		{src: `join(title, ", ")`, exp: "Senior Engineer"},
+		{src: `replace(title, "\\s+", "_")`, exp: "Senior_Engineer"},
+		{src: `replace(unknown, "a", "b")`, exp: nil},
+		{src: `if(active, "yes", "no")`, exp: "yes"},
+		{src: `if(unknown, "yes", "no")`, exp: "no"},
+		{src: `if(unknown, "yes")`, exp: nil},
+		{src: `if(active, "yes", fail())`, exp: "yes"},
+		{src: `coalesce(unknown, "", title, fail())`, exp: "Senior Engineer"},
+		{src: `coalesce(unknown)`, exp: nil},
+		{src: `if(active)`, isError: true},
+		{src: `join(emails)`, isError: true},
+		{src: `replace(title, "(", "")`, isError: true},
+		{src: `foo(title)`, isError: true},
+		{src: `fail()`, isError: true},
+		{src: `lower(title, title)`, isError: true},
+		{src: `title =~ unknown + "("`, isError: true},
+	}
+	for _, d := range data {
+		expr, err := ParseExpr(d.src)
+		if err != nil {
+			require.True(t, d.isError, d.src)
+			continue
+		}
+		v, err := expr.Eval(env)
+		if d.isError {
+			require.NotNil(t, err, d.src)
+			continue
+		}
+		require.Nil(t, err, d.src)
+		require.Equal(t, d.exp, v, d.src)
+	}
+}
+
+func TestExpr_Eval_noValues(t *testing.T) {
+	vars := map[string]interface{}{
+		"title":  "Engineer",
+		"emails": []interface{}{"foo@example.com", "bar@example.org"},
+		"empty":  []interface{}{},
+		"nulls":  []interface{}{nil},
+	}
+	env := &ExprEnv{
+		Resolve: func(name string) interface{} {
+			return vars[name]
+		},
+	}
+	data := []struct {
+		src string
+		exp bool
+	}{
+		// a missing attribute doesn't satisfy any comparison except == null
+		{src: `missing == "a"`},
+		{src: `missing != "a"`},
+		{src: `missing =~ ""`},
+		{src: `missing == null`, exp: true},
+		{src: `missing != null`},
+		{src: `!(missing == "a")`, exp: true},
+		// an empty list and a list of null are the same as a missing attribute
+		{src: `empty == "a"`},
+		{src: `empty != "a"`},
+		{src: `empty =~ ""`},
+		{src: `empty == null`, exp: true},
+		{src: `empty != null`},
+		{src: `nulls != "a"`},
+		{src: `nulls == null`, exp: true},
+		// a list satisfies == and =~ if any value satisfies them, and != if no value equals
+		{src: `emails == "foo@example.com"`, exp: true},
+		{src: `emails == "baz@example.com"`},
+		{src: `emails != "foo@example.com"`},
+		{src: `emails != "baz@example.com"`, exp: true},
+		{src: `emails =~ "\\.org$"`, exp: true},
+		{src: `emails != null`, exp: true},
+		// a single value
+		{src: `title != "Manager"`, exp: true},
+		{src: `title != null`, exp: true},
+		{src: `title == 1`},
+		{src: `title != 1`, exp: true},
+	}
+	for _, d := range data {
+		expr, err := ParseExpr(d.src)
+		require.Nil(t, err, d.src)
+		v, err := expr.Eval(env)
+		require.Nil(t, err, d.src)
+		require.Equal(t, d.exp, v, d.src)
+	}
+}
+
+func TestExpr_EvalBool(t *testing.T) {
+	data := []struct {
+		src string
+		exp bool
+	}{
+		{src: `null`},
+		{src: `""`},
+		{src: `0`},
+		{src: `missing`},
+		{src: `"a"`, exp: true},
+		{src: `1`, exp: true},
+	}
+	for _, d := range data {
+		expr, err := ParseExpr(d.src)
+		require.Nil(t, err, d.src)
+		v, err := expr.EvalBool(nil)
+		require.Nil(t, err, d.src)
+		require.Equal(t, d.exp, v, d.src)
+	}
+}
@@ -0,0 +1,208 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type (
+	// ExtensionFactory returns a pointer to a new value of a typed schema extension.
+	// The returned value is used to decode the extension's JSON.
+	ExtensionFactory func() interface{}
+
+	extensionRegistry struct {
+		factories map[string]ExtensionFactory
+		mutex     sync.RWMutex
+	}
+)
+
+var (
+	userExtensions  = &extensionRegistry{factories: map[string]ExtensionFactory{}}
+	groupExtensions = &extensionRegistry{factories: map[string]ExtensionFactory{}}
+
+	userKnownAttributes  = knownJSONKeys(reflect.TypeOf(User{}))
+	groupKnownAttributes = knownJSONKeys(reflect.TypeOf(Group{}))
+)
+
+// RegisterUserExtension registers a typed schema extension of User.
+// When a user is decoded, the attribute urn is decoded to the value returned by factory and stored in User.Extensions .
+// When a user is encoded, User.Extensions[urn] is encoded as the attribute urn.
+// Extensions defined by this package such as SchemaEnterpriseUser can't be registered.
+func RegisterUserExtension(urn string, factory ExtensionFactory) error {
+	return userExtensions.register(urn, factory, userKnownAttributes)
+}
+
+// RegisterGroupExtension registers a typed schema extension of Group.
+// See RegisterUserExtension .
+func RegisterGroupExtension(urn string, factory ExtensionFactory) error {
+	return groupExtensions.register(urn, factory, groupKnownAttributes)
+}
+
+func (reg *extensionRegistry) register(urn string, factory ExtensionFactory, known map[string]struct{}) error {
+	if urn == "" {
+		return fmt.Errorf("urn is required")
+	}
+	if factory == nil {
+		return fmt.Errorf("factory is required")
+	}
+	if _, ok := known[strings.ToLower(urn)]; ok {
+		return fmt.Errorf("%s is a built-in attribute", urn)
+	}
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+	reg.factories[urn] = factory
+	return nil
+}
+
+func (reg *extensionRegistry) get(urn string) (ExtensionFactory, bool) {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+	factory, ok := reg.factories[urn]
+	return factory, ok
+}
+
+// knownJSONKeys returns lower case JSON keys of the struct type's fields.
+func knownJSONKeys(t reflect.Type) map[string]struct{} {
+	keys := map[string]struct{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		keys[strings.ToLower(name)] = struct{}{}
+	}
+	return keys
+}
+
+// decodeExtraAttributes returns attributes of b which aren't included in known.
+// Attributes registered in reg are decoded to typed values.
+func decodeExtraAttributes(
+	b []byte, known map[string]struct{}, reg *extensionRegistry,
+) (map[string]json.RawMessage, map[string]interface{}, error) {
+	all := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, nil, err
+	}
+	var (
+		extra      map[string]json.RawMessage
+		extensions map[string]interface{}
+	)
+	for k, v := range all {
+		if _, ok := known[strings.ToLower(k)]; ok {
+			continue
+		}
+		if factory, ok := reg.get(k); ok {
+			ext := factory()
+			if err := json.Unmarshal(v, ext); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode the extension %s: %w", k, err)
+			}
+			if extensions == nil {
+				extensions = map[string]interface{}{}
+			}
+			extensions[k] = ext
+			continue
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[k] = v
+	}
+	return extra, extensions, nil
+}
+
+// encodeExtraAttributes appends extra and extensions to the JSON object b.
+// Attributes included in known are ignored, and extensions take precedence over extra.
+func encodeExtraAttributes(
+	b []byte, known map[string]struct{}, extra map[string]json.RawMessage, extensions map[string]interface{},
+) ([]byte, error) {
+	if len(extra) == 0 && len(extensions) == 0 {
+		return b, nil
+	}
+	fields := make(map[string]json.RawMessage, len(extra)+len(extensions))
+	for k, v := range extra {
+		fields[k] = v
+	}
+	for k, v := range extensions {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the extension %s: %w", k, err)
+		}
+		fields[k] = raw
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if _, ok := known[strings.ToLower(k)]; ok {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return b, nil
+	}
+	sort.Strings(keys)
+
+	b = bytes.TrimRight(b, " \n")
+	if len(b) < 2 || b[len(b)-1] != '}' {
+		return nil, fmt.Errorf("JSON object is expected")
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(b)))
+	buf.Write(b[:len(b)-1])
+	sep := len(bytes.TrimSpace(b[1:len(b)-1])) != 0
+	for _, k := range keys {
+		if sep {
+			buf.WriteByte(',')
+		}
+		sep = true
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		if len(fields[k]) == 0 {
+			buf.WriteString("null")
+			continue
+		}
+		buf.Write(fields[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func decodeExtension(
+	extra map[string]json.RawMessage, extensions map[string]interface{}, urn string, v interface{},
+) (bool, error) {
+	if ext, ok := extensions[urn]; ok {
+		b, err := json.Marshal(ext)
+		if err != nil {
+			return true, err
+		}
+		return true, json.Unmarshal(b, v)
+	}
+	if raw, ok := extra[urn]; ok {
+		return true, json.Unmarshal(raw, v)
+	}
+	return false, nil
+}
+
+// urns returns the registered urns.
+func (reg *extensionRegistry) urns() []string {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+	urns := make([]string, 0, len(reg.factories))
+	for urn := range reg.factories {
+		urns = append(urns, urn)
+	}
+	sort.Strings(urns)
+	return urns
+}
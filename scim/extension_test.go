@@ -0,0 +1,187 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testExtension struct {
+	Badge string `json:"badge"`
+}
+
+const (
+	testExtensionURN = "urn:scim:schemas:extension:example:1.0"
+)
+
+func init() {
+	if err := RegisterUserExtension(testExtensionURN, func() interface{} { return &testExtension{} }); err != nil {
+		panic(err)
+	}
+	if err := RegisterGroupExtension(testExtensionURN, func() interface{} { return &testExtension{} }); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterUserExtension(t *testing.T) {
+	factory := func() interface{} { return &testExtension{} }
+	require.NotNil(t, RegisterUserExtension("", factory))
+	require.NotNil(t, RegisterUserExtension("urn:foo", nil))
+	require.NotNil(t, RegisterUserExtension(SchemaEnterpriseUser, factory))
+	require.NotNil(t, RegisterGroupExtension("members", factory))
+}
+
+func TestUser_extraAttributes(t *testing.T) {
+	body := `{
+  "id": "U1",
+  "schemas": ["urn:scim:schemas:core:1.0", "urn:scim:schemas:extension:unknown:1.0", "urn:scim:schemas:extension:example:1.0"],
+  "unknownAttribute": {"foo": [1, 2]},
+  "urn:scim:schemas:extension:unknown:1.0": {"bar": "zoo"},
+  "urn:scim:schemas:extension:example:1.0": {"badge": "gold"}
+}`
+	user := &User{}
+	require.Nil(t, json.Unmarshal([]byte(body), user))
+	require.Equal(t, "U1", user.ID)
+	require.Equal(t, map[string]json.RawMessage{
+		"unknownAttribute":                       json.RawMessage(`{"foo": [1, 2]}`),
+		"urn:scim:schemas:extension:unknown:1.0": json.RawMessage(`{"bar": "zoo"}`),
+	}, user.Extra)
+	require.Equal(t, map[string]interface{}{
+		testExtensionURN: &testExtension{Badge: "gold"},
+	}, user.Extensions)
+
+	b, err := json.Marshal(user)
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+  "id": "U1",
+  "groups": [],
+  "schemas": ["urn:scim:schemas:core:1.0", "urn:scim:schemas:extension:unknown:1.0", "urn:scim:schemas:extension:example:1.0"],
+  "unknownAttribute": {"foo": [1, 2]},
+  "urn:scim:schemas:extension:unknown:1.0": {"bar": "zoo"},
+  "urn:scim:schemas:extension:example:1.0": {"badge": "gold"}
+}`, string(b))
+
+	ext := &testExtension{}
+	ok, err := user.DecodeExtension(testExtensionURN, ext)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, "gold", ext.Badge)
+	m := map[string]string{}
+	ok, err = user.DecodeExtension("urn:scim:schemas:extension:unknown:1.0", &m)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"bar": "zoo"}, m)
+	ok, err = user.DecodeExtension("urn:foo", &m)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	require.NotNil(t, json.Unmarshal([]byte(`{"urn:scim:schemas:extension:example:1.0": "foo"}`), &User{}))
+}
+
+func TestUser_SetExtension(t *testing.T) {
+	user := &User{
+		Extra: map[string]json.RawMessage{
+			testExtensionURN: json.RawMessage(`{"badge": "silver"}`),
+		},
+	}
+	user.SetExtension(testExtensionURN, &testExtension{Badge: "gold"})
+	require.Equal(t, []string{SchemaCore, testExtensionURN}, user.Schemas)
+	require.Empty(t, user.Extra)
+	b, err := json.Marshal(user)
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+  "groups": [],
+  "schemas": ["urn:scim:schemas:core:1.0", "urn:scim:schemas:extension:example:1.0"],
+  "urn:scim:schemas:extension:example:1.0": {"badge": "gold"}
+}`, string(b))
+}
+
+func TestGroup_extraAttributes(t *testing.T) {
+	body := `{
+  "id": "G1",
+  "displayName": "admins",
+  "meta": {
+    "created": "2018-01-16T19:33:57-08:00",
+    "lastModified": "2018-01-16T19:33:57-08:00",
+    "location": "https://api.slack.com/scim/v1/Groups/G1",
+    "version": "1",
+    "attributes": ["displayName"]
+  },
+  "members": [],
+  "schemas": ["urn:scim:schemas:core:1.0"],
+  "unknownAttribute": "foo",
+  "urn:scim:schemas:extension:example:1.0": {"badge": "gold"}
+}`
+	group := &Group{}
+	require.Nil(t, json.Unmarshal([]byte(body), group))
+	require.Equal(t, map[string]json.RawMessage{
+		"unknownAttribute": json.RawMessage(`"foo"`),
+	}, group.Extra)
+	ext := &testExtension{}
+	ok, err := group.DecodeExtension(testExtensionURN, ext)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, "gold", ext.Badge)
+
+	b, err := json.Marshal(group)
+	require.Nil(t, err)
+	require.JSONEq(t, body, string(b))
+
+	group = &Group{ID: "G2", DisplayName: "members", Meta: group.Meta}
+	group.SetExtension(testExtensionURN, &testExtension{Badge: "silver"})
+	b, err = json.Marshal(group)
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+  "id": "G2",
+  "displayName": "members",
+  "meta": {
+    "created": "2018-01-16T19:33:57-08:00",
+    "lastModified": "2018-01-16T19:33:57-08:00",
+    "location": "https://api.slack.com/scim/v1/Groups/G1",
+    "version": "1",
+    "attributes": ["displayName"]
+  },
+  "members": null,
+  "schemas": ["urn:scim:schemas:core:1.0", "urn:scim:schemas:extension:example:1.0"],
+  "urn:scim:schemas:extension:example:1.0": {"badge": "silver"}
+}`, string(b))
+}
+
+func Test_encodeExtraAttributes(t *testing.T) {
+	data := []struct {
+		b       string
+		extra   map[string]json.RawMessage
+		exp     string
+		isError bool
+	}{
+		{
+			b:   `{}`,
+			exp: `{}`,
+		},
+		{
+			b:     `{}`,
+			extra: map[string]json.RawMessage{"foo": json.RawMessage(`1`), "bar": nil},
+			exp:   `{"bar":null,"foo":1}`,
+		},
+		{
+			b:     `{"id":"foo"}`,
+			extra: map[string]json.RawMessage{"ID": json.RawMessage(`"bar"`)},
+			exp:   `{"id":"foo"}`,
+		},
+		{
+			b:       `[]`,
+			extra:   map[string]json.RawMessage{"foo": json.RawMessage(`1`)},
+			isError: true,
+		},
+	}
+	for _, d := range data {
+		b, err := encodeExtraAttributes([]byte(d.b), map[string]struct{}{"id": {}}, d.extra, nil)
+		if d.isError {
+			require.NotNil(t, err)
+			continue
+		}
+		require.Nil(t, err)
+		require.Equal(t, d.exp, string(b))
+	}
+}
@@ -0,0 +1,16 @@
+package scim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// escapeFilterValue escapes a string value embedded in a filter's double quoted string.
+func escapeFilterValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v)
+}
+
+// eqFilter returns a filter `attr eq "value"` .
+func eqFilter(attr, value string) string {
+	return fmt.Sprintf(`%s eq "%s"`, attr, escapeFilterValue(value))
+}
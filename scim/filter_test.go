@@ -0,0 +1,29 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_eqFilter(t *testing.T) {
+	data := []struct {
+		attr  string
+		value string
+		exp   string
+	}{
+		{
+			attr:  "userName",
+			value: "foo",
+			exp:   `userName eq "foo"`,
+		},
+		{
+			attr:  "email",
+			value: `fo"o\`,
+			exp:   `email eq "fo\"o\\"`,
+		},
+	}
+	for _, d := range data {
+		require.Equal(t, d.exp, eqFilter(d.attr, d.value))
+	}
+}
@@ -2,6 +2,8 @@ package scim
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -12,10 +14,32 @@ type (
 	// https://api.slack.com/scim#groups
 	Group struct {
 		ID          string   `json:"id"`
+		ExternalID  string   `json:"externalId,omitempty"`
 		DisplayName string   `json:"displayName"`
 		Members     []Member `json:"members"`
 		Schemas     []string `json:"schemas"`
 		Meta        *Meta    `json:"meta"`
+		// Extra has attributes which aren't defined in Group such as unknown schema extensions.
+		// Extra is kept as raw JSON so that the attributes aren't lost when the group is decoded and encoded again.
+		Extra map[string]json.RawMessage `json:"-"`
+		// Extensions has typed schema extensions registered by RegisterGroupExtension .
+		Extensions map[string]interface{} `json:"-"`
+	}
+
+	// PatchedGroupError is the error of Client.PatchGroup when the group is updated but the updated group can't be got,
+	// that is, the follow-up GET /Groups/{id} request fails or the response body of PATCH /Groups/{id} API can't be parsed.
+	// The update itself succeeded, so the mutation shouldn't be retried. Use errors.As to check it.
+	PatchedGroupError struct {
+		GroupID string
+		Err     error
+	}
+
+	// groupPatch is the request body of PATCH /Groups/{id} API built by the client, such as Client.PatchGroupMembers .
+	// Attributes which aren't patched are omitted, so that they aren't cleared.
+	groupPatch struct {
+		Schemas     []string `json:"schemas"`
+		DisplayName string   `json:"displayName,omitempty"`
+		Members     []Member `json:"members"`
 	}
 
 	// Groups is a response body of GET groups API.
@@ -28,9 +52,12 @@ type (
 	}
 
 	// Member is member of the group.
+	// Operation is used only for PATCH group API's request.
+	// To remove the member from the group, set MemberOperationDelete .
 	Member struct {
-		Value   string `json:"value"`
-		Display string `json:"display"`
+		Value     string `json:"value"`
+		Display   string `json:"display"`
+		Operation string `json:"operation,omitempty"`
 	}
 )
 
@@ -44,7 +71,7 @@ func (c *Client) GetGroupsResp(ctx context.Context, page *Pagination, filter str
 		query.Add("filter", filter)
 	}
 	setPageToQuery(page, query)
-	return c.getResp(ctx, "GET", "/Groups", nil, query)
+	return c.getResp(ctx, "GetGroups", "GET", "/Groups", nil, query)
 }
 
 // GetGroups calls GET /Groups API and returns groups.
@@ -70,7 +97,7 @@ func (c *Client) GetGroupResp(ctx context.Context, id string) (*http.Response, e
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	return c.getResp(ctx, "GET", fmt.Sprintf("/Groups/%s", id), nil, nil)
+	return c.getResp(ctx, "GetGroup", "GET", fmt.Sprintf("/Groups/%s", id), nil, nil)
 }
 
 // GetGroup calls GET /Groups/{id} API and returns a group.
@@ -91,31 +118,62 @@ func (c *Client) GetGroup(
 // CreateGroupResp calls POST /Groups API and returns a HTTP response.
 // If the returned error is nil, the returned response isn't nil and you have to close the response body.
 // Internally, this method returns the returned values of *http.Client.Do .
+// The mutation guard is called before the request is sent, and if the guard returns an error the request isn't sent.
 func (c *Client) CreateGroupResp(ctx context.Context, group *Group) (*http.Response, error) {
+	ctx, mutation := c.newMutation(ctx, MutationCreate, ResourceTypeGroup, "", group)
+	return c.guardedResp(ctx, mutation, func(ctx context.Context) (*http.Response, error) {
+		return c.createGroupResp(ctx, group)
+	})
+}
+
+// createGroupResp sends the request without the mutation guard.
+func (c *Client) createGroupResp(ctx context.Context, group *Group) (*http.Response, error) {
 	// POST /Groups
 	if group == nil {
 		return nil, fmt.Errorf("group is required")
 	}
-	return c.getResp(ctx, "POST", "/Groups", group, nil)
+	return c.getResp(ctx, "CreateGroup", "POST", "/Groups", group, nil)
 }
 
 // CreateGroup calls POST /Groups API and returns a created group.
 // The returned response body is closed.
 func (c *Client) CreateGroup(ctx context.Context, group *Group) (*Group, *http.Response, error) {
 	// POST /Groups
-	resp, err := c.CreateGroupResp(ctx, group)
+	ctx, mutation := c.newMutation(ctx, MutationCreate, ResourceTypeGroup, "", group)
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.createGroupResp(ctx, group)
 	if err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
 		return nil, resp, err
 	}
 	defer resp.Body.Close()
 	g := &Group{}
-	return g, resp, c.parseResponse(resp, g)
+	if err := c.parseResponse(resp, g); err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return g, resp, err
+	}
+	mutation.ResourceID = g.ID
+	mutation.Result = g
+	c.afterMutation(ctx, mutation, resp, nil)
+	return g, resp, nil
 }
 
 // PatchGroupResp calls PATCH /Groups/{id} API and returns a HTTP response.
 // If the returned error is nil, the returned response isn't nil and you have to close the response body.
 // Internally, this method returns the returned values of *http.Client.Do .
+// The mutation guard is called before the request is sent, and if the guard returns an error the request isn't sent.
 func (c *Client) PatchGroupResp(ctx context.Context, id string, group *Group) (*http.Response, error) {
+	ctx, mutation := c.newMutation(ctx, MutationPatch, ResourceTypeGroup, id, group)
+	return c.guardedResp(ctx, mutation, func(ctx context.Context) (*http.Response, error) {
+		return c.patchGroupResp(ctx, id, group, group)
+	})
+}
+
+// patchGroupResp sends the request whose body is body without the mutation guard.
+// body is group or the groupPatch of group.
+func (c *Client) patchGroupResp(ctx context.Context, id string, group *Group, body interface{}) (*http.Response, error) {
 	// PATCH /Groups/{id}
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
@@ -123,25 +181,106 @@ func (c *Client) PatchGroupResp(ctx context.Context, id string, group *Group) (*
 	if group == nil {
 		return nil, fmt.Errorf("group is required")
 	}
-	return c.getResp(ctx, "PATCH", fmt.Sprintf("/Groups/%s", id), group, nil)
+	return c.getResp(ctx, "PatchGroup", "PATCH", fmt.Sprintf("/Groups/%s", id), body, nil)
 }
 
-// PatchGroup calls PATCH /Groups/{id} API.
-// The returned response body is closed.
-func (c *Client) PatchGroup(ctx context.Context, id string, group *Group) (*http.Response, error) {
+// PatchGroup calls PATCH /Groups/{id} API and returns the updated group.
+// Slack may return no content for PATCH /Groups/{id} API, and then the group is got by GET /Groups/{id} API.
+// The returned response is the response of PATCH /Groups/{id} API, and the response body is closed.
+// If the group is updated but the updated group can't be got, *PatchedGroupError is returned,
+// and the mutation is passed to MutationHook as a succeeded mutation.
+func (c *Client) PatchGroup(ctx context.Context, id string, group *Group) (*Group, *http.Response, error) {
+	return c.patchGroup(ctx, id, group, group)
+}
+
+// patchGroup is Client.PatchGroup whose request body is body.
+// body is group or the groupPatch of group.
+func (c *Client) patchGroup(ctx context.Context, id string, group *Group, body interface{}) (*Group, *http.Response, error) {
 	// PATCH /Groups/{id}
-	resp, err := c.PatchGroupResp(ctx, id, group)
+	ctx, mutation := c.newMutation(ctx, MutationPatch, ResourceTypeGroup, id, group)
+	return c.sendGroupPatch(ctx, mutation, id, group, body, false)
+}
+
+// sendGroupPatch sends the PATCH /Groups/{id} request of the mutation created by Client.patchGroup .
+// If withPreImage is true, the group before the patch is got as the mutation's pre-image,
+// and the request isn't sent if the group can't be got.
+func (c *Client) sendGroupPatch(
+	ctx context.Context, mutation *Mutation, id string, group *Group, body interface{}, withPreImage bool,
+) (*Group, *http.Response, error) {
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, nil, err
+	}
+	if withPreImage {
+		if _, err := mutation.PreImage(ctx); err != nil {
+			err = fmt.Errorf("failed to get the group before the patch: %w", err)
+			c.afterMutation(ctx, mutation, nil, err)
+			return nil, nil, err
+		}
+	}
+	resp, err := c.patchGroupResp(ctx, id, group, body)
 	if err != nil {
-		return resp, err
+		c.afterMutation(ctx, mutation, resp, err)
+		return nil, resp, err
 	}
 	defer resp.Body.Close()
-	return resp, c.parseResponse(resp, nil)
+	if c.isError(resp) {
+		err := c.parseErrorResp(resp)
+		c.afterMutation(ctx, mutation, resp, err)
+		return nil, resp, err
+	}
+	empty, err := isEmptyBody(resp)
+	if err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return nil, resp, err
+	}
+	// the group has been updated, so the failure to get the updated group isn't the failure of the mutation.
+	g := &Group{}
+	if empty {
+		g, _, err = c.GetGroup(ctx, id)
+	} else {
+		err = c.parseResp(resp, g)
+	}
+	if err != nil {
+		c.afterMutation(ctx, mutation, resp, nil)
+		return nil, resp, &PatchedGroupError{
+			GroupID: id,
+			Err:     err,
+		}
+	}
+	mutation.Result = g
+	c.afterMutation(ctx, mutation, resp, nil)
+	return g, resp, nil
+}
+
+// Error returns the error message with the group id.
+func (e *PatchedGroupError) Error() string {
+	return fmt.Sprintf("the group %s is updated but failed to get the updated group: %v", e.GroupID, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *PatchedGroupError) Unwrap() error {
+	return e.Err
+}
+
+// isPatchedGroupError returns true if err is PatchedGroupError, that is, the group has been updated.
+func isPatchedGroupError(err error) bool {
+	var e *PatchedGroupError
+	return errors.As(err, &e)
 }
 
 // PutGroupResp calls PUT /Groups/{id} API and returns a HTTP response.
 // If the returned error is nil, the returned response isn't nil and you have to close the response body.
 // Internally, this method returns the returned values of *http.Client.Do .
+// The mutation guard is called before the request is sent, and if the guard returns an error the request isn't sent.
 func (c *Client) PutGroupResp(ctx context.Context, id string, group *Group) (*http.Response, error) {
+	ctx, mutation := c.newMutation(ctx, MutationPut, ResourceTypeGroup, id, group)
+	return c.guardedResp(ctx, mutation, func(ctx context.Context) (*http.Response, error) {
+		return c.putGroupResp(ctx, id, group)
+	})
+}
+
+// putGroupResp sends the request without the mutation guard.
+func (c *Client) putGroupResp(ctx context.Context, id string, group *Group) (*http.Response, error) {
 	// PUT /Groups/{id}
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
@@ -149,41 +288,125 @@ func (c *Client) PutGroupResp(ctx context.Context, id string, group *Group) (*ht
 	if group == nil {
 		return nil, fmt.Errorf("group is required")
 	}
-	return c.getResp(ctx, "PUT", fmt.Sprintf("/Groups/%s", id), group, nil)
+	return c.getResp(ctx, "PutGroup", "PUT", fmt.Sprintf("/Groups/%s", id), group, nil)
 }
 
-// PutGroup calls PUT /Groups/{id} API and returns a updated group.
+// PutGroup calls PUT /Groups/{id} API and returns the updated group.
 // The returned response body is closed.
 func (c *Client) PutGroup(ctx context.Context, id string, group *Group) (*Group, *http.Response, error) {
 	// PUT /Groups/{id}
-	resp, err := c.PutGroupResp(ctx, id, group)
+	ctx, mutation := c.newMutation(ctx, MutationPut, ResourceTypeGroup, id, group)
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.putGroupResp(ctx, id, group)
 	if err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
 		return nil, resp, err
 	}
 	defer resp.Body.Close()
 	g := &Group{}
-	return g, resp, c.parseResponse(resp, g)
+	if err := c.parseResponse(resp, g); err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return g, resp, err
+	}
+	mutation.Result = g
+	c.afterMutation(ctx, mutation, resp, nil)
+	return g, resp, nil
 }
 
 // DeleteGroupResp calls DELETE /Groups/{id} API and returns a HTTP response.
 // If the returned error is nil, the returned response isn't nil and you have to close the response body.
 // Internally, this method returns the returned values of *http.Client.Do .
+// The mutation guard is called before the request is sent, and if the guard returns an error the request isn't sent.
 func (c *Client) DeleteGroupResp(ctx context.Context, id string) (*http.Response, error) {
+	ctx, mutation := c.newMutation(ctx, MutationDelete, ResourceTypeGroup, id, nil)
+	return c.guardedResp(ctx, mutation, func(ctx context.Context) (*http.Response, error) {
+		return c.deleteGroupResp(ctx, id)
+	})
+}
+
+// deleteGroupResp sends the request without the mutation guard.
+func (c *Client) deleteGroupResp(ctx context.Context, id string) (*http.Response, error) {
 	// DELETE /Groups/{id}
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	return c.getResp(ctx, "DELETE", fmt.Sprintf("/Groups/%s", id), nil, nil)
+	return c.getResp(ctx, "DeleteGroup", "DELETE", fmt.Sprintf("/Groups/%s", id), nil, nil)
 }
 
 // DeleteGroup calls DELETE /Groups/{id} API.
 // The returned response body is closed.
 func (c *Client) DeleteGroup(ctx context.Context, id string) (*http.Response, error) {
 	// DELETE /Groups/{id}
-	resp, err := c.DeleteGroupResp(ctx, id)
+	ctx, mutation := c.newMutation(ctx, MutationDelete, ResourceTypeGroup, id, nil)
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, err
+	}
+	resp, err := c.deleteGroupResp(ctx, id)
 	if err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
 		return resp, err
 	}
 	defer resp.Body.Close()
-	return resp, c.parseResponse(resp, nil)
+	if err := c.parseResponse(resp, nil); err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return resp, err
+	}
+	c.afterMutation(ctx, mutation, resp, nil)
+	return resp, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler .
+func (group *Group) UnmarshalJSON(b []byte) error {
+	type alias Group
+	if err := json.Unmarshal(b, (*alias)(group)); err != nil {
+		return err
+	}
+	extra, extensions, err := decodeExtraAttributes(b, groupKnownAttributes, groupExtensions)
+	if err != nil {
+		return err
+	}
+	group.Extra = extra
+	group.Extensions = extensions
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler .
+func (group *Group) MarshalJSON() ([]byte, error) {
+	type alias Group
+	b, err := json.Marshal((*alias)(group))
+	if err != nil {
+		return nil, err
+	}
+	return encodeExtraAttributes(b, groupKnownAttributes, group.Extra, group.Extensions)
+}
+
+// newGroupPatch returns the request body of PATCH /Groups/{id} API which has only displayName and members of group.
+func newGroupPatch(group *Group) *groupPatch {
+	if group == nil {
+		return nil
+	}
+	return &groupPatch{
+		Schemas:     group.Schemas,
+		DisplayName: group.DisplayName,
+		Members:     group.Members,
+	}
+}
+
+// DecodeExtension decodes the attribute urn kept in Extra or Extensions to v.
+// If the group doesn't have the attribute, the second returned value is false.
+func (group *Group) DecodeExtension(urn string, v interface{}) (bool, error) {
+	return decodeExtension(group.Extra, group.Extensions, urn, v)
+}
+
+// SetExtension sets v to Extensions[urn] .
+// The schema urn is added to Schemas.
+func (group *Group) SetExtension(urn string, v interface{}) {
+	if group.Extensions == nil {
+		group.Extensions = map[string]interface{}{}
+	}
+	group.Extensions[urn] = v
+	delete(group.Extra, urn)
+	group.Schemas = appendSchema(group.Schemas, urn)
 }
@@ -0,0 +1,131 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// MemberOperationDelete is Member's Operation to remove the member from the group.
+	MemberOperationDelete = "delete"
+)
+
+type (
+	// GroupMembershipResult is a membership level result of PATCH /Groups/{id} API.
+	// Slack ignores some requested changes, for example adding a deactivated user,
+	// so the requested changes are compared with the members of the group before and after the patch.
+	GroupMembershipResult struct {
+		Group *Group
+		// Added is ids of requested users who weren't members before the patch and are members of the updated group.
+		Added []string
+		// NotAdded is ids of requested users who aren't members of the updated group.
+		NotAdded []string
+		// Removed is ids of requested users who were members before the patch and aren't members of the updated group.
+		Removed []string
+		// NotRemoved is ids of requested users who are still members of the updated group.
+		NotRemoved []string
+		// Unchanged is ids of requested users whose membership didn't need to be changed,
+		// that is, users who were already members or who weren't members to be removed.
+		Unchanged []string
+	}
+)
+
+// NewGroupMembershipResult compares members of patch with members of current, the group before the patch,
+// and members of updated, the group after the patch, and returns the result.
+func NewGroupMembershipResult(current, patch, updated *Group) *GroupMembershipResult {
+	result := &GroupMembershipResult{
+		Group: updated,
+	}
+	if patch == nil {
+		return result
+	}
+	before := memberSet(current)
+	after := memberSet(updated)
+	for _, member := range patch.Members {
+		_, was := before[member.Value]
+		_, is := after[member.Value]
+		switch {
+		case member.Operation == MemberOperationDelete && is:
+			result.NotRemoved = append(result.NotRemoved, member.Value)
+		case member.Operation == MemberOperationDelete && was:
+			result.Removed = append(result.Removed, member.Value)
+		case member.Operation != MemberOperationDelete && !is:
+			result.NotAdded = append(result.NotAdded, member.Value)
+		case member.Operation != MemberOperationDelete && !was:
+			result.Added = append(result.Added, member.Value)
+		default:
+			result.Unchanged = append(result.Unchanged, member.Value)
+		}
+	}
+	return result
+}
+
+// memberSet returns the set of the ids of the group's members.
+func memberSet(group *Group) map[string]struct{} {
+	members := map[string]struct{}{}
+	if group == nil {
+		return members
+	}
+	for _, member := range group.Members {
+		members[member.Value] = struct{}{}
+	}
+	return members
+}
+
+// AddGroupMembers calls PATCH /Groups/{id} API to add users to the group.
+// The returned response body is closed.
+func (c *Client) AddGroupMembers(
+	ctx context.Context, id string, userIDs ...string,
+) (*GroupMembershipResult, *http.Response, error) {
+	return c.PatchGroupMembers(ctx, id, newMembers(userIDs, ""))
+}
+
+// RemoveGroupMembers calls PATCH /Groups/{id} API to remove users from the group.
+// The returned response body is closed.
+func (c *Client) RemoveGroupMembers(
+	ctx context.Context, id string, userIDs ...string,
+) (*GroupMembershipResult, *http.Response, error) {
+	return c.PatchGroupMembers(ctx, id, newMembers(userIDs, MemberOperationDelete))
+}
+
+// PatchGroupMembers calls PATCH /Groups/{id} API to add and remove members and returns the membership level result.
+// To remove a member, set MemberOperationDelete to the member's Operation.
+// The group before the patch is got by GET /Groups/{id} API as the mutation's pre-image,
+// and if it can't be got the group isn't patched.
+// If the group is updated but the updated group can't be got, *PatchedGroupError is returned without the result.
+// The returned response body is closed.
+func (c *Client) PatchGroupMembers(
+	ctx context.Context, id string, members []Member,
+) (_ *GroupMembershipResult, _ *http.Response, err error) {
+	ctx, span := c.startMethodSpan(ctx, "PatchGroupMembers")
+	defer func() {
+		span.end(err)
+	}()
+	if len(members) == 0 {
+		return nil, nil, fmt.Errorf("members is required")
+	}
+	patch := &Group{
+		Schemas: []string{SchemaCore},
+		Members: members,
+	}
+	ctx, mutation := c.newMutation(ctx, MutationPatch, ResourceTypeGroup, id, patch)
+	group, resp, err := c.sendGroupPatch(ctx, mutation, id, patch, newGroupPatch(patch), true)
+	if err != nil {
+		return nil, resp, err
+	}
+	preImage, _ := mutation.PreImage(ctx)
+	current, _ := preImage.(*Group)
+	return NewGroupMembershipResult(current, patch, group), resp, nil
+}
+
+func newMembers(userIDs []string, operation string) []Member {
+	members := make([]Member, len(userIDs))
+	for i, userID := range userIDs {
+		members[i] = Member{
+			Value:     userID,
+			Operation: operation,
+		}
+	}
+	return members
+}
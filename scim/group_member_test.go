@@ -0,0 +1,139 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGroupMembershipResult(t *testing.T) {
+	current := &Group{
+		Members: []Member{
+			{Value: "foo"},
+			{Value: "baz"},
+			{Value: "qux"},
+		},
+	}
+	patch := &Group{
+		Members: []Member{
+			{Value: "foo"},
+			{Value: "bar"},
+			{Value: "quux"},
+			{Value: "baz", Operation: MemberOperationDelete},
+			{Value: "qux", Operation: MemberOperationDelete},
+			{Value: "corge", Operation: MemberOperationDelete},
+		},
+	}
+	updated := &Group{
+		Members: []Member{
+			{Value: "foo"},
+			{Value: "qux"},
+			{Value: "quux"},
+		},
+	}
+	require.Equal(t, &GroupMembershipResult{
+		Group:      updated,
+		Added:      []string{"quux"},
+		NotAdded:   []string{"bar"},
+		Removed:    []string{"baz"},
+		NotRemoved: []string{"qux"},
+		Unchanged:  []string{"foo", "corge"},
+	}, NewGroupMembershipResult(current, patch, updated))
+	require.Equal(t, &GroupMembershipResult{}, NewGroupMembershipResult(nil, nil, nil))
+}
+
+func TestClient_AddGroupMembers(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	_, _, err := client.AddGroupMembers(ctx, dummyID)
+	require.NotNil(t, err)
+
+	// the group before the patch
+	gock.New("https://api.slack.com").
+		Get(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		Reply(200).
+		JSON(map[string]interface{}{
+			"id":      dummyID,
+			"members": []map[string]string{{"value": "baz"}},
+		})
+	gock.New("https://api.slack.com").
+		Patch(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore},
+			"members": []map[string]string{
+				{"value": "foo", "display": ""},
+				{"value": "bar", "display": ""},
+				{"value": "baz", "display": ""},
+			},
+		}).
+		Reply(204)
+	// the group after the patch
+	gock.New("https://api.slack.com").
+		Get(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		Reply(200).
+		JSON(map[string]interface{}{
+			"id":      dummyID,
+			"members": []map[string]string{{"value": "foo"}, {"value": "baz"}},
+		})
+	result, resp, err := client.AddGroupMembers(ctx, dummyID, "foo", "bar", "baz")
+	require.Nil(t, err)
+	require.Equal(t, 204, resp.StatusCode)
+	require.Equal(t, []string{"foo"}, result.Added)
+	require.Equal(t, []string{"bar"}, result.NotAdded)
+	require.Equal(t, []string{"baz"}, result.Unchanged)
+	require.Equal(t, dummyID, result.Group.ID)
+	require.True(t, gock.IsDone())
+
+	// the group isn't patched if the group before the patch can't be got
+	gock.New("https://api.slack.com").
+		Get(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		Reply(500)
+	_, resp, err = client.AddGroupMembers(ctx, dummyID, "foo")
+	require.NotNil(t, err)
+	require.Nil(t, resp)
+	require.True(t, gock.IsDone())
+}
+
+func TestClient_RemoveGroupMembers(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	_, _, err := client.RemoveGroupMembers(ctx, dummyID)
+	require.NotNil(t, err)
+
+	gock.New("https://api.slack.com").
+		Get(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		Reply(200).
+		JSON(map[string]interface{}{
+			"id":      dummyID,
+			"members": []map[string]string{{"value": "foo"}, {"value": "bar"}},
+		})
+	gock.New("https://api.slack.com").
+		Patch(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore},
+			"members": []map[string]string{
+				{"value": "foo", "display": "", "operation": "delete"},
+			},
+		}).
+		Reply(200).
+		JSON(map[string]interface{}{
+			"id":      dummyID,
+			"members": []map[string]string{{"value": "bar"}},
+		})
+	result, resp, err := client.RemoveGroupMembers(ctx, dummyID, "foo")
+	require.Nil(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	require.Equal(t, []string{"foo"}, result.Removed)
+	require.Empty(t, result.NotRemoved)
+	require.True(t, gock.IsDone())
+}
@@ -10,7 +10,7 @@ import (
 // Internally, this method returns the returned values of *http.Client.Do .
 func (c *Client) GetGroupSchemaResp(ctx context.Context) (*http.Response, error) {
 	// GET /Schemas/Groups
-	return c.getResp(ctx, "GET", "/Schemas/Groups", nil, nil)
+	return c.getResp(ctx, "GetGroupSchema", "GET", "/Schemas/Groups", nil, nil)
 }
 
 // GetGroupSchema calls GET /Schemas/Groups API and returns a group schema.
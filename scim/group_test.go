@@ -2,6 +2,7 @@ package scim
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -147,6 +148,31 @@ func TestClient_GetGroup(t *testing.T) {
 	}
 }
 
+func TestClient_GetGroup_extraAttributes(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	gock.New("https://api.slack.com").
+		Get(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		Reply(200).
+		BodyString(`{"id": "XXXX", "displayName": "foo", "members": [], "unknownAttribute": {"foo": "bar"}}`)
+	group, _, err := client.GetGroup(ctx, dummyID)
+	require.Nil(t, err)
+	require.Equal(t, "foo", group.DisplayName)
+	require.JSONEq(t, `{"foo": "bar"}`, string(group.Extra["unknownAttribute"]))
+
+	// the unknown attribute isn't lost when the group is sent
+	gock.New("https://api.slack.com").
+		Put(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		BodyString(`"unknownAttribute":{"foo":"bar"}`).
+		Reply(200).
+		BodyString(`{"id": "XXXX", "displayName": "foo", "members": []}`)
+	_, _, err = client.PutGroup(ctx, dummyID, group)
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+}
+
 func TestClient_DeleteGroup(t *testing.T) {
 	defer gock.Off()
 
@@ -285,12 +311,23 @@ func TestClient_PatchGroup(t *testing.T) {
 		isError    bool
 		id         string
 		group      *Group
+		body       string
+		exp        *Group
 	}{
 		{
 			statusCode: 200,
 			isError:    false,
 			id:         dummyID,
 			group:      &Group{},
+			body:       testGroupJSON,
+			exp:        &testGroup,
+		},
+		{
+			statusCode: 204,
+			isError:    false,
+			id:         dummyID,
+			group:      &Group{},
+			exp:        &testGroup,
 		},
 		{
 			statusCode: 200,
@@ -311,8 +348,14 @@ func TestClient_PatchGroup(t *testing.T) {
 	for _, d := range data {
 		gock.New("https://api.slack.com").
 			Patch(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
-			MatchType("json").JSON(d.group).Reply(d.statusCode)
-		resp, err := client.PatchGroup(ctx, d.id, d.group)
+			MatchType("json").JSON(d.group).Reply(d.statusCode).
+			BodyString(d.body)
+		if d.statusCode == 204 {
+			gock.New("https://api.slack.com").
+				Get(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+				Reply(200).BodyString(testGroupJSON)
+		}
+		group, resp, err := client.PatchGroup(ctx, d.id, d.group)
 		if d.isError {
 			require.NotNil(t, err)
 			return
@@ -320,5 +363,44 @@ func TestClient_PatchGroup(t *testing.T) {
 		require.Nil(t, err)
 		require.NotNil(t, resp)
 		require.Equal(t, d.statusCode, resp.StatusCode)
+		require.Equal(t, d.exp, group)
 	}
 }
+
+func TestClient_PatchGroup_followUpError(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	var mutations []*Mutation
+	client := NewClient("XXX").WithMutationHook(func(ctx context.Context, mutation *Mutation) {
+		mutations = append(mutations, mutation)
+	})
+	gock.New("https://api.slack.com").
+		Patch(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		Reply(204)
+	gock.New("https://api.slack.com").
+		Get(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal_error", "code": 500}}`)
+	group, resp, err := client.PatchGroup(ctx, dummyID, &Group{DisplayName: "foo"})
+	require.True(t, gock.IsDone())
+	require.Nil(t, group)
+	require.NotNil(t, resp)
+	require.Equal(t, 204, resp.StatusCode)
+	var patchedErr *PatchedGroupError
+	require.True(t, errors.As(err, &patchedErr))
+	require.Equal(t, dummyID, patchedErr.GroupID)
+	require.Contains(t, err.Error(), "internal_error")
+	// the PATCH request succeeded, so the mutation isn't failed
+	require.Len(t, mutations, 1)
+	require.Nil(t, mutations[0].Err)
+
+	gock.New("https://api.slack.com").
+		Patch(fmt.Sprintf("/scim/v1/Groups/%s", dummyID)).
+		Reply(200).
+		BodyString("{")
+	_, _, err = client.PatchGroup(ctx, dummyID, &Group{DisplayName: "foo"})
+	require.True(t, errors.As(err, &patchedErr))
+	require.Len(t, mutations, 2)
+	require.Nil(t, mutations[1].Err)
+}
@@ -0,0 +1,160 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// GuestTypeMultiChannel is the guest type of multi-channel guests.
+	GuestTypeMultiChannel = "multi"
+	// GuestTypeSingleChannel is the guest type of single-channel guests.
+	GuestTypeSingleChannel = "single"
+)
+
+type (
+	// GuestExpiry is a guest whose account expires soon.
+	GuestExpiry struct {
+		User       User
+		Expiration time.Time
+		// Expired is true if the expiration has passed.
+		Expired bool
+	}
+
+	// guestRemovalPatch is the request body of Client.ConvertGuestToMember .
+	// UserPatch isn't sent as is, because Meta's attributes other than Attributes aren't omitted.
+	guestRemovalPatch struct {
+		Schemas []string         `json:"schemas"`
+		Meta    guestRemovalMeta `json:"meta"`
+	}
+
+	guestRemovalMeta struct {
+		Attributes []string `json:"attributes"`
+	}
+)
+
+// IsGuest returns true if the user is a guest.
+func (user *User) IsGuest() bool {
+	return user.SlackGuestUserSchemaExtension != nil
+}
+
+// ExpirationTime parses the expiration.
+// If the guest has no expiration, the zero time is returned.
+func (ext *SlackGuestUserSchemaExtension) ExpirationTime() (time.Time, error) {
+	if ext.Expiration == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, ext.Expiration)
+}
+
+// CreateGuest calls POST /Users API to create a guest.
+// guestType is GuestTypeMultiChannel or GuestTypeSingleChannel .
+// If expiration is zero, the guest account doesn't expire.
+// user isn't changed.
+// The returned response body is closed.
+func (c *Client) CreateGuest(
+	ctx context.Context, user *User, guestType string, expiration time.Time,
+) (*User, *http.Response, error) {
+	if user == nil {
+		return nil, nil, fmt.Errorf("user is required")
+	}
+	if guestType != GuestTypeMultiChannel && guestType != GuestTypeSingleChannel {
+		return nil, nil, fmt.Errorf("invalid guest type: %s", guestType)
+	}
+	guest := *user
+	guest.SlackGuestUserSchemaExtension = &SlackGuestUserSchemaExtension{
+		Type: guestType,
+	}
+	if !expiration.IsZero() {
+		guest.SlackGuestUserSchemaExtension.Expiration = formatGuestExpiration(expiration)
+	}
+	guest.Schemas = appendSchema(user.Schemas, SchemaSlackGuest)
+	return c.CreateUser(ctx, &guest)
+}
+
+// ConvertGuestToMember calls PATCH /Users/{id} API to remove the guest extension and convert the guest to a full member.
+// The returned response body is closed.
+func (c *Client) ConvertGuestToMember(ctx context.Context, id string) (*User, *http.Response, error) {
+	patch := &UserPatch{
+		Schemas: []string{SchemaCore},
+		Meta: &Meta{
+			Attributes: []string{SchemaSlackGuest},
+		},
+	}
+	return c.patchUser(ctx, id, patch, &guestRemovalPatch{
+		Schemas: patch.Schemas,
+		Meta: guestRemovalMeta{
+			Attributes: patch.Meta.Attributes,
+		},
+	})
+}
+
+// SetGuestExpiration calls PATCH /Users/{id} API to change the guest's expiration.
+// The returned response body is closed.
+func (c *Client) SetGuestExpiration(
+	ctx context.Context, id string, expiration time.Time,
+) (*User, *http.Response, error) {
+	if expiration.IsZero() {
+		return nil, nil, fmt.Errorf("expiration is required")
+	}
+	exp := formatGuestExpiration(expiration)
+	return c.PatchUser(ctx, id, &UserPatch{
+		Schemas: []string{SchemaCore, SchemaSlackGuest},
+		SlackGuestUserSchemaExtension: &SlackGuestUserSchemaExtensionPatch{
+			Expiration: &exp,
+		},
+	})
+}
+
+// GuestReport returns active guests whose accounts expire before now + within.
+// Guests whose expiration has already passed are included too.
+// The returned list is sorted by the expiration.
+// If a guest's expiration can't be parsed, an error is returned.
+func GuestReport(users []User, now time.Time, within time.Duration) ([]GuestExpiry, error) {
+	deadline := now.Add(within)
+	ret := []GuestExpiry{}
+	for _, user := range users {
+		if !user.Active || !user.IsGuest() {
+			continue
+		}
+		exp, err := user.SlackGuestUserSchemaExtension.ExpirationTime()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the expiration of the user %s: %w", user.ID, err)
+		}
+		if exp.IsZero() || exp.After(deadline) {
+			continue
+		}
+		ret = append(ret, GuestExpiry{
+			User:       user,
+			Expiration: exp,
+			Expired:    !exp.After(now),
+		})
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Expiration.Before(ret[j].Expiration)
+	})
+	return ret, nil
+}
+
+func formatGuestExpiration(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// appendSchema returns a copy of schemas with schema appended.
+// If schemas is empty, the core schema is added too.
+func appendSchema(schemas []string, schema string) []string {
+	ret := make([]string, 0, len(schemas)+2)
+	if len(schemas) == 0 {
+		ret = append(ret, SchemaCore)
+	}
+	for _, s := range schemas {
+		if s == schema {
+			return append(ret, schemas...)
+		}
+	}
+	ret = append(ret, schemas...)
+	return append(ret, schema)
+}
@@ -0,0 +1,158 @@
+package scim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_IsGuest(t *testing.T) {
+	require.False(t, (&User{}).IsGuest())
+	require.True(t, (&User{SlackGuestUserSchemaExtension: &SlackGuestUserSchemaExtension{}}).IsGuest())
+}
+
+func TestSlackGuestUserSchemaExtension_ExpirationTime(t *testing.T) {
+	exp, err := (&SlackGuestUserSchemaExtension{}).ExpirationTime()
+	require.Nil(t, err)
+	require.True(t, exp.IsZero())
+	exp, err = (&SlackGuestUserSchemaExtension{Expiration: "2020-11-30T23:59:59Z"}).ExpirationTime()
+	require.Nil(t, err)
+	require.Equal(t, time.Date(2020, 11, 30, 23, 59, 59, 0, time.UTC), exp)
+	_, err = (&SlackGuestUserSchemaExtension{Expiration: "foo"}).ExpirationTime()
+	require.NotNil(t, err)
+}
+
+func TestUser_UnmarshalJSON_guest(t *testing.T) {
+	user := &User{}
+	require.Nil(t, user.UnmarshalJSON([]byte(`{
+  "id": "U1",
+  "urn:scim:schemas:extension:slack:guest:1.0": {
+    "type": "multi",
+    "expiration": "2020-11-30T23:59:59Z"
+  }
+}`)))
+	require.Equal(t, &SlackGuestUserSchemaExtension{
+		Type:       GuestTypeMultiChannel,
+		Expiration: "2020-11-30T23:59:59Z",
+	}, user.SlackGuestUserSchemaExtension)
+}
+
+func TestClient_CreateGuest(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	user := &User{UserName: "foo"}
+
+	_, _, err := client.CreateGuest(ctx, nil, GuestTypeMultiChannel, time.Time{})
+	require.NotNil(t, err)
+	_, _, err = client.CreateGuest(ctx, user, "foo", time.Time{})
+	require.NotNil(t, err)
+
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"userName": "foo",
+			"groups":   []interface{}{},
+			"schemas":  []string{SchemaCore, SchemaSlackGuest},
+			SchemaSlackGuest: map[string]string{
+				"type":       "single",
+				"expiration": "2020-11-30T23:59:59Z",
+			},
+		}).
+		Reply(201).
+		BodyString(`{"id": "U1", "userName": "foo", "urn:scim:schemas:extension:slack:guest:1.0": {"type": "single"}}`)
+	guest, _, err := client.CreateGuest(
+		ctx, user, GuestTypeSingleChannel,
+		time.Date(2020, 12, 1, 8, 59, 59, 0, time.FixedZone("Asia/Tokyo", 9*60*60)))
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.True(t, guest.IsGuest())
+	require.Nil(t, user.SlackGuestUserSchemaExtension)
+}
+
+func TestClient_ConvertGuestToMember(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U1").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore},
+			"meta": map[string]interface{}{
+				"attributes": []string{SchemaSlackGuest},
+			},
+		}).
+		Reply(200).
+		BodyString(`{"id": "U1"}`)
+	user, _, err := NewClient("XXX").ConvertGuestToMember(context.Background(), "U1")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.False(t, user.IsGuest())
+}
+
+func TestClient_SetGuestExpiration(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	_, _, err := client.SetGuestExpiration(ctx, "U1", time.Time{})
+	require.NotNil(t, err)
+
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U1").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore, SchemaSlackGuest},
+			SchemaSlackGuest: map[string]string{
+				"expiration": "2021-01-01T00:00:00Z",
+			},
+		}).
+		Reply(200).
+		BodyString(`{"id": "U1"}`)
+	_, _, err = client.SetGuestExpiration(ctx, "U1", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+}
+
+func TestGuestReport(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	guest := func(id, exp string) User {
+		return User{
+			ID:     id,
+			Active: true,
+			SlackGuestUserSchemaExtension: &SlackGuestUserSchemaExtension{
+				Type:       GuestTypeMultiChannel,
+				Expiration: exp,
+			},
+		}
+	}
+	users := []User{
+		{ID: "U1", Active: true},
+		guest("U2", "2021-01-05T00:00:00Z"),
+		guest("U3", "2020-12-31T00:00:00Z"),
+		guest("U4", "2021-03-01T00:00:00Z"),
+		guest("U5", ""),
+		{ID: "U6", SlackGuestUserSchemaExtension: &SlackGuestUserSchemaExtension{Expiration: "2021-01-02T00:00:00Z"}},
+	}
+	report, err := GuestReport(users, now, 7*24*time.Hour)
+	require.Nil(t, err)
+	require.Equal(t, []GuestExpiry{
+		{User: users[2], Expiration: time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), Expired: true},
+		{User: users[1], Expiration: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)},
+	}, report)
+
+	_, err = GuestReport([]User{guest("U1", "foo")}, now, time.Hour)
+	require.NotNil(t, err)
+}
+
+func Test_appendSchema(t *testing.T) {
+	require.Equal(t, []string{SchemaCore, SchemaSlackGuest}, appendSchema(nil, SchemaSlackGuest))
+	require.Equal(t, []string{SchemaCore, SchemaSlackGuest}, appendSchema([]string{SchemaCore}, SchemaSlackGuest))
+	require.Equal(t, []string{SchemaCore, SchemaSlackGuest}, appendSchema([]string{SchemaCore, SchemaSlackGuest}, SchemaSlackGuest))
+}
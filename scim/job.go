@@ -0,0 +1,128 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type (
+	// UserJobFunc processes a user in Client.RunUserJob .
+	// If the operation has already been done, for example the user has already been patched,
+	// UserJobFunc should return ErrAlreadyDone, which is treated as success.
+	UserJobFunc func(ctx context.Context, user *User) error
+
+	// UserJobOption is an option of Client.RunUserJob .
+	UserJobOption struct {
+		// Filter is a filter of GET /Users API.
+		Filter string
+		// PageSize is the count of users per page. If PageSize is zero, 1000 is used.
+		PageSize int
+		// IsAlreadyDone returns true if the error of UserJobFunc means the operation has already been done.
+		// For example, a 404 error of DELETE /Users/{id} API can be treated as success.
+		// Errors wrapping ErrAlreadyDone are always treated as success.
+		IsAlreadyDone func(err error) bool
+		// If StopOnError is true, the job stops at the first error of UserJobFunc.
+		// Otherwise the failed users are reported and the job continues.
+		StopOnError bool
+	}
+
+	// JobReport is a result of Client.RunUserJob .
+	JobReport struct {
+		// Processed is ids of users processed in this run.
+		Processed []string
+		// AlreadyDone is ids of users whose operations had already been done.
+		AlreadyDone []string
+		// Skipped is ids of users who had been processed in the previous runs according to the checkpoint.
+		Skipped []string
+		Failed  []UserError
+	}
+)
+
+var (
+	// ErrAlreadyDone means the operation has already been done. See UserJobFunc .
+	ErrAlreadyDone = errors.New("the operation has already been done")
+)
+
+// RunUserJob calls GET /Users API page by page and calls fn for each user.
+// Progress is persisted to checkpoint: ids of processed users, and the start index of the next page
+// after all users of the previous pages are processed.
+// When the job is run again with the same checkpoint, it resumes from the recorded page and skips processed users.
+// Users whose fn failed aren't recorded, so they are retried in the next run.
+//
+// Note that if fn changes whether users match opts.Filter, pages shift and some users may be skipped in this run.
+// Run the job again until no user is processed to process such users.
+//
+// Unless opts.StopOnError is true, the job goes on after fn fails and the failures are joined into the returned error.
+// The report is returned with an error too, so that processed and failed users can be checked.
+func (c *Client) RunUserJob(ctx context.Context, checkpoint *Checkpoint, opts *UserJobOption, fn UserJobFunc) (_ *JobReport, err error) {
+	ctx, span := c.startMethodSpan(ctx, "RunUserJob")
+	defer func() {
+		span.end(err)
+	}()
+	report := &JobReport{
+		Processed:   []string{},
+		AlreadyDone: []string{},
+		Skipped:     []string{},
+	}
+	if checkpoint == nil {
+		return report, fmt.Errorf("checkpoint is required")
+	}
+	if fn == nil {
+		return report, fmt.Errorf("fn is required")
+	}
+	if opts == nil {
+		opts = &UserJobOption{}
+	}
+	page := &Pagination{Count: opts.PageSize, StartIndex: checkpoint.StartIndex()}
+	if page.Count == 0 {
+		page.Count = maxPageCount
+	}
+	// the start index isn't advanced after a failure, so that the failed user is retried in the next run
+	advance := true
+	for {
+		users, _, err := c.GetUsers(ctx, page, opts.Filter)
+		if err != nil {
+			return report, fmt.Errorf("failed to get users: %w", err)
+		}
+		for i := range users.Resources {
+			user := &users.Resources[i]
+			if checkpoint.Done(user.ID) {
+				report.Skipped = append(report.Skipped, user.ID)
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			err := fn(ctx, user)
+			switch {
+			case err == nil:
+				report.Processed = append(report.Processed, user.ID)
+			case errors.Is(err, ErrAlreadyDone) || (opts.IsAlreadyDone != nil && opts.IsAlreadyDone(err)):
+				report.AlreadyDone = append(report.AlreadyDone, user.ID)
+			default:
+				report.Failed = append(report.Failed, UserError{UserID: user.ID, Err: err})
+				advance = false
+				if opts.StopOnError {
+					return report, fmt.Errorf("failed to process the user %s: %w", user.ID, err)
+				}
+				continue
+			}
+			if err := checkpoint.MarkDone(user.ID); err != nil {
+				return report, fmt.Errorf("the user %s is processed but failed to record the checkpoint: %w", user.ID, err)
+			}
+		}
+		if len(users.Resources) == 0 || page.StartIndex+len(users.Resources) > users.TotalResults {
+			break
+		}
+		page.StartIndex += len(users.Resources)
+		if advance {
+			if err := checkpoint.SetStartIndex(page.StartIndex); err != nil {
+				return report, fmt.Errorf("failed to record the checkpoint: %w", err)
+			}
+		}
+	}
+	return report, joinErrors("failed to process users", len(report.Failed), func(i int) error {
+		return &report.Failed[i]
+	})
+}
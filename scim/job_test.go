@@ -0,0 +1,126 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_RunUserJob(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "job.jsonl")
+	cp, err := OpenCheckpoint(path)
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	opts := &UserJobOption{Filter: `active eq true`, PageSize: 2}
+
+	page := func(startIndex string, body string) {
+		gock.New("https://api.slack.com").
+			Get("/scim/v1/Users").
+			MatchParam("startIndex", startIndex).
+			MatchParam("count", "2").
+			MatchParam("filter", "active eq true").
+			Reply(200).
+			BodyString(body)
+	}
+	page("1", `{"totalResults": 5, "Resources": [{"id": "U1"}, {"id": "U2"}]}`)
+	page("3", `{"totalResults": 5, "Resources": [{"id": "U3"}, {"id": "U4"}]}`)
+	page("5", `{"totalResults": 5, "Resources": [{"id": "U5"}]}`)
+
+	calls := []string{}
+	report, err := client.RunUserJob(ctx, cp, opts, func(ctx context.Context, user *User) error {
+		calls = append(calls, user.ID)
+		switch user.ID {
+		case "U2":
+			return fmt.Errorf("patched: %w", ErrAlreadyDone)
+		case "U4":
+			return fmt.Errorf("failed")
+		}
+		return nil
+	})
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+	require.Equal(t, []string{"U1", "U2", "U3", "U4", "U5"}, calls)
+	require.Equal(t, []string{"U1", "U3", "U5"}, report.Processed)
+	require.Equal(t, []string{"U2"}, report.AlreadyDone)
+	require.Len(t, report.Failed, 1)
+	require.Equal(t, "U4", report.Failed[0].UserID)
+	// the page of U4 isn't recorded as completed
+	require.Equal(t, 3, cp.StartIndex())
+	require.Nil(t, cp.Close())
+
+	// resume
+	cp, err = OpenCheckpoint(path)
+	require.Nil(t, err)
+	defer cp.Close()
+	page("3", `{"totalResults": 5, "Resources": [{"id": "U3"}, {"id": "U4"}]}`)
+	page("5", `{"totalResults": 5, "Resources": [{"id": "U5"}]}`)
+	calls = []string{}
+	report, err = client.RunUserJob(ctx, cp, opts, func(ctx context.Context, user *User) error {
+		calls = append(calls, user.ID)
+		return nil
+	})
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Equal(t, []string{"U4"}, calls)
+	require.Equal(t, []string{"U3", "U5"}, report.Skipped)
+	require.Equal(t, 5, cp.StartIndex())
+}
+
+func TestClient_RunUserJob_stopOnError(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cp, err := OpenCheckpoint(filepath.Join(dir, "job.jsonl"))
+	require.Nil(t, err)
+	defer cp.Close()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 3, "Resources": [{"id": "U1"}, {"id": "U2"}, {"id": "U3"}]}`)
+	notFound := &Error{Code: 404}
+	report, err := client.RunUserJob(ctx, cp, &UserJobOption{
+		StopOnError: true,
+		IsAlreadyDone: func(err error) bool {
+			return err == notFound
+		},
+	}, func(ctx context.Context, user *User) error {
+		switch user.ID {
+		case "U1":
+			return notFound
+		case "U2":
+			return fmt.Errorf("failed")
+		}
+		return nil
+	})
+	require.NotNil(t, err)
+	require.Equal(t, []string{"U1"}, report.AlreadyDone)
+	require.Len(t, report.Failed, 1)
+	require.Empty(t, report.Processed)
+	require.True(t, cp.Done("U1"))
+	require.False(t, cp.Done("U2"))
+
+	_, err = client.RunUserJob(ctx, nil, nil, func(ctx context.Context, user *User) error { return nil })
+	require.NotNil(t, err)
+	_, err = client.RunUserJob(ctx, cp, nil, nil)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,322 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type (
+	// Journal records inverse operations of mutations to undo them by Client.Rollback .
+	// Journal.Guard and Journal.Hook should be set to the client, because pre-images are required to compute inverses.
+	// Journal is encoded to JSON, so it can be saved and rolled back later.
+	// Journal is safe for concurrent use.
+	Journal struct {
+		Entries []JournalEntry `json:"entries"`
+		now     func() time.Time
+		mutex   sync.Mutex
+	}
+
+	// JournalEntry is a mutation and its inverse operation.
+	JournalEntry struct {
+		Time         time.Time `json:"time"`
+		Operation    string    `json:"operation"`
+		ResourceType string    `json:"resourceType"`
+		ResourceID   string    `json:"resourceId"`
+		// Inverse is nil if the mutation can't be undone, for example the pre-image couldn't be got
+		// or the id of the created resource is unknown.
+		Inverse *InverseOperation `json:"inverse,omitempty"`
+	}
+
+	// InverseOperation is an operation to undo a mutation.
+	// Operation is one of MutationCreate, MutationPatch, MutationPut, and MutationDelete,
+	// and one of User, UserPatch, and Group is set as the request body.
+	InverseOperation struct {
+		Operation    string     `json:"operation"`
+		ResourceType string     `json:"resourceType"`
+		ResourceID   string     `json:"resourceId,omitempty"`
+		User         *User      `json:"user,omitempty"`
+		UserPatch    *UserPatch `json:"userPatch,omitempty"`
+		Group        *Group     `json:"group,omitempty"`
+	}
+
+	// RollbackReport is a result of Client.Rollback .
+	RollbackReport struct {
+		// Restored is entries which are undone.
+		Restored []JournalEntry
+		// Failed is entries which couldn't be undone.
+		Failed []RollbackError
+	}
+
+	// RollbackError is an error of undoing a journal entry.
+	RollbackError struct {
+		Entry JournalEntry
+		Err   error
+	}
+
+	// rollbackKey is the context key of the journal which is being rolled back by Client.Rollback .
+	rollbackKey struct{}
+)
+
+// Error returns the error message.
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("%s %s %s: %v", e.Entry.Operation, e.Entry.ResourceType, e.Entry.ResourceID, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// NewJournal returns an empty journal.
+func NewJournal() *Journal {
+	return &Journal{
+		Entries: []JournalEntry{},
+		now:     time.Now,
+	}
+}
+
+// ReadJournal reads a journal encoded in JSON.
+func ReadJournal(r io.Reader) (*Journal, error) {
+	journal := NewJournal()
+	if err := json.NewDecoder(r).Decode(journal); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// WriteJSON writes the journal in JSON.
+func (journal *Journal) WriteJSON(w io.Writer) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(journal)
+}
+
+// Guard gets the pre-image of the mutation. Guard is a MutationGuard .
+// Guard doesn't refuse the mutation even if the pre-image can't be got,
+// and then the mutation is recorded without the inverse operation.
+func (journal *Journal) Guard(ctx context.Context, mutation *Mutation) error {
+	_, _ = mutation.PreImage(ctx)
+	return nil
+}
+
+// Hook records the inverse operation of the mutation. Hook is a MutationHook .
+// Failed mutations aren't recorded.
+// Mutations sent by Client.Rollback to roll back the journal itself aren't recorded either.
+func (journal *Journal) Hook(ctx context.Context, mutation *Mutation) {
+	if mutation.Err != nil {
+		return
+	}
+	if j, ok := ctx.Value(rollbackKey{}).(*Journal); ok && j == journal {
+		return
+	}
+	preImage, _ := mutation.PreImage(ctx)
+	entry := JournalEntry{
+		Operation:    mutation.Operation,
+		ResourceType: mutation.ResourceType,
+		ResourceID:   mutation.ResourceID,
+		Inverse:      newInverseOperation(mutation, preImage),
+	}
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	now := journal.now
+	if now == nil {
+		now = time.Now
+	}
+	entry.Time = now().UTC()
+	journal.Entries = append(journal.Entries, entry)
+}
+
+func newInverseOperation(mutation *Mutation, preImage interface{}) *InverseOperation {
+	if mutation.ResourceID == "" {
+		// the response body of the raw API such as Client.CreateUserResp isn't parsed,
+		// so the id of the created resource is unknown.
+		return nil
+	}
+	inverse := &InverseOperation{
+		ResourceType: mutation.ResourceType,
+		ResourceID:   mutation.ResourceID,
+	}
+	switch mutation.ResourceType {
+	case ResourceTypeUser:
+		switch mutation.Operation {
+		case MutationCreate:
+			inverse.Operation = MutationDelete
+			return inverse
+		case MutationDelete:
+			active := true
+			inverse.Operation = MutationPatch
+			inverse.UserPatch = &UserPatch{
+				Schemas: []string{SchemaCore},
+				Active:  &active,
+			}
+			return inverse
+		}
+		user, ok := preImage.(*User)
+		if !ok || user == nil {
+			return nil
+		}
+		u := *user
+		// groups and meta are read-only
+		u.Groups = nil
+		u.Meta = nil
+		inverse.Operation = MutationPut
+		inverse.User = &u
+		return inverse
+	case ResourceTypeGroup:
+		if mutation.Operation == MutationCreate {
+			inverse.Operation = MutationDelete
+			return inverse
+		}
+		group, ok := preImage.(*Group)
+		if !ok || group == nil {
+			return nil
+		}
+		switch mutation.Operation {
+		case MutationPatch:
+			patch, ok := mutation.Request.(*Group)
+			if !ok || patch == nil {
+				return nil
+			}
+			inverse.Operation = MutationPatch
+			inverse.Group = invertGroupPatch(group, patch)
+			return inverse
+		case MutationPut:
+			inverse.Operation = MutationPut
+			inverse.Group = &Group{
+				Schemas:     []string{SchemaCore},
+				DisplayName: group.DisplayName,
+				ExternalID:  group.ExternalID,
+				Members:     group.Members,
+			}
+			return inverse
+		case MutationDelete:
+			// the group is recreated with a new id
+			inverse.Operation = MutationCreate
+			inverse.ResourceID = ""
+			inverse.Group = &Group{
+				Schemas:     []string{SchemaCore},
+				DisplayName: group.DisplayName,
+				ExternalID:  group.ExternalID,
+				Members:     group.Members,
+			}
+			return inverse
+		}
+	}
+	return nil
+}
+
+// invertGroupPatch returns a patch which undoes patch applied to current.
+func invertGroupPatch(current, patch *Group) *Group {
+	members := make(map[string]Member, len(current.Members))
+	for _, member := range current.Members {
+		members[member.Value] = member
+	}
+	inverse := &Group{
+		Schemas: []string{SchemaCore},
+		Members: []Member{},
+	}
+	if patch.DisplayName != "" && patch.DisplayName != current.DisplayName {
+		inverse.DisplayName = current.DisplayName
+	}
+	for _, member := range patch.Members {
+		cur, ok := members[member.Value]
+		switch {
+		case member.Operation == MemberOperationDelete && ok:
+			inverse.Members = append(inverse.Members, Member{
+				Value:   cur.Value,
+				Display: cur.Display,
+			})
+		case member.Operation != MemberOperationDelete && !ok:
+			inverse.Members = append(inverse.Members, Member{
+				Value:     member.Value,
+				Display:   member.Display,
+				Operation: MemberOperationDelete,
+			})
+		}
+	}
+	return inverse
+}
+
+// Rollback undoes mutations recorded in the journal in reverse order.
+// An entry which can't be undone, for example an entry without the inverse operation, is skipped and recorded in
+// the report's Failed, and the older entries are still undone.
+// Note that a deleted group is recreated with a new id, because the id can't be specified.
+// If Journal.Hook of the journal is set to the client, the inverse operations aren't recorded to the journal,
+// but they are recorded to other journals.
+// The report is returned with an error too, so that the failed entries can be retried.
+func (c *Client) Rollback(ctx context.Context, journal *Journal) (_ *RollbackReport, err error) {
+	ctx, span := c.startMethodSpan(ctx, "Rollback")
+	defer func() {
+		span.end(err)
+	}()
+	report := &RollbackReport{}
+	if journal == nil {
+		return report, fmt.Errorf("journal is required")
+	}
+	journal.mutex.Lock()
+	entries := make([]JournalEntry, len(journal.Entries))
+	copy(entries, journal.Entries)
+	journal.mutex.Unlock()
+	// the inverse operations aren't recorded to the journal by Journal.Hook
+	ctx = context.WithValue(ctx, rollbackKey{}, journal)
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if err := c.undo(ctx, entry.Inverse); err != nil {
+			report.Failed = append(report.Failed, RollbackError{
+				Entry: entry,
+				Err:   err,
+			})
+			continue
+		}
+		report.Restored = append(report.Restored, entry)
+	}
+	return report, joinErrors("failed to roll back", len(report.Failed), func(i int) error {
+		return &report.Failed[i]
+	})
+}
+
+func (c *Client) undo(ctx context.Context, inverse *InverseOperation) error {
+	if inverse == nil {
+		return fmt.Errorf("the mutation can't be undone")
+	}
+	var err error
+	switch inverse.ResourceType {
+	case ResourceTypeUser:
+		switch inverse.Operation {
+		case MutationDelete:
+			_, err = c.DeleteUser(ctx, inverse.ResourceID)
+		case MutationPatch:
+			_, _, err = c.PatchUser(ctx, inverse.ResourceID, inverse.UserPatch)
+		case MutationPut:
+			_, _, err = c.PutUser(ctx, inverse.ResourceID, inverse.User)
+		default:
+			err = fmt.Errorf("unsupported inverse operation: %s", inverse.Operation)
+		}
+	case ResourceTypeGroup:
+		switch inverse.Operation {
+		case MutationCreate:
+			_, _, err = c.CreateGroup(ctx, inverse.Group)
+		case MutationDelete:
+			_, err = c.DeleteGroup(ctx, inverse.ResourceID)
+		case MutationPatch:
+			_, _, err = c.patchGroup(ctx, inverse.ResourceID, inverse.Group, newGroupPatch(inverse.Group))
+			if isPatchedGroupError(err) {
+				err = nil
+			}
+		case MutationPut:
+			_, _, err = c.PutGroup(ctx, inverse.ResourceID, inverse.Group)
+		default:
+			err = fmt.Errorf("unsupported inverse operation: %s", inverse.Operation)
+		}
+	default:
+		err = fmt.Errorf("unsupported resource type: %s", inverse.ResourceType)
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	journal := NewJournal()
+	client := NewClient("XXX").
+		WithMutationGuard(journal.Guard).
+		WithMutationHook(journal.Hook)
+
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(201).
+		BodyString(`{"id": "U1", "userName": "foo"}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U2").
+		Reply(200).
+		BodyString(`{"id": "U2", "userName": "bar", "title": "old", "active": true, "groups": [{"value": "G1"}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U2").
+		Reply(200).
+		BodyString(`{"id": "U2", "userName": "bar", "title": "new", "active": true}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "displayName": "old", "members": [{"value": "U2", "display": "bar"}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "displayName": "new", "members": [{"value": "U1"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U3").
+		Reply(200).
+		BodyString(`{"id": "U3", "active": true}`)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U3").
+		Reply(204)
+
+	_, _, err := client.CreateUser(ctx, &User{UserName: "foo"})
+	require.Nil(t, err)
+	title := "new"
+	_, _, err = client.PatchUser(ctx, "U2", &UserPatch{Title: &title})
+	require.Nil(t, err)
+	_, _, err = client.PatchGroup(ctx, "G1", &Group{
+		DisplayName: "new",
+		Members: []Member{
+			{Value: "U1"},
+			{Value: "U2", Operation: MemberOperationDelete},
+		},
+	})
+	require.Nil(t, err)
+	_, err = client.DeleteUser(ctx, "U3")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+
+	require.Len(t, journal.Entries, 4)
+	require.Equal(t, &InverseOperation{
+		Operation:    MutationDelete,
+		ResourceType: ResourceTypeUser,
+		ResourceID:   "U1",
+	}, journal.Entries[0].Inverse)
+	require.Equal(t, &InverseOperation{
+		Operation:    MutationPut,
+		ResourceType: ResourceTypeUser,
+		ResourceID:   "U2",
+		User:         &User{ID: "U2", UserName: "bar", Title: "old", Active: true},
+	}, journal.Entries[1].Inverse)
+	require.Equal(t, &Group{
+		Schemas:     []string{SchemaCore},
+		DisplayName: "old",
+		Members: []Member{
+			{Value: "U1", Operation: MemberOperationDelete},
+			{Value: "U2", Display: "bar"},
+		},
+	}, journal.Entries[2].Inverse.Group)
+	active := true
+	require.Equal(t, &InverseOperation{
+		Operation:    MutationPatch,
+		ResourceType: ResourceTypeUser,
+		ResourceID:   "U3",
+		UserPatch:    &UserPatch{Schemas: []string{SchemaCore}, Active: &active},
+	}, journal.Entries[3].Inverse)
+
+	// the journal can be saved and read
+	buf := &bytes.Buffer{}
+	require.Nil(t, journal.WriteJSON(buf))
+	journal, err = ReadJournal(buf)
+	require.Nil(t, err)
+	require.Len(t, journal.Entries, 4)
+
+	// roll back in reverse order
+	journal.Entries = append(journal.Entries, JournalEntry{
+		Operation:    MutationDelete,
+		ResourceType: ResourceTypeGroup,
+		ResourceID:   "G2",
+	})
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U3").
+		Reply(200).
+		BodyString(`{"id": "U3", "active": true}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		MatchType("json").
+		JSON(newGroupPatch(journal.Entries[2].Inverse.Group)).
+		Reply(200).
+		BodyString(`{"id": "G1", "displayName": "old", "members": [{"value": "U2"}]}`)
+	gock.New("https://api.slack.com").
+		Put("/scim/v1/Users/U2").
+		Reply(200).
+		BodyString(`{"id": "U2", "userName": "bar", "title": "old", "active": true}`)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U1").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+	report, err := NewClient("XXX").Rollback(ctx, journal)
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+	require.Len(t, report.Restored, 3)
+	require.Equal(t, "U3", report.Restored[0].ResourceID)
+	require.Len(t, report.Failed, 2)
+	require.Equal(t, "G2", report.Failed[0].Entry.ResourceID)
+	require.Equal(t, "U1", report.Failed[1].Entry.ResourceID)
+}
+
+func TestClient_Rollback_hook(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	journal := NewJournal()
+	other := NewJournal()
+	client := NewClient("XXX").WithMutationHook(MutationHooks(journal.Hook, other.Hook))
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(201).
+		BodyString(`{"id": "U1", "userName": "foo"}`)
+	_, _, err := client.CreateUser(ctx, &User{UserName: "foo"})
+	require.Nil(t, err)
+	require.Len(t, journal.Entries, 1)
+
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U1").
+		Reply(204)
+	report, err := client.Rollback(ctx, journal)
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Len(t, report.Restored, 1)
+	// the inverse operation isn't recorded to the journal which is rolled back
+	require.Len(t, journal.Entries, 1)
+	require.Len(t, other.Entries, 2)
+	require.Equal(t, MutationDelete, other.Entries[1].Operation)
+}
+
+func TestJournal_Hook_createResp(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	journal := NewJournal()
+	client := NewClient("XXX").WithMutationHook(journal.Hook)
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(201).
+		BodyString(`{"id": "U1", "userName": "foo"}`)
+	resp, err := client.CreateUserResp(ctx, &User{UserName: "foo"})
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Len(t, journal.Entries, 1)
+	// the id of the user created by the raw API is unknown
+	require.Equal(t, "", journal.Entries[0].ResourceID)
+	require.Nil(t, journal.Entries[0].Inverse)
+
+	report, err := client.Rollback(ctx, journal)
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+	require.Empty(t, report.Restored)
+	require.Len(t, report.Failed, 1)
+	require.Contains(t, err.Error(), "can't be undone")
+}
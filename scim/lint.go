@@ -0,0 +1,311 @@
+package scim
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// SeverityError is the severity of issues which should be fixed.
+	SeverityError = "error"
+	// SeverityWarning is the severity of issues which may be problems.
+	SeverityWarning = "warning"
+	// SeverityInfo is the severity of informational issues.
+	SeverityInfo = "info"
+	// SeverityOff disables the rule.
+	SeverityOff = "off"
+)
+
+type (
+	// LintRule is a rule to check data quality of users and groups.
+	// CheckUser and CheckGroup return messages of violations, and either of them may be nil.
+	LintRule struct {
+		ID          string
+		Description string
+		// Severity is the default severity of the rule's issues.
+		Severity   string
+		CheckUser  func(user *User, env *LintEnv) []string
+		CheckGroup func(group *Group, env *LintEnv) []string
+	}
+
+	// LintEnv is passed to rules' checks.
+	LintEnv struct {
+		Directory *Directory
+		Config    *LintConfig
+	}
+
+	// LintConfig is a configuration of Linter .
+	LintConfig struct {
+		// Severities overrides the rules' severities. The keys are rule ids.
+		// To disable a rule, set SeverityOff .
+		Severities map[string]string `json:"severities,omitempty"`
+		// EmailTypes is canonical values of Email.Type .
+		// If EmailTypes is empty, DefaultEmailTypes is used.
+		EmailTypes []string `json:"emailTypes,omitempty"`
+		// If IncludeInactive is true, deactivated users are checked too.
+		IncludeInactive bool `json:"includeInactive,omitempty"`
+	}
+
+	// LintIssue is a violation of a rule.
+	LintIssue struct {
+		RuleID   string `json:"ruleId"`
+		Severity string `json:"severity"`
+		// ResourceType is ResourceTypeUser or ResourceTypeGroup .
+		ResourceType string `json:"resourceType"`
+		ResourceID   string `json:"resourceId"`
+		Message      string `json:"message"`
+	}
+
+	// Linter checks users and groups with rules.
+	// Linter should be created by the function NewLinter .
+	Linter struct {
+		rules  []LintRule
+		config *LintConfig
+	}
+)
+
+var (
+	// DefaultEmailTypes is the default canonical values of Email.Type .
+	DefaultEmailTypes = []string{"work", "home", "other"}
+)
+
+// DefaultLintRules returns the built-in rules.
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		{
+			ID:          "manager-not-found",
+			Description: "the user's manager doesn't exist",
+			Severity:    SeverityError,
+			CheckUser: func(user *User, env *LintEnv) []string {
+				id := managerID(user)
+				if id == "" {
+					return nil
+				}
+				if _, ok := env.Directory.User(id); !ok {
+					return []string{fmt.Sprintf("the manager %s doesn't exist", id)}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "manager-inactive",
+			Description: "the user's manager is deactivated",
+			Severity:    SeverityError,
+			CheckUser: func(user *User, env *LintEnv) []string {
+				manager, ok := env.Directory.User(managerID(user))
+				if ok && !manager.Active {
+					return []string{fmt.Sprintf("the manager %s is deactivated", manager.ID)}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "no-primary-email",
+			Description: "the user has no primary email",
+			Severity:    SeverityError,
+			CheckUser: func(user *User, env *LintEnv) []string {
+				for _, email := range user.Emails {
+					if email.Primary {
+						return nil
+					}
+				}
+				return []string{"the user has no primary email"}
+			},
+		},
+		{
+			ID:          "multiple-primary-emails",
+			Description: "the user has multiple primary emails",
+			Severity:    SeverityError,
+			CheckUser: func(user *User, env *LintEnv) []string {
+				cnt := 0
+				for _, email := range user.Emails {
+					if email.Primary {
+						cnt++
+					}
+				}
+				if cnt > 1 {
+					return []string{fmt.Sprintf("the user has %d primary emails", cnt)}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "invalid-timezone",
+			Description: "the user's timezone isn't an IANA time zone name",
+			Severity:    SeverityWarning,
+			CheckUser: func(user *User, env *LintEnv) []string {
+				if user.Timezone == "" || isIANATimezone(user.Timezone) {
+					return nil
+				}
+				return []string{fmt.Sprintf("the timezone %q isn't an IANA time zone name", user.Timezone)}
+			},
+		},
+		{
+			ID:          "empty-title",
+			Description: "the user's title is empty",
+			Severity:    SeverityInfo,
+			CheckUser: func(user *User, env *LintEnv) []string {
+				if user.Title == "" {
+					return []string{"the title is empty"}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "empty-department",
+			Description: "the user's department is empty",
+			Severity:    SeverityInfo,
+			CheckUser: func(user *User, env *LintEnv) []string {
+				if user.EnterpriseUserSchemaExtension == nil || user.EnterpriseUserSchemaExtension.Department == "" {
+					return []string{"the department is empty"}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "non-canonical-email-type",
+			Description: "the email's type isn't canonical",
+			Severity:    SeverityWarning,
+			CheckUser: func(user *User, env *LintEnv) []string {
+				types := env.Config.EmailTypes
+				if len(types) == 0 {
+					types = DefaultEmailTypes
+				}
+				var msgs []string
+				for _, email := range user.Emails {
+					if email.Type == "" || containsString(types, email.Type) {
+						continue
+					}
+					msgs = append(msgs, fmt.Sprintf(
+						"the type %q of the email %s isn't one of %s", email.Type, email.Value, strings.Join(types, ", ")))
+				}
+				return msgs
+			},
+		},
+		{
+			ID:          "empty-group",
+			Description: "the group has no member",
+			Severity:    SeverityWarning,
+			CheckGroup: func(group *Group, env *LintEnv) []string {
+				if len(group.Members) == 0 {
+					return []string{"the group has no member"}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// NewLinter returns a linter with the built-in rules.
+// If cfg is nil, the default configuration is used.
+func NewLinter(cfg *LintConfig) *Linter {
+	if cfg == nil {
+		cfg = &LintConfig{}
+	}
+	return &Linter{
+		rules:  DefaultLintRules(),
+		config: cfg,
+	}
+}
+
+// AddRule adds a custom rule.
+// If a rule with the same id exists, the rule is replaced.
+func (linter *Linter) AddRule(rule LintRule) {
+	for i, r := range linter.rules {
+		if r.ID == rule.ID {
+			linter.rules[i] = rule
+			return
+		}
+	}
+	linter.rules = append(linter.rules, rule)
+}
+
+// Rules returns the linter's rules.
+func (linter *Linter) Rules() []LintRule {
+	return linter.rules
+}
+
+// Lint checks all users and groups of dir and returns a report.
+// Issues are sorted by resource type, resource id, and rule id.
+func (linter *Linter) Lint(dir *Directory) *LintReport {
+	env := &LintEnv{
+		Directory: dir,
+		Config:    linter.config,
+	}
+	report := &LintReport{
+		Rules:  []LintRule{},
+		Issues: []LintIssue{},
+	}
+	users := dir.Users()
+	groups := dir.Groups()
+	for _, rule := range linter.rules {
+		severity := rule.Severity
+		if s, ok := linter.config.Severities[rule.ID]; ok {
+			severity = s
+		}
+		if severity == SeverityOff {
+			continue
+		}
+		rule.Severity = severity
+		report.Rules = append(report.Rules, rule)
+		if rule.CheckUser != nil {
+			for _, user := range users {
+				if !user.Active && !linter.config.IncludeInactive {
+					continue
+				}
+				for _, msg := range rule.CheckUser(user, env) {
+					report.Issues = append(report.Issues, LintIssue{
+						RuleID:       rule.ID,
+						Severity:     severity,
+						ResourceType: ResourceTypeUser,
+						ResourceID:   user.ID,
+						Message:      msg,
+					})
+				}
+			}
+		}
+		if rule.CheckGroup != nil {
+			for _, group := range groups {
+				for _, msg := range rule.CheckGroup(group, env) {
+					report.Issues = append(report.Issues, LintIssue{
+						RuleID:       rule.ID,
+						Severity:     severity,
+						ResourceType: ResourceTypeGroup,
+						ResourceID:   group.ID,
+						Message:      msg,
+					})
+				}
+			}
+		}
+	}
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.ResourceType != b.ResourceType {
+			return a.ResourceType > b.ResourceType
+		}
+		if a.ResourceID != b.ResourceID {
+			return a.ResourceID < b.ResourceID
+		}
+		return a.RuleID < b.RuleID
+	})
+	return report
+}
+
+func isIANATimezone(name string) bool {
+	if name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, a := range list {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
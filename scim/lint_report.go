@@ -0,0 +1,170 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type (
+	// LintReport is a result of Linter.Lint .
+	LintReport struct {
+		// Rules is the enabled rules. Severity is overridden by the configuration.
+		Rules  []LintRule  `json:"-"`
+		Issues []LintIssue `json:"issues"`
+	}
+
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	}
+
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	sarifLocation struct {
+		LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+	}
+
+	sarifLogicalLocation struct {
+		Name               string `json:"name"`
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		Kind               string `json:"kind"`
+	}
+)
+
+// Count returns the number of issues whose severity is severity.
+func (report *LintReport) Count(severity string) int {
+	cnt := 0
+	for _, issue := range report.Issues {
+		if issue.Severity == severity {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// HasErrors returns true if the report has issues whose severity is SeverityError .
+func (report *LintReport) HasErrors() bool {
+	return report.Count(SeverityError) != 0
+}
+
+// WriteText writes the report in the human readable format.
+//
+//	error [no-primary-email] User U0XXXXXXX: the user has no primary email
+func (report *LintReport) WriteText(w io.Writer) error {
+	for _, issue := range report.Issues {
+		if _, err := fmt.Fprintf(
+			w, "%s [%s] %s %s: %s\n",
+			issue.Severity, issue.RuleID, issue.ResourceType, issue.ResourceID, issue.Message,
+		); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(
+		w, "%d errors, %d warnings, %d info\n",
+		report.Count(SeverityError), report.Count(SeverityWarning), report.Count(SeverityInfo))
+	return err
+}
+
+// WriteJSON writes the report in JSON.
+func (report *LintReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteSARIF writes the report in SARIF 2.1.0 .
+// Users and groups are reported as logical locations such as "Users/U0XXXXXXX".
+func (report *LintReport) WriteSARIF(w io.Writer) error {
+	rules := make([]sarifRule, len(report.Rules))
+	for i, rule := range report.Rules {
+		rules[i] = sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Severity)},
+		}
+	}
+	results := make([]sarifResult, len(report.Issues))
+	for i, issue := range report.Issues {
+		results[i] = sarifResult{
+			RuleID:  issue.RuleID,
+			Level:   sarifLevel(issue.Severity),
+			Message: sarifMessage{Text: issue.Message},
+			Locations: []sarifLocation{
+				{
+					LogicalLocations: []sarifLogicalLocation{
+						{
+							Name:               issue.ResourceID,
+							FullyQualifiedName: fmt.Sprintf("%ss/%s", issue.ResourceType, issue.ResourceID),
+							Kind:               issue.ResourceType,
+						},
+					},
+				},
+			},
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "go-slack-scimapi",
+						InformationURI: "https://github.com/suzuki-shunsuke/go-slack-scimapi",
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	})
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -0,0 +1,114 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testLintDirectory() *Directory {
+	return NewDirectory([]User{
+		{
+			ID: "U1", Active: true, Title: "Engineer", Timezone: "Asia/Tokyo",
+			Emails: []Email{{Value: "foo@example.com", Type: "work", Primary: true}},
+			EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+				Department: "Engineering",
+			},
+		},
+		{
+			ID: "U2", Active: true, Timezone: "Mars/Olympus",
+			Emails: []Email{{Value: "bar@example.com", Type: "Work"}},
+			EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+				Manager: &Manager{ManagerID: "U3"},
+			},
+		},
+		{
+			ID: "U3", Active: false,
+		},
+		{
+			ID: "U4", Active: true, Title: "Designer",
+			Emails: []Email{{Value: "baz@example.com", Primary: true}, {Value: "baz2@example.com", Primary: true}},
+			EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+				Department: "Design",
+				Manager:    &Manager{ManagerID: "U5"},
+			},
+		},
+	}, []Group{
+		{ID: "G1", Members: []Member{{Value: "U1"}}},
+		{ID: "G2"},
+	})
+}
+
+func TestLinter_Lint(t *testing.T) {
+	report := NewLinter(nil).Lint(testLintDirectory())
+	require.Equal(t, []LintIssue{
+		{RuleID: "empty-department", Severity: SeverityInfo, ResourceType: ResourceTypeUser, ResourceID: "U2", Message: "the department is empty"},
+		{RuleID: "empty-title", Severity: SeverityInfo, ResourceType: ResourceTypeUser, ResourceID: "U2", Message: "the title is empty"},
+		{RuleID: "invalid-timezone", Severity: SeverityWarning, ResourceType: ResourceTypeUser, ResourceID: "U2", Message: `the timezone "Mars/Olympus" isn't an IANA time zone name`},
+		{RuleID: "manager-inactive", Severity: SeverityError, ResourceType: ResourceTypeUser, ResourceID: "U2", Message: "the manager U3 is deactivated"},
+		{RuleID: "no-primary-email", Severity: SeverityError, ResourceType: ResourceTypeUser, ResourceID: "U2", Message: "the user has no primary email"},
+		{RuleID: "non-canonical-email-type", Severity: SeverityWarning, ResourceType: ResourceTypeUser, ResourceID: "U2", Message: `the type "Work" of the email bar@example.com isn't one of work, home, other`},
+		{RuleID: "manager-not-found", Severity: SeverityError, ResourceType: ResourceTypeUser, ResourceID: "U4", Message: "the manager U5 doesn't exist"},
+		{RuleID: "multiple-primary-emails", Severity: SeverityError, ResourceType: ResourceTypeUser, ResourceID: "U4", Message: "the user has 2 primary emails"},
+		{RuleID: "empty-group", Severity: SeverityWarning, ResourceType: ResourceTypeGroup, ResourceID: "G2", Message: "the group has no member"},
+	}, report.Issues)
+	require.Equal(t, 4, report.Count(SeverityError))
+	require.True(t, report.HasErrors())
+}
+
+func TestLinter_config(t *testing.T) {
+	linter := NewLinter(&LintConfig{
+		Severities: map[string]string{
+			"empty-title":      SeverityOff,
+			"empty-department": SeverityOff,
+			"invalid-timezone": SeverityOff,
+			"empty-group":      SeverityError,
+		},
+		EmailTypes: []string{"Work"},
+	})
+	linter.AddRule(LintRule{
+		ID:       "manager-not-found",
+		Severity: SeverityWarning,
+	})
+	report := linter.Lint(testLintDirectory())
+	require.Equal(t, []LintIssue{
+		{RuleID: "non-canonical-email-type", Severity: SeverityWarning, ResourceType: ResourceTypeUser, ResourceID: "U1", Message: `the type "work" of the email foo@example.com isn't one of Work`},
+		{RuleID: "manager-inactive", Severity: SeverityError, ResourceType: ResourceTypeUser, ResourceID: "U2", Message: "the manager U3 is deactivated"},
+		{RuleID: "no-primary-email", Severity: SeverityError, ResourceType: ResourceTypeUser, ResourceID: "U2", Message: "the user has no primary email"},
+		{RuleID: "multiple-primary-emails", Severity: SeverityError, ResourceType: ResourceTypeUser, ResourceID: "U4", Message: "the user has 2 primary emails"},
+		{RuleID: "empty-group", Severity: SeverityError, ResourceType: ResourceTypeGroup, ResourceID: "G2", Message: "the group has no member"},
+	}, report.Issues)
+}
+
+func TestLintReport_Write(t *testing.T) {
+	report := &LintReport{
+		Rules: []LintRule{
+			{ID: "empty-title", Description: "the user's title is empty", Severity: SeverityInfo},
+		},
+		Issues: []LintIssue{
+			{RuleID: "empty-title", Severity: SeverityInfo, ResourceType: ResourceTypeUser, ResourceID: "U1", Message: "the title is empty"},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, report.WriteText(buf))
+	require.Equal(t, "info [empty-title] User U1: the title is empty\n0 errors, 0 warnings, 1 info\n", buf.String())
+
+	buf.Reset()
+	require.Nil(t, report.WriteJSON(buf))
+	require.JSONEq(t, `{"issues": [{"ruleId": "empty-title", "severity": "info", "resourceType": "User", "resourceId": "U1", "message": "the title is empty"}]}`, buf.String())
+
+	buf.Reset()
+	require.Nil(t, report.WriteSARIF(buf))
+	sarif := map[string]interface{}{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &sarif))
+	require.Equal(t, "2.1.0", sarif["version"])
+	run := sarif["runs"].([]interface{})[0].(map[string]interface{})
+	result := run["results"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "note", result["level"])
+	require.Equal(t, "empty-title", result["ruleId"])
+	location := result["locations"].([]interface{})[0].(map[string]interface{})["logicalLocations"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "Users/U1", location["fullyQualifiedName"])
+}
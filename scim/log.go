@@ -0,0 +1,180 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// LogLevelDebug is the level of logs of request and response bodies.
+	LogLevelDebug LogLevel = iota - 1
+	// LogLevelInfo is the level of logs of succeeded requests.
+	LogLevelInfo
+	// LogLevelWarn is the level of logs of error responses.
+	LogLevelWarn
+	// LogLevelError is the level of logs of requests which failed without responses such as network errors.
+	LogLevelError
+
+	// requestIDHeader is the response header of Slack's request id.
+	requestIDHeader = "X-Slack-Req-Id"
+)
+
+type (
+	// LogLevel is a level of logs. The values are compatible with log/slog's levels divided by 4.
+	LogLevel int
+
+	// LogField is a key value pair of a structured log.
+	LogField struct {
+		Key   string
+		Value interface{}
+	}
+
+	// Logger writes structured logs.
+	// Logger is an interface so that the client doesn't depend on a logging library.
+	// NewSlogLogger returns a Logger of log/slog on Go 1.21 or later.
+	Logger interface {
+		Log(ctx context.Context, level LogLevel, msg string, fields ...LogField)
+	}
+
+	// LoggerFunc is a function which implements Logger .
+	LoggerFunc func(ctx context.Context, level LogLevel, msg string, fields ...LogField)
+
+	// RequestLogOption is an option of NewRequestLogger .
+	RequestLogOption struct {
+		// If LogBodies is true, request and response bodies are logged at LogLevelDebug after values are redacted.
+		// Bodies may contain personal information which isn't covered by RedactFields, so LogBodies is false by default.
+		LogBodies bool
+		// RedactFields is names of fields whose values are redacted in logged bodies.
+		// Names are compared case-insensitively at any depth.
+		// If RedactFields is nil, DefaultLogRedactFields is used.
+		RedactFields []string
+	}
+
+	// RequestLogger logs HTTP requests sent by the client.
+	// Each request is logged with the fields "operation", "method", "path", and "duration",
+	// and "status" and "request_id" if the response is returned.
+	// "filter" is logged with its values redacted by RedactFilter, and "error_code" and "error" are logged if the request fails.
+	// The filter in the URL of "error" is redacted too.
+	// RequestLogger should be created by the function NewRequestLogger and set to the client by Client.SetRequestLogger .
+	RequestLogger struct {
+		logger    Logger
+		logBodies bool
+		redact    map[string]struct{}
+	}
+)
+
+var (
+	// DefaultLogRedactFields is the default names of fields which are redacted in logged bodies.
+	// Unlike audit records, logged bodies don't need to show what was changed,
+	// so contact information is redacted in addition to DefaultAuditRedactFields .
+	DefaultLogRedactFields = append(append([]string{}, DefaultAuditRedactFields...), ContactRedactFields...)
+)
+
+// Log calls fn.
+func (fn LoggerFunc) Log(ctx context.Context, level LogLevel, msg string, fields ...LogField) {
+	fn(ctx, level, msg, fields...)
+}
+
+// String returns the name of the level.
+func (level LogLevel) String() string {
+	switch level {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(level))
+}
+
+// NewRequestLogger returns a request logger which writes logs to logger.
+func NewRequestLogger(logger Logger, opts *RequestLogOption) *RequestLogger {
+	if opts == nil {
+		opts = &RequestLogOption{}
+	}
+	fields := opts.RedactFields
+	if fields == nil {
+		fields = DefaultLogRedactFields
+	}
+	return &RequestLogger{
+		logger:    logger,
+		logBodies: opts.LogBodies,
+		redact:    newRedactFields(fields),
+	}
+}
+
+// RedactBody returns the JSON body whose values of RedactFields are replaced with AuditRedacted .
+// If the body isn't JSON, a placeholder with the size of the body is returned instead of the body.
+func (l *RequestLogger) RedactBody(body []byte) string {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Sprintf("<non-JSON body of %d bytes>", len(body))
+	}
+	b, err := json.Marshal(redactValue(data, l.redact))
+	if err != nil {
+		return fmt.Sprintf("<body of %d bytes>", len(body))
+	}
+	return string(b)
+}
+
+// loggedDo sends the request and logs it.
+func (c *Client) loggedDo(
+	ctx context.Context, operation, method, endpoint string, body []byte, token string,
+) (*http.Response, error) {
+	l := c.requestLogger
+	if l == nil {
+		return c.do(ctx, method, endpoint, body, token)
+	}
+	fields := []LogField{
+		{Key: "operation", Value: operation},
+		{Key: "method", Value: method},
+	}
+	if u, err := url.Parse(endpoint); err == nil {
+		fields = append(fields, LogField{Key: "path", Value: u.Path})
+		if filter := u.Query().Get("filter"); filter != "" {
+			fields = append(fields, LogField{Key: "filter", Value: RedactFilter(filter)})
+		}
+	}
+	if l.logBodies && len(body) != 0 {
+		l.logger.Log(ctx, LogLevelDebug, "request body", withLogField(fields, "body", l.RedactBody(body))...)
+	}
+	start := time.Now()
+	resp, err := c.do(ctx, method, endpoint, body, token)
+	fields = append(fields, LogField{Key: "duration", Value: time.Since(start)})
+	if err != nil {
+		l.logger.Log(ctx, LogLevelError, "request failed", withLogField(fields, "error", redactURLError(err).Error())...)
+		return resp, err
+	}
+	fields = append(fields,
+		LogField{Key: "status", Value: resp.StatusCode},
+		LogField{Key: "request_id", Value: resp.Header.Get(requestIDHeader)},
+	)
+	if l.logBodies {
+		if b, err := peekBody(resp); err == nil && len(b) != 0 {
+			l.logger.Log(ctx, LogLevelDebug, "response body", withLogField(fields, "body", l.RedactBody(b))...)
+		}
+	}
+	if !c.isError(resp) {
+		l.logger.Log(ctx, LogLevelInfo, "request succeeded", fields...)
+		return resp, nil
+	}
+	if code := peekErrorCode(resp); code != 0 {
+		fields = append(fields, LogField{Key: "error_code", Value: code})
+	}
+	l.logger.Log(ctx, LogLevelWarn, "the API returned an error response", fields...)
+	return resp, nil
+}
+
+// withLogField returns a copy of fields with the field, so that fields logged before aren't overwritten by later appends.
+func withLogField(fields []LogField, key string, value interface{}) []LogField {
+	a := make([]LogField, len(fields), len(fields)+1)
+	copy(a, fields)
+	return append(a, LogField{Key: key, Value: value})
+}
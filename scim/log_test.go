@@ -0,0 +1,114 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testLog struct {
+	level  LogLevel
+	msg    string
+	fields map[string]interface{}
+}
+
+func newTestLogger(logs *[]testLog) Logger {
+	return LoggerFunc(func(ctx context.Context, level LogLevel, msg string, fields ...LogField) {
+		m := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			m[field.Key] = field.Value
+		}
+		*logs = append(*logs, testLog{level: level, msg: msg, fields: m})
+	})
+}
+
+func TestClient_loggedDo(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	logs := []testLog{}
+	client := NewClient("XXX").WithRequestLogger(NewRequestLogger(newTestLogger(&logs), nil))
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		SetHeader("X-Slack-Req-Id", "req-1").
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1", "emails": [{"value": "alice@example.com"}]}]}`)
+	users, _, err := client.GetUsers(ctx, nil, `userName eq "alice"`)
+	require.Nil(t, err)
+	require.Len(t, users.Resources, 1)
+	// bodies aren't logged by default
+	require.Len(t, logs, 1)
+	require.Equal(t, LogLevelInfo, logs[0].level)
+	require.Equal(t, "GetUsers", logs[0].fields["operation"])
+	require.Equal(t, "GET", logs[0].fields["method"])
+	require.Equal(t, "/scim/v1/Users", logs[0].fields["path"])
+	require.Equal(t, `userName eq "REDACTED"`, logs[0].fields["filter"])
+	require.Equal(t, 200, logs[0].fields["status"])
+	require.Equal(t, "req-1", logs[0].fields["request_id"])
+	require.Contains(t, logs[0].fields, "duration")
+
+	logs = []testLog{}
+	client.SetRequestLogger(NewRequestLogger(newTestLogger(&logs), &RequestLogOption{LogBodies: true}))
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(409).
+		BodyString(`{"Errors": {"description": "email_taken", "code": 409}}`)
+	_, _, err = client.CreateUser(ctx, &User{
+		UserName: "alice",
+		Password: "secret",
+		Emails:   []Email{{Value: "alice@example.com"}},
+	})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "email_taken")
+	require.True(t, gock.IsDone())
+	require.Len(t, logs, 3)
+	require.Equal(t, LogLevelDebug, logs[0].level)
+	require.JSONEq(t, `{"userName": "alice", "password": "REDACTED", "emails": "REDACTED", "groups": [], "schemas": null}`, logs[0].fields["body"].(string))
+	require.Equal(t, LogLevelDebug, logs[1].level)
+	require.JSONEq(t, `{"Errors": {"description": "email_taken", "code": 409}}`, logs[1].fields["body"].(string))
+	require.Equal(t, LogLevelWarn, logs[2].level)
+	require.Equal(t, 409, logs[2].fields["status"])
+	require.Equal(t, 409, logs[2].fields["error_code"])
+	require.NotContains(t, logs[2].fields, "body")
+}
+
+func TestClient_loggedDo_networkError(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	logs := []testLog{}
+	client := NewClient("XXX").WithRequestLogger(NewRequestLogger(newTestLogger(&logs), nil))
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		ReplyError(errors.New("connection reset"))
+	_, _, err := client.GetUsers(ctx, nil, `userName eq "alice@example.com"`)
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+	require.Len(t, logs, 1)
+	require.Equal(t, LogLevelError, logs[0].level)
+	require.Equal(t, "request failed", logs[0].msg)
+	require.Equal(t, `userName eq "REDACTED"`, logs[0].fields["filter"])
+	require.NotContains(t, logs[0].fields, "status")
+	msg := logs[0].fields["error"].(string)
+	require.NotContains(t, msg, "alice")
+	require.Contains(t, msg, "REDACTED")
+	require.Contains(t, msg, "connection reset")
+}
+
+func TestRequestLogger_RedactBody(t *testing.T) {
+	l := NewRequestLogger(nil, &RequestLogOption{RedactFields: []string{"userName", "value"}})
+	require.JSONEq(t,
+		`{"UserName": "REDACTED", "members": [{"value": "REDACTED", "display": "alice"}]}`,
+		l.RedactBody([]byte(`{"UserName": "alice", "members": [{"value": "U1", "display": "alice"}]}`)))
+	require.Equal(t, "<non-JSON body of 3 bytes>", l.RedactBody([]byte("foo")))
+}
+
+func TestLogLevel_String(t *testing.T) {
+	require.Equal(t, "DEBUG", LogLevelDebug.String())
+	require.Equal(t, "ERROR", LogLevelError.String())
+	require.Equal(t, "LEVEL(5)", LogLevel(5).String())
+}
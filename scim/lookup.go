@@ -0,0 +1,98 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned when no resource matches the condition.
+	ErrNotFound = errors.New("resource isn't found")
+)
+
+// GetUserByExternalID calls GET /Users API with the filter `externalId eq "{externalID}"` and returns the user.
+// If no user is found, an error wrapping ErrNotFound is returned.
+// If multiple users are found, an error is returned.
+// The returned response body is closed.
+func (c *Client) GetUserByExternalID(ctx context.Context, externalID string) (*User, *http.Response, error) {
+	if externalID == "" {
+		return nil, nil, fmt.Errorf("externalID is required")
+	}
+	return c.findUser(ctx, "externalId", externalID)
+}
+
+// GetGroupByExternalID calls GET /Groups API with the filter `externalId eq "{externalID}"` and returns the group.
+// If no group is found, an error wrapping ErrNotFound is returned.
+// If multiple groups are found, an error is returned.
+// The returned response body is closed.
+func (c *Client) GetGroupByExternalID(ctx context.Context, externalID string) (*Group, *http.Response, error) {
+	if externalID == "" {
+		return nil, nil, fmt.Errorf("externalID is required")
+	}
+	return c.findGroup(ctx, "externalId", externalID)
+}
+
+// EnsureGroup gets the group whose externalId is group.ExternalID, and if the group isn't found creates the group.
+// EnsureGroup doesn't update the existing group.
+// The second returned value is true if the group is created.
+func (c *Client) EnsureGroup(ctx context.Context, group *Group) (_ *Group, _ bool, err error) {
+	ctx, span := c.startMethodSpan(ctx, "EnsureGroup")
+	defer func() {
+		span.end(err)
+	}()
+	if group == nil {
+		return nil, false, fmt.Errorf("group is required")
+	}
+	if group.ExternalID == "" {
+		return nil, false, fmt.Errorf("group.ExternalID is required")
+	}
+	existing, _, err := c.GetGroupByExternalID(ctx, group.ExternalID)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
+	created, _, err := c.CreateGroup(ctx, group)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create the group %s: %w", group.ExternalID, err)
+	}
+	return created, true, nil
+}
+
+func (c *Client) getUserByUserName(ctx context.Context, userName string) (*User, error) {
+	user, _, err := c.findUser(ctx, "userName", userName)
+	return user, err
+}
+
+func (c *Client) findUser(ctx context.Context, attr, value string) (*User, *http.Response, error) {
+	users, resp, err := c.GetUsers(ctx, nil, eqFilter(attr, value))
+	if err != nil {
+		return nil, resp, err
+	}
+	switch len(users.Resources) {
+	case 0:
+		return nil, resp, fmt.Errorf("user whose %s is %s: %w", attr, value, ErrNotFound)
+	case 1:
+		return &users.Resources[0], resp, nil
+	default:
+		return nil, resp, fmt.Errorf("multiple users have the %s %s", attr, value)
+	}
+}
+
+func (c *Client) findGroup(ctx context.Context, attr, value string) (*Group, *http.Response, error) {
+	groups, resp, err := c.GetGroups(ctx, nil, eqFilter(attr, value))
+	if err != nil {
+		return nil, resp, err
+	}
+	switch len(groups.Resources) {
+	case 0:
+		return nil, resp, fmt.Errorf("group whose %s is %s: %w", attr, value, ErrNotFound)
+	case 1:
+		return &groups.Resources[0], resp, nil
+	default:
+		return nil, resp, fmt.Errorf("multiple groups have the %s %s", attr, value)
+	}
+}
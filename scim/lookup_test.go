@@ -0,0 +1,135 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetUserByExternalID(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+
+	_, _, err := client.GetUserByExternalID(ctx, "")
+	require.NotNil(t, err)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `externalId eq "E1"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1", "externalId": "E1"}]}`)
+	user, resp, err := client.GetUserByExternalID(ctx, "E1")
+	require.Nil(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	require.Equal(t, &User{ID: "U1", ExternalID: "E1"}, user)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+	_, _, err = client.GetUserByExternalID(ctx, "E2")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 2, "Resources": [{"id": "U1"}, {"id": "U2"}]}`)
+	_, _, err = client.GetUserByExternalID(ctx, "E3")
+	require.NotNil(t, err)
+	require.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestClient_GetGroupByExternalID(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+
+	_, _, err := client.GetGroupByExternalID(ctx, "")
+	require.NotNil(t, err)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		MatchParam("filter", `externalId eq "E1"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "G1", "externalId": "E1"}]}`)
+	group, _, err := client.GetGroupByExternalID(ctx, "E1")
+	require.Nil(t, err)
+	require.Equal(t, &Group{ID: "G1", ExternalID: "E1"}, group)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+	_, _, err = client.GetGroupByExternalID(ctx, "E1")
+	require.NotNil(t, err)
+	require.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestClient_EnsureGroup(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+
+	_, _, err := client.EnsureGroup(ctx, nil)
+	require.NotNil(t, err)
+	_, _, err = client.EnsureGroup(ctx, &Group{DisplayName: "foo"})
+	require.NotNil(t, err)
+
+	group := &Group{ExternalID: "E1", DisplayName: "foo", Schemas: []string{SchemaCore}}
+
+	// the group exists
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		MatchParam("filter", `externalId eq "E1"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "G1", "externalId": "E1", "displayName": "bar"}]}`)
+	g, created, err := client.EnsureGroup(ctx, group)
+	require.Nil(t, err)
+	require.False(t, created)
+	require.Equal(t, "G1", g.ID)
+
+	// the group doesn't exist
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Groups").
+		MatchType("json").
+		JSON(group).
+		Reply(201).
+		BodyString(`{"id": "G2", "externalId": "E1", "displayName": "foo"}`)
+	g, created, err = client.EnsureGroup(ctx, group)
+	require.Nil(t, err)
+	require.True(t, created)
+	require.Equal(t, "G2", g.ID)
+	require.True(t, gock.IsDone())
+
+	// failed to get the group
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+	_, _, err = client.EnsureGroup(ctx, group)
+	require.NotNil(t, err)
+
+	// failed to create the group
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Groups").
+		Reply(409).
+		BodyString(`{"Errors": {"description": "conflict", "code": 409}}`)
+	_, _, err = client.EnsureGroup(ctx, group)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,217 @@
+package scim
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type (
+	// UserMapping is a declarative definition to convert records of a source system such as HRIS to users.
+	// UserMapping can be decoded from JSON.
+	UserMapping struct {
+		// Attributes are evaluated in order, so a later rule overwrites an earlier rule of the same attribute.
+		Attributes []AttributeRule `json:"attributes"`
+		// Tables are lookup tables used by the function lookup(table, key, default) in expressions.
+		Tables map[string]map[string]string `json:"tables,omitempty"`
+	}
+
+	// AttributeRule maps the value of the expression to the attribute.
+	// Attribute is an attribute path of SetAttribute such as "name.givenName" and "emails[work].value".
+	// Expr is an expression of ParseExpr, and identifiers in Expr refer to fields of the source record.
+	// Fields whose names can't be identifiers, such as "First Name", are referred by the function field("First Name").
+	AttributeRule struct {
+		Attribute string `json:"attribute"`
+		Expr      string `json:"expr"`
+	}
+
+	// UserMapper converts records to users by UserMapping.
+	// UserMapper should be created by the function NewUserMapper .
+	UserMapper struct {
+		rules  []compiledAttributeRule
+		tables map[string]map[string]string
+	}
+
+	// UserMappingResult is a user converted from a record and the provenance of its attributes.
+	UserMappingResult struct {
+		User *User
+		// Sources is the provenance of the mapped attributes in order of the rules.
+		Sources []AttributeSource
+	}
+
+	// AttributeSource is the provenance of an attribute.
+	AttributeSource struct {
+		Attribute string
+		Value     interface{}
+		// Fields is the names of the source fields which were read to compute the value.
+		// Fields whose values were null aren't included.
+		Fields []string
+	}
+
+	compiledAttributeRule struct {
+		attribute string
+		expr      *Expr
+		// isString is true if the attribute is a string attribute, and then numbers and bools are converted to strings.
+		isString bool
+	}
+)
+
+// ReadUserMapping reads a JSON mapping from r.
+func ReadUserMapping(r io.Reader) (*UserMapping, error) {
+	mapping := &UserMapping{}
+	if err := json.NewDecoder(r).Decode(mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// NewUserMapper parses expressions of the mapping and returns a mapper.
+func NewUserMapper(mapping *UserMapping) (*UserMapper, error) {
+	if mapping == nil || len(mapping.Attributes) == 0 {
+		return nil, fmt.Errorf("attributes are required")
+	}
+	mapper := &UserMapper{
+		rules:  make([]compiledAttributeRule, len(mapping.Attributes)),
+		tables: mapping.Tables,
+	}
+	for i, rule := range mapping.Attributes {
+		if rule.Attribute == "" {
+			return nil, fmt.Errorf("attribute is required")
+		}
+		expr, err := ParseExpr(rule.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression of the attribute %s: %w", rule.Attribute, err)
+		}
+		mapper.rules[i] = compiledAttributeRule{
+			attribute: rule.Attribute,
+			expr:      expr,
+			isString:  isStringAttribute(rule.Attribute),
+		}
+	}
+	return mapper, nil
+}
+
+// Map converts the record to a user.
+// record is a JSON object decoded to map[string]interface{} or a CSV row read by ReadCSVRecords .
+// Identifiers in expressions are looked up in record by LookupAttribute, so nested fields such as "address.city" can be referred.
+// Attributes whose values are null or empty strings aren't set.
+// Numbers and bools are converted to strings if the attributes are strings such as employeeNumber.
+func (mapper *UserMapper) Map(record map[string]interface{}) (*UserMappingResult, error) {
+	attrs := map[string]interface{}{}
+	sources := []AttributeSource{}
+	for _, rule := range mapper.rules {
+		fields := map[string]struct{}{}
+		env := &ExprEnv{
+			Resolve: func(name string) interface{} {
+				v, _ := LookupAttribute(record, name)
+				if v != nil {
+					fields[name] = struct{}{}
+				}
+				return v
+			},
+			Functions: map[string]ExprFunc{
+				"field": func(args []interface{}) (interface{}, error) {
+					if len(args) != 1 {
+						return nil, fmt.Errorf("field requires one argument")
+					}
+					name := toString(args[0])
+					v := record[name]
+					if v != nil {
+						fields[name] = struct{}{}
+					}
+					return v, nil
+				},
+				"lookup": mapper.lookup,
+			},
+		}
+		v, err := rule.expr.Eval(env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate the attribute %s: %w", rule.attribute, err)
+		}
+		if v == nil || v == "" {
+			continue
+		}
+		switch v.(type) {
+		case float64, bool:
+			// for example, an employee number may be a number in the source system
+			if rule.isString {
+				v = toString(v)
+			}
+		}
+		if err := SetAttribute(attrs, rule.attribute, v); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sources = append(sources, AttributeSource{
+			Attribute: rule.attribute,
+			Value:     v,
+			Fields:    names,
+		})
+	}
+	user, err := UserFromAttributes(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return &UserMappingResult{
+		User:    user,
+		Sources: sources,
+	}, nil
+}
+
+// lookup is the function lookup(table, key, default).
+// If the key isn't found in the table, default is returned. default is optional.
+func (mapper *UserMapper) lookup(args []interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("lookup requires two or three arguments")
+	}
+	name := toString(args[0])
+	table, ok := mapper.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown table: %s", name)
+	}
+	if args[1] != nil {
+		if v, ok := table[toString(args[1])]; ok {
+			return v, nil
+		}
+	}
+	if len(args) == 3 {
+		return args[2], nil
+	}
+	return nil, nil
+}
+
+// ReadCSVRecords reads CSV whose first row is the header and returns rows as records for UserMapper.Map .
+// Keys of records are headers, and empty cells are omitted.
+func ReadCSVRecords(r io.Reader) ([]map[string]interface{}, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("the header is required")
+		}
+		return nil, err
+	}
+	records := []map[string]interface{}{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]interface{}, len(row))
+		for i, cell := range row {
+			if cell != "" {
+				record[header[i]] = cell
+			}
+		}
+		records = append(records, record)
+	}
+}
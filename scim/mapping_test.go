@@ -0,0 +1,143 @@
+package scim
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testUserMapping = `{
+  "attributes": [
+    {"attribute": "userName", "expr": "lower(concat(first_name, \".\", last_name))"},
+    {"attribute": "name.givenName", "expr": "first_name"},
+    {"attribute": "name.familyName", "expr": "last_name"},
+    {"attribute": "displayName", "expr": "coalesce(field(\"Preferred Name\"), concat(first_name, \" \", last_name))"},
+    {"attribute": "emails[work].value", "expr": "replace(lower(email), \"@corp\\\\.example\\\\.com$\", \"@example.com\")"},
+    {"attribute": "emails[work].primary", "expr": "if(email, true)"},
+    {"attribute": "title", "expr": "job.title"},
+    {"attribute": "urn:scim:schemas:extension:enterprise:1.0.department", "expr": "lookup(\"departments\", dept_code, \"Other\")"},
+    {"attribute": "userType", "expr": "if(contractor, \"Contractor\", \"Employee\")"},
+    {"attribute": "active", "expr": "status == \"A\""}
+  ],
+  "tables": {
+    "departments": {"ENG": "Engineering", "SLS": "Sales"}
+  }
+}`
+
+func TestUserMapper_Map(t *testing.T) {
+	mapping, err := ReadUserMapping(strings.NewReader(testUserMapping))
+	require.Nil(t, err)
+	mapper, err := NewUserMapper(mapping)
+	require.Nil(t, err)
+
+	result, err := mapper.Map(map[string]interface{}{
+		"first_name": "Alice",
+		"last_name":  "Smith",
+		"email":      "Alice.Smith@corp.example.com",
+		"job":        map[string]interface{}{"title": "Engineer"},
+		"dept_code":  "ENG",
+		"contractor": false,
+		"status":     "A",
+	})
+	require.Nil(t, err)
+	require.Equal(t, &User{
+		UserName:    "alice.smith",
+		DisplayName: "Alice Smith",
+		Name:        &Name{GivenName: "Alice", FamilyName: "Smith"},
+		Emails:      []Email{{Value: "alice.smith@example.com", Type: "work", Primary: true}},
+		Title:       "Engineer",
+		UserType:    "Employee",
+		Active:      true,
+		Schemas:     []string{SchemaCore, SchemaEnterpriseUser},
+		EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+			Department: "Engineering",
+		},
+	}, result.User)
+	require.Equal(t, []AttributeSource{
+		{Attribute: "userName", Value: "alice.smith", Fields: []string{"first_name", "last_name"}},
+		{Attribute: "name.givenName", Value: "Alice", Fields: []string{"first_name"}},
+		{Attribute: "name.familyName", Value: "Smith", Fields: []string{"last_name"}},
+		{Attribute: "displayName", Value: "Alice Smith", Fields: []string{"first_name", "last_name"}},
+		{Attribute: "emails[work].value", Value: "alice.smith@example.com", Fields: []string{"email"}},
+		{Attribute: "emails[work].primary", Value: true, Fields: []string{"email"}},
+		{Attribute: "title", Value: "Engineer", Fields: []string{"job.title"}},
+		{Attribute: "urn:scim:schemas:extension:enterprise:1.0.department", Value: "Engineering", Fields: []string{"dept_code"}},
+		{Attribute: "userType", Value: "Employee", Fields: []string{"contractor"}},
+		{Attribute: "active", Value: true, Fields: []string{"status"}},
+	}, result.Sources)
+}
+
+func TestUserMapper_Map_numbers(t *testing.T) {
+	mapper, err := NewUserMapper(&UserMapping{
+		Attributes: []AttributeRule{
+			{Attribute: "userName", Expr: "user_name"},
+			{Attribute: "urn:scim:schemas:extension:enterprise:1.0.employeeNumber", Expr: "employee_number"},
+			{Attribute: "urn:scim:schemas:extension:enterprise:1.0.costCenter", Expr: "cost_center"},
+			{Attribute: "nickName", Expr: "remote"},
+			{Attribute: "active", Expr: "active"},
+			{Attribute: "emails[work].value", Expr: "email"},
+			{Attribute: "emails[work].primary", Expr: "active"},
+		},
+	})
+	require.Nil(t, err)
+	result, err := mapper.Map(map[string]interface{}{
+		"user_name":       "alice",
+		"employee_number": float64(12345),
+		"cost_center":     1.5,
+		"remote":          true,
+		"active":          true,
+		"email":           "alice@example.com",
+	})
+	require.Nil(t, err)
+	user := result.User
+	require.Equal(t, "12345", user.EnterpriseUserSchemaExtension.EmployeeNumber)
+	require.Equal(t, "1.5", user.EnterpriseUserSchemaExtension.CostCenter)
+	require.Equal(t, "true", user.NickName)
+	require.True(t, user.Active)
+	require.Equal(t, []Email{{Value: "alice@example.com", Type: "work", Primary: true}}, user.Emails)
+	require.Equal(t, "12345", result.Sources[1].Value)
+}
+
+func TestUserMapper_MapCSV(t *testing.T) {
+	mapping, err := ReadUserMapping(strings.NewReader(testUserMapping))
+	require.Nil(t, err)
+	mapper, err := NewUserMapper(mapping)
+	require.Nil(t, err)
+
+	records, err := ReadCSVRecords(strings.NewReader(`first_name,last_name,Preferred Name,dept_code,contractor,status
+Bob,Jones,Bobby,XXX,true,I
+`))
+	require.Nil(t, err)
+	require.Len(t, records, 1)
+	result, err := mapper.Map(records[0])
+	require.Nil(t, err)
+	require.Equal(t, "bob.jones", result.User.UserName)
+	require.Equal(t, "Bobby", result.User.DisplayName)
+	require.Equal(t, "Other", result.User.EnterpriseUserSchemaExtension.Department)
+	require.Equal(t, "Contractor", result.User.UserType)
+	require.False(t, result.User.Active)
+	require.Nil(t, result.User.Emails)
+	for _, source := range result.Sources {
+		if source.Attribute == "displayName" {
+			require.Equal(t, []string{"Preferred Name"}, source.Fields)
+		}
+	}
+}
+
+func TestNewUserMapper(t *testing.T) {
+	_, err := NewUserMapper(nil)
+	require.NotNil(t, err)
+	_, err = NewUserMapper(&UserMapping{Attributes: []AttributeRule{{Expr: "foo"}}})
+	require.NotNil(t, err)
+	_, err = NewUserMapper(&UserMapping{Attributes: []AttributeRule{{Attribute: "userName", Expr: "lower("}}})
+	require.NotNil(t, err)
+
+	mapper, err := NewUserMapper(&UserMapping{Attributes: []AttributeRule{{Attribute: "userName", Expr: `lookup("foo", id)`}}})
+	require.Nil(t, err)
+	_, err = mapper.Map(map[string]interface{}{"id": "1"})
+	require.NotNil(t, err)
+
+	_, err = ReadCSVRecords(strings.NewReader(""))
+	require.NotNil(t, err)
+}
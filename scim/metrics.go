@@ -0,0 +1,132 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// RetryReasonTokenRefreshed is the reason of a retry after the token is refreshed by a 401 response.
+	RetryReasonTokenRefreshed = "token_refreshed"
+)
+
+type (
+	// RequestMetrics is a record of an HTTP request sent by the client.
+	RequestMetrics struct {
+		// Operation is the name of the client's method such as "GetUsers" and "PatchGroup".
+		Operation string
+		Method    string
+		// StatusCode is the status code of the response. If no response is returned, StatusCode is zero.
+		StatusCode int
+		// ErrorCode is the code of Slack's error response body. If the response isn't an error or has no code, ErrorCode is zero.
+		ErrorCode int
+		// Duration is the time until the response header is received.
+		Duration time.Duration
+		// Err is the error of sending the request such as a network error. Error responses aren't set to Err.
+		// The filter in the request URL of Err is redacted by RedactFilter .
+		Err error
+	}
+
+	// MetricsHook records metrics of requests sent by the client.
+	// The methods are called synchronously, so they should return quickly.
+	// PrometheusMetrics implements MetricsHook.
+	MetricsHook interface {
+		// ObserveRequest is called for each HTTP request including retries.
+		ObserveRequest(ctx context.Context, metrics *RequestMetrics)
+		// ObserveRetry is called when the request is retried.
+		ObserveRetry(ctx context.Context, operation, reason string)
+		// ObserveRateLimit is called when the response is 429 Too Many Requests.
+		// retryAfter is the wait requested by the Retry-After header, and zero if the header isn't set.
+		// The client doesn't wait for retryAfter and returns the response as is.
+		ObserveRateLimit(ctx context.Context, operation string, retryAfter time.Duration)
+	}
+)
+
+// observedDo sends the request and records the metrics.
+func (c *Client) observedDo(
+	ctx context.Context, operation, method, endpoint string, body []byte, token string,
+) (*http.Response, error) {
+	if c.metricsHook == nil {
+		return c.loggedDo(ctx, operation, method, endpoint, body, token)
+	}
+	start := time.Now()
+	resp, err := c.loggedDo(ctx, operation, method, endpoint, body, token)
+	metrics := &RequestMetrics{
+		Operation: operation,
+		Method:    method,
+		Duration:  time.Since(start),
+		// the error message contains the request URL, so the filter is redacted
+		Err: redactURLError(err),
+	}
+	if err == nil {
+		metrics.StatusCode = resp.StatusCode
+		if c.isError(resp) {
+			metrics.ErrorCode = peekErrorCode(resp)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			c.metricsHook.ObserveRateLimit(ctx, operation, retryAfter(resp))
+		}
+	}
+	c.metricsHook.ObserveRequest(ctx, metrics)
+	return resp, err
+}
+
+// peekErrorCode returns the code of Slack's error response body.
+// The response body is replaced so that the body can be read from the beginning.
+func peekErrorCode(resp *http.Response) int {
+	b, err := peekBody(resp)
+	if err != nil || len(b) == 0 {
+		return 0
+	}
+	a := &struct {
+		Errors *Error
+	}{}
+	if err := json.Unmarshal(b, a); err != nil || a.Errors == nil {
+		return 0
+	}
+	return a.Errors.Code
+}
+
+// peekBody reads the response body and replaces the body so that the body can be read from the beginning.
+// If reading the body fails, the replaced body returns the error after the read bytes.
+func peekBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body = &struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(b), errReader{err: err}),
+		Closer: resp.Body,
+	}
+	return b, err
+}
+
+// errReader returns err, or io.EOF if err is nil.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	if r.err == nil {
+		return 0, io.EOF
+	}
+	return 0, r.err
+}
+
+// retryAfter returns the wait of the Retry-After header in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	sec, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || sec < 0 {
+		return 0
+	}
+	return time.Duration(sec) * time.Second
+}
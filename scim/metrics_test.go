@@ -0,0 +1,80 @@
+package scim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testMetricsHook struct {
+	requests   []*RequestMetrics
+	retries    []string
+	rateLimits []time.Duration
+}
+
+func (h *testMetricsHook) ObserveRequest(ctx context.Context, metrics *RequestMetrics) {
+	h.requests = append(h.requests, metrics)
+}
+
+func (h *testMetricsHook) ObserveRetry(ctx context.Context, operation, reason string) {
+	h.retries = append(h.retries, operation+":"+reason)
+}
+
+func (h *testMetricsHook) ObserveRateLimit(ctx context.Context, operation string, retryAfter time.Duration) {
+	h.rateLimits = append(h.rateLimits, retryAfter)
+}
+
+func TestClient_observedDo(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	hook := &testMetricsHook{}
+	client := NewClientWithTokenSource(&testTokenSource{tokens: []string{"old", "new"}}).WithMetricsHook(hook)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U1").
+		MatchHeader("Authorization", "Bearer old").
+		Reply(401).
+		BodyString(`{"Errors": {"description": "invalid_authentication", "code": 401}}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U1").
+		MatchHeader("Authorization", "Bearer new").
+		Reply(200).
+		BodyString(`{"id": "U1"}`)
+	user, _, err := client.GetUser(ctx, "U1")
+	require.Nil(t, err)
+	require.Equal(t, "U1", user.ID)
+	require.Len(t, hook.requests, 2)
+	require.Equal(t, "GetUser", hook.requests[0].Operation)
+	require.Equal(t, "GET", hook.requests[0].Method)
+	require.Equal(t, 401, hook.requests[0].StatusCode)
+	require.Equal(t, 401, hook.requests[0].ErrorCode)
+	require.Equal(t, 200, hook.requests[1].StatusCode)
+	require.Equal(t, 0, hook.requests[1].ErrorCode)
+	require.Equal(t, []string{"GetUser:" + RetryReasonTokenRefreshed}, hook.retries)
+
+	// the error response can be parsed after the error code is peeked
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U2").
+		Reply(404).
+		BodyString(`{"Errors": {"description": "not_found", "code": 404}}`)
+	_, err = client.DeleteUser(ctx, "U2")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "not_found")
+	require.Equal(t, "DeleteUser", hook.requests[2].Operation)
+	require.Equal(t, 404, hook.requests[2].ErrorCode)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(429).
+		SetHeader("Retry-After", "30").
+		BodyString(`{"Errors": {"description": "ratelimited", "code": 429}}`)
+	_, _, err = client.GetGroups(ctx, nil, "")
+	require.NotNil(t, err)
+	require.Equal(t, []time.Duration{30 * time.Second}, hook.rateLimits)
+	require.Equal(t, 429, hook.requests[3].StatusCode)
+	require.True(t, gock.IsDone())
+}
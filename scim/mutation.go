@@ -0,0 +1,202 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ResourceTypeUser is Mutation's ResourceType of users.
+	ResourceTypeUser = "User"
+	// ResourceTypeGroup is Mutation's ResourceType of groups.
+	ResourceTypeGroup = "Group"
+
+	// MutationCreate is Mutation's Operation of POST /Users and POST /Groups API.
+	MutationCreate = "create"
+	// MutationPatch is Mutation's Operation of PATCH /Users/{id} and PATCH /Groups/{id} API.
+	MutationPatch = "patch"
+	// MutationPut is Mutation's Operation of PUT /Users/{id} and PUT /Groups/{id} API.
+	MutationPut = "put"
+	// MutationDelete is Mutation's Operation of DELETE /Users/{id} and DELETE /Groups/{id} API.
+	MutationDelete = "delete"
+)
+
+type (
+	// Mutation is a change of a user or a group made by the client.
+	Mutation struct {
+		// Operation is one of MutationCreate, MutationPatch, MutationPut, and MutationDelete .
+		Operation string
+		// ResourceType is ResourceTypeUser or ResourceTypeGroup .
+		ResourceType string
+		ResourceID   string
+		// Request is the request body, that is, *User, *UserPatch, or *Group .
+		// Request is nil if Operation is MutationDelete .
+		Request interface{}
+		// Result is the resource returned by the API, that is, *User or *Group .
+		// Result is nil if Operation is MutationDelete .
+		Result interface{}
+		// StatusCode is the status code of the response. If no response is returned, StatusCode is zero.
+		StatusCode int
+		// Err is the error returned by the method. If Err isn't nil, the mutation may not be applied.
+		Err error
+		// Refused is true if MutationGuard refused the mutation and the request wasn't sent.
+		// Then Err is the error returned by MutationGuard.
+		Refused bool
+
+		preImage       interface{}
+		preImageLoaded bool
+		getPreImage    func(ctx context.Context) (interface{}, error)
+		span           *methodSpan
+	}
+
+	// MutationHook is called after the client creates, updates, or deletes a user or a group.
+	// MutationHook is called even if the request fails or MutationGuard refuses the mutation, and then Mutation.Err isn't nil.
+	// MutationHook is also called by methods which return the raw response such as Client.CreateUserResp,
+	// and then Mutation.Result is nil because the response body isn't parsed,
+	// and Mutation.ResourceID of MutationCreate is empty.
+	MutationHook func(ctx context.Context, mutation *Mutation)
+
+	// MutationGuard is called before the client creates, updates, or deletes a user or a group.
+	// If MutationGuard returns an error, the request isn't sent and the error is returned.
+	// Unlike MutationHook, MutationGuard is also called by methods which return the raw response such as Client.DeleteUserResp,
+	// so the guard can't be bypassed.
+	MutationGuard func(ctx context.Context, mutation *Mutation) error
+)
+
+// MutationHooks returns a MutationHook which calls hooks in order.
+// nil hooks are ignored.
+func MutationHooks(hooks ...MutationHook) MutationHook {
+	return func(ctx context.Context, mutation *Mutation) {
+		for _, hook := range hooks {
+			if hook != nil {
+				hook(ctx, mutation)
+			}
+		}
+	}
+}
+
+// MutationGuards returns a MutationGuard which calls guards in order and returns the first error.
+// nil guards are ignored.
+func MutationGuards(guards ...MutationGuard) MutationGuard {
+	return func(ctx context.Context, mutation *Mutation) error {
+		for _, guard := range guards {
+			if guard == nil {
+				continue
+			}
+			if err := guard(ctx, mutation); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// PreImage returns the user or the group before the mutation, that is, *User or *Group .
+// At the first call in MutationGuard, the resource is got by GET /Users/{id} or GET /Groups/{id} API,
+// and the result is shared by guards and hooks.
+// After the request is sent, PreImage doesn't call the API and returns nil if the resource hasn't been got.
+// If Operation is MutationCreate, PreImage returns nil.
+func (mutation *Mutation) PreImage(ctx context.Context) (interface{}, error) {
+	if mutation.preImageLoaded || mutation.getPreImage == nil {
+		return mutation.preImage, nil
+	}
+	preImage, err := mutation.getPreImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mutation.preImage = preImage
+	mutation.preImageLoaded = true
+	return preImage, nil
+}
+
+// newMutation returns a mutation and starts the span of the method such as "CreateUser".
+// Requests of the guards are nested in the span, and the span is ended by Client.afterMutation .
+func (c *Client) newMutation(
+	ctx context.Context, operation, resourceType, id string, request interface{},
+) (context.Context, *Mutation) {
+	mutation := &Mutation{
+		Operation:    operation,
+		ResourceType: resourceType,
+		ResourceID:   id,
+		Request:      request,
+	}
+	ctx, mutation.span = c.startMethodSpan(ctx, strings.ToUpper(operation[:1])+operation[1:]+resourceType)
+	if operation == MutationCreate {
+		return ctx, mutation
+	}
+	mutation.getPreImage = func(ctx context.Context) (interface{}, error) {
+		if id == "" {
+			return nil, fmt.Errorf("id is required")
+		}
+		if resourceType == ResourceTypeGroup {
+			group, _, err := c.GetGroup(ctx, id)
+			return group, err
+		}
+		user, _, err := c.GetUser(ctx, id)
+		return user, err
+	}
+	return ctx, mutation
+}
+
+// beforeMutation calls the mutation guard.
+// If the guard refuses the mutation, the mutation hook is called with the error.
+func (c *Client) beforeMutation(ctx context.Context, mutation *Mutation) error {
+	if c.mutationGuard == nil {
+		return nil
+	}
+	if err := c.mutationGuard(ctx, mutation); err != nil {
+		mutation.Refused = true
+		c.afterMutation(ctx, mutation, nil, err)
+		return err
+	}
+	return nil
+}
+
+// guardedResp calls the mutation guard, sends the request by send, and calls the mutation hook.
+// guardedResp is used by methods which return the raw response such as Client.DeleteUserResp .
+// The response body isn't consumed, so the caller can read the body.
+func (c *Client) guardedResp(
+	ctx context.Context, mutation *Mutation, send func(ctx context.Context) (*http.Response, error),
+) (*http.Response, error) {
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx)
+	if err != nil || c.mutationHook == nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return resp, err
+	}
+	c.afterMutation(ctx, mutation, resp, c.peekError(resp))
+	return resp, nil
+}
+
+// peekError returns the error of the error response without consuming the response body.
+func (c *Client) peekError(resp *http.Response) error {
+	if !c.isError(resp) {
+		return nil
+	}
+	b, err := peekBody(resp)
+	if err != nil {
+		return err
+	}
+	r := *resp
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return c.parseErrorResp(&r)
+}
+
+func (c *Client) afterMutation(ctx context.Context, mutation *Mutation, resp *http.Response, err error) {
+	mutation.getPreImage = nil
+	if resp != nil {
+		mutation.StatusCode = resp.StatusCode
+	}
+	mutation.Err = err
+	if c.mutationHook != nil {
+		c.mutationHook(ctx, mutation)
+	}
+	mutation.span.end(err)
+	mutation.span = nil
+}
@@ -0,0 +1,153 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutationHooks(t *testing.T) {
+	calls := []string{}
+	hook := MutationHooks(
+		func(ctx context.Context, mutation *Mutation) {
+			calls = append(calls, "foo")
+		},
+		nil,
+		func(ctx context.Context, mutation *Mutation) {
+			calls = append(calls, "bar")
+		},
+	)
+	hook(context.Background(), &Mutation{})
+	require.Equal(t, []string{"foo", "bar"}, calls)
+}
+
+func TestClient_mutated(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	mutations := []*Mutation{}
+	client := NewClient("XXX").WithMutationHook(func(ctx context.Context, mutation *Mutation) {
+		mutations = append(mutations, mutation)
+	})
+
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(201).
+		BodyString(`{"id": "U1", "userName": "foo"}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": []}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}]}`)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U2").
+		Reply(404).
+		BodyString(`{"Errors": {"description": "not found", "code": 404}}`)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Groups/G1").
+		Reply(204)
+
+	user := &User{UserName: "foo"}
+	_, _, err := client.CreateUser(ctx, user)
+	require.Nil(t, err)
+	_, _, err = client.AddGroupMembers(ctx, "G1", "U1")
+	require.Nil(t, err)
+	_, err = client.DeleteUser(ctx, "U2")
+	require.NotNil(t, err)
+	_, err = client.DeleteGroup(ctx, "G1")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+
+	require.Len(t, mutations, 4)
+	require.Equal(t, MutationCreate, mutations[0].Operation)
+	require.Equal(t, ResourceTypeUser, mutations[0].ResourceType)
+	require.Equal(t, "U1", mutations[0].ResourceID)
+	require.Equal(t, user, mutations[0].Request)
+	require.Equal(t, "U1", mutations[0].Result.(*User).ID)
+	require.Equal(t, MutationPatch, mutations[1].Operation)
+	require.Equal(t, ResourceTypeGroup, mutations[1].ResourceType)
+	require.Equal(t, "G1", mutations[1].ResourceID)
+	require.Equal(t, []Member{{Value: "U1"}}, mutations[1].Result.(*Group).Members)
+	require.Equal(t, 404, mutations[2].StatusCode)
+	require.NotNil(t, mutations[2].Err)
+	require.Equal(t, &Mutation{
+		Operation:    MutationDelete,
+		ResourceType: ResourceTypeGroup,
+		ResourceID:   "G1",
+		StatusCode:   204,
+	}, mutations[3])
+}
+
+func TestMutationGuards(t *testing.T) {
+	calls := []string{}
+	guard := MutationGuards(
+		func(ctx context.Context, mutation *Mutation) error {
+			calls = append(calls, "foo")
+			return nil
+		},
+		nil,
+		func(ctx context.Context, mutation *Mutation) error {
+			calls = append(calls, "bar")
+			return errors.New("bar")
+		},
+		func(ctx context.Context, mutation *Mutation) error {
+			calls = append(calls, "baz")
+			return nil
+		},
+	)
+	require.NotNil(t, guard(context.Background(), &Mutation{}))
+	require.Equal(t, []string{"foo", "bar"}, calls)
+}
+
+func TestMutation_PreImage(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	var preImage interface{}
+	client.SetMutationGuard(func(ctx context.Context, mutation *Mutation) error {
+		// the pre-image is got only once
+		for i := 0; i < 2; i++ {
+			p, err := mutation.PreImage(ctx)
+			if err != nil {
+				return err
+			}
+			preImage = p
+		}
+		return nil
+	})
+	client.SetMutationHook(func(ctx context.Context, mutation *Mutation) {
+		p, err := mutation.PreImage(ctx)
+		require.Nil(t, err)
+		require.Equal(t, preImage, p)
+	})
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U1").
+		Reply(200).
+		BodyString(`{"id": "U1", "active": true}`)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U1").
+		Reply(204)
+	_, err := client.DeleteUser(ctx, "U1")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Equal(t, &User{ID: "U1", Active: true}, preImage)
+
+	// the guard's error is returned and the request isn't sent
+	// the hook is called with the refused mutation, which has no pre-image
+	preImage = nil
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U2").
+		Reply(404).
+		BodyString(`{"Errors": {"description": "not found", "code": 404}}`)
+	_, err = client.DeleteUser(ctx, "U2")
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+}
@@ -0,0 +1,170 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+)
+
+type (
+	// OffboardOption is an option of Client.Offboard .
+	OffboardOption struct {
+		// Scrub is applied to the user by PATCH /Users/{id} API before the user is deactivated.
+		// For example, to remove the user's phone numbers, set &UserPatch{PhoneNumbers: &[]PhoneNumber{}} .
+		// If Scrub is nil, the user isn't patched.
+		Scrub *UserPatch
+	}
+
+	// OffboardReport is a result of Client.Offboard .
+	// The report can be passed to Client.Reactivate to restore the user.
+	OffboardReport struct {
+		UserID string `json:"userId"`
+		// PreImage is the user got before offboarding.
+		PreImage *User `json:"preImage"`
+		// RemovedGroups is groups which the user has been removed from. Only ID and DisplayName are set.
+		RemovedGroups []Group      `json:"removedGroups"`
+		GroupErrors   []GroupError `json:"-"`
+		Scrubbed      bool         `json:"scrubbed"`
+		Deactivated   bool         `json:"deactivated"`
+	}
+
+	// ReactivateReport is a result of Client.Reactivate .
+	ReactivateReport struct {
+		User           *User
+		RestoredGroups []Group
+		GroupErrors    []GroupError
+	}
+
+	// GroupError is an error which occurred in updating a group.
+	GroupError struct {
+		GroupID string
+		Err     error
+	}
+)
+
+// Error returns the error message with the group id.
+func (e *GroupError) Error() string {
+	return fmt.Sprintf("group %s: %v", e.GroupID, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *GroupError) Unwrap() error {
+	return e.Err
+}
+
+func groupErrorsToError(errs []GroupError) error {
+	return joinErrors("failed to update groups", len(errs), func(i int) error {
+		return &errs[i]
+	})
+}
+
+// Offboard deactivates a user.
+// Offboard gets the user, removes the user from all groups the user belongs to, which are got by GET /Groups API,
+// patches the user with opts.Scrub, and deactivates the user by DELETE /Users/{id} API.
+// Note that DELETE /Users/{id} API doesn't delete the user but deactivates the user.
+// A group which can't be updated doesn't stop offboarding; it is recorded in the report's GroupErrors,
+// and the user is still scrubbed and deactivated.
+// The report records the steps which have been done, so it is returned even with an error
+// and can be passed to Client.Reactivate .
+func (c *Client) Offboard(ctx context.Context, userID string, opts *OffboardOption) (_ *OffboardReport, err error) {
+	ctx, span := c.startMethodSpan(ctx, "Offboard")
+	defer func() {
+		span.end(err)
+	}()
+	report := &OffboardReport{
+		UserID: userID,
+	}
+	if userID == "" {
+		return report, fmt.Errorf("userID is required")
+	}
+	if opts == nil {
+		opts = &OffboardOption{}
+	}
+	user, _, err := c.GetUser(ctx, userID)
+	if err != nil {
+		return report, fmt.Errorf("failed to get the user %s: %w", userID, err)
+	}
+	report.PreImage = user
+
+	// the user's groups got by GET /Users/{id} API may be stale, so groups are got by GET /Groups API.
+	groups, err := c.GetAllGroups(ctx, "")
+	if err != nil {
+		return report, fmt.Errorf("failed to get groups: %w", err)
+	}
+	for _, group := range groups {
+		if !hasMember(&group, userID) {
+			continue
+		}
+		if _, _, err := c.RemoveGroupMembers(ctx, group.ID, userID); err != nil && !isPatchedGroupError(err) {
+			report.GroupErrors = append(report.GroupErrors, GroupError{
+				GroupID: group.ID,
+				Err:     err,
+			})
+			continue
+		}
+		report.RemovedGroups = append(report.RemovedGroups, Group{
+			ID:          group.ID,
+			DisplayName: group.DisplayName,
+		})
+	}
+
+	if opts.Scrub != nil {
+		if _, _, err := c.PatchUser(ctx, userID, opts.Scrub); err != nil {
+			return report, fmt.Errorf("failed to scrub the user %s: %w", userID, err)
+		}
+		report.Scrubbed = true
+	}
+
+	if _, err := c.DeleteUser(ctx, userID); err != nil {
+		return report, fmt.Errorf("failed to deactivate the user %s: %w", userID, err)
+	}
+	report.Deactivated = true
+	return report, groupErrorsToError(report.GroupErrors)
+}
+
+func hasMember(group *Group, userID string) bool {
+	for _, member := range group.Members {
+		if member.Value == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Reactivate activates a user offboarded by Client.Offboard and restores the user's group memberships.
+// Fields scrubbed by Client.Offboard aren't restored.
+// Groups are restored only after the user is activated, and groups which can't be restored are recorded in GroupErrors.
+// The returned ReactivateReport isn't nil even if an error is returned.
+func (c *Client) Reactivate(ctx context.Context, report *OffboardReport) (_ *ReactivateReport, err error) {
+	ctx, span := c.startMethodSpan(ctx, "Reactivate")
+	defer func() {
+		span.end(err)
+	}()
+	ret := &ReactivateReport{}
+	if report == nil {
+		return ret, fmt.Errorf("report is required")
+	}
+	if report.UserID == "" {
+		return ret, fmt.Errorf("report.UserID is required")
+	}
+	active := true
+	user, _, err := c.PatchUser(ctx, report.UserID, &UserPatch{
+		Schemas: []string{SchemaCore},
+		Active:  &active,
+	})
+	if err != nil {
+		return ret, fmt.Errorf("failed to activate the user %s: %w", report.UserID, err)
+	}
+	ret.User = user
+
+	for _, group := range report.RemovedGroups {
+		if _, _, err := c.AddGroupMembers(ctx, group.ID, report.UserID); err != nil && !isPatchedGroupError(err) {
+			ret.GroupErrors = append(ret.GroupErrors, GroupError{
+				GroupID: group.ID,
+				Err:     err,
+			})
+			continue
+		}
+		ret.RestoredGroups = append(ret.RestoredGroups, group)
+	}
+	return ret, groupErrorsToError(ret.GroupErrors)
+}
@@ -0,0 +1,146 @@
+package scim
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testOffboardUserJSON = `{
+  "id": "U1",
+  "userName": "foo",
+  "active": true,
+  "phoneNumbers": [{"value": "555-555-5555"}],
+  "groups": [
+    {"value": "G1", "display": "group 1"},
+    {"value": "G2", "display": "group 2"},
+    {"value": "G9", "display": "stale group"}
+  ]
+}`
+	testOffboardGroupsJSON = `{
+  "totalResults": 3,
+  "Resources": [
+    {"id": "G1", "displayName": "group 1", "members": [{"value": "U1"}, {"value": "U2"}]},
+    {"id": "G2", "displayName": "group 2", "members": [{"value": "U1"}]},
+    {"id": "G3", "displayName": "group 3", "members": [{"value": "U2"}]}
+  ]
+}`
+)
+
+func TestClient_Offboard(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+
+	_, err := client.Offboard(ctx, "", nil)
+	require.NotNil(t, err)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U1").
+		Reply(200).
+		BodyString(testOffboardUserJSON)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(200).
+		BodyString(testOffboardGroupsJSON)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}, {"value": "U2"}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": []}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G2").
+		Reply(200).
+		BodyString(`{"id": "G2", "members": [{"value": "U1"}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G2").
+		Reply(404).
+		BodyString(`{"Errors": {"description": "group not found", "code": 404}}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U1").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas":      nil,
+			"phoneNumbers": []interface{}{},
+		}).
+		Reply(200).
+		BodyString(`{"id": "U1"}`)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U1").
+		Reply(204)
+
+	report, err := client.Offboard(ctx, "U1", &OffboardOption{
+		Scrub: &UserPatch{PhoneNumbers: &[]PhoneNumber{}},
+	})
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+	require.Equal(t, "U1", report.PreImage.ID)
+	require.Equal(t, []Group{{ID: "G1", DisplayName: "group 1"}}, report.RemovedGroups)
+	require.Len(t, report.GroupErrors, 1)
+	require.Equal(t, "G2", report.GroupErrors[0].GroupID)
+	require.True(t, report.Scrubbed)
+	require.True(t, report.Deactivated)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U2").
+		Reply(404).
+		BodyString(`{"Errors": {"description": "user not found", "code": 404}}`)
+	report, err = client.Offboard(ctx, "U2", nil)
+	require.NotNil(t, err)
+	require.Nil(t, report.PreImage)
+	require.False(t, report.Deactivated)
+}
+
+func TestClient_Reactivate(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+
+	_, err := client.Reactivate(ctx, nil)
+	require.NotNil(t, err)
+	_, err = client.Reactivate(ctx, &OffboardReport{})
+	require.NotNil(t, err)
+
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U1").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore},
+			"active":  true,
+		}).
+		Reply(200).
+		BodyString(`{"id": "U1", "active": true}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": []}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore},
+			"members": []map[string]string{
+				{"value": "U1", "display": ""},
+			},
+		}).
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}]}`)
+
+	ret, err := client.Reactivate(ctx, &OffboardReport{
+		UserID:        "U1",
+		RemovedGroups: []Group{{ID: "G1", DisplayName: "group 1"}},
+	})
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.True(t, ret.User.Active)
+	require.Equal(t, []Group{{ID: "G1", DisplayName: "group 1"}}, ret.RestoredGroups)
+}
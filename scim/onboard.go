@@ -0,0 +1,135 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type (
+	// OnboardOption is an option of Client.Onboard .
+	OnboardOption struct {
+		Rules []OnboardRule
+	}
+
+	// OnboardRule is a rule to add a new user to groups.
+	// If all non empty conditions match the user's attributes, the user is added to the groups GroupIDs.
+	// Conditions are compared case-insensitively.
+	// Department and Division are compared with the user's EnterpriseUserSchemaExtension.
+	OnboardRule struct {
+		Department string   `json:"department,omitempty"`
+		Division   string   `json:"division,omitempty"`
+		Title      string   `json:"title,omitempty"`
+		UserType   string   `json:"userType,omitempty"`
+		GroupIDs   []string `json:"groupIds"`
+	}
+
+	// OnboardResult is a result of Client.Onboard .
+	OnboardResult struct {
+		User *User
+		// Created is true if the user is created.
+		Created bool
+		// Linked is true if the userName is already taken and the existing user is used instead of creating a new user.
+		Linked bool
+		// AddedGroups is ids of groups the user is added to.
+		// Even if a group is included in multiple rules, the group is added once.
+		AddedGroups []string
+		// SkippedGroups is ids of groups the user already belongs to before Client.Onboard is called.
+		SkippedGroups []string
+		GroupErrors   []GroupError
+	}
+)
+
+// Match returns true if user matches all conditions of the rule.
+func (rule *OnboardRule) Match(user *User) bool {
+	ext := user.EnterpriseUserSchemaExtension
+	if ext == nil {
+		ext = &EnterpriseUserSchemaExtension{}
+	}
+	return matchCondition(rule.Department, ext.Department) &&
+		matchCondition(rule.Division, ext.Division) &&
+		matchCondition(rule.Title, user.Title) &&
+		matchCondition(rule.UserType, user.UserType)
+}
+
+func matchCondition(cond, value string) bool {
+	return cond == "" || strings.EqualFold(cond, value)
+}
+
+// Onboard creates a user and adds the user to groups according to opts.Rules .
+// If the userName is already taken, Onboard links to the existing user instead of failing,
+// and groups the existing user already belongs to are got by GET /Groups API and skipped.
+// The rules are evaluated against the given user's attributes.
+// Once the user exists, a failure to add the user to a group is recorded in the result's GroupErrors
+// and the user is still added to the remaining groups.
+// The result is returned with an error too, so that the created or linked user can be checked.
+func (c *Client) Onboard(ctx context.Context, user *User, opts *OnboardOption) (_ *OnboardResult, err error) {
+	ctx, span := c.startMethodSpan(ctx, "Onboard")
+	defer func() {
+		span.end(err)
+	}()
+	result := &OnboardResult{}
+	if user == nil {
+		return result, fmt.Errorf("user is required")
+	}
+	if opts == nil {
+		opts = &OnboardOption{}
+	}
+	created, resp, err := c.CreateUser(ctx, user)
+	switch {
+	case err == nil:
+		result.User = created
+		result.Created = true
+	case resp != nil && resp.StatusCode == http.StatusConflict:
+		existing, err := c.getUserByUserName(ctx, user.UserName)
+		if err != nil {
+			return result, fmt.Errorf("userName %s is already taken but failed to get the user: %w", user.UserName, err)
+		}
+		result.User = existing
+		result.Linked = true
+	default:
+		return result, fmt.Errorf("failed to create the user %s: %w", user.UserName, err)
+	}
+
+	joined := map[string]struct{}{}
+	if result.Linked {
+		// the user's groups got by GET /Users API may be stale, so groups are got by GET /Groups API.
+		groups, err := c.GetAllGroups(ctx, "")
+		if err != nil {
+			return result, fmt.Errorf("failed to get groups: %w", err)
+		}
+		for _, group := range groups {
+			if hasMember(&group, result.User.ID) {
+				joined[group.ID] = struct{}{}
+			}
+		}
+	}
+	// groups which have been processed in this call, so that a group of multiple rules is processed once
+	processed := map[string]struct{}{}
+	for _, rule := range opts.Rules {
+		if !rule.Match(user) {
+			continue
+		}
+		for _, groupID := range rule.GroupIDs {
+			if _, ok := processed[groupID]; ok {
+				continue
+			}
+			processed[groupID] = struct{}{}
+			if _, ok := joined[groupID]; ok {
+				result.SkippedGroups = append(result.SkippedGroups, groupID)
+				continue
+			}
+			if _, _, err := c.AddGroupMembers(ctx, groupID, result.User.ID); err != nil && !isPatchedGroupError(err) {
+				result.GroupErrors = append(result.GroupErrors, GroupError{
+					GroupID: groupID,
+					Err:     err,
+				})
+				continue
+			}
+			joined[groupID] = struct{}{}
+			result.AddedGroups = append(result.AddedGroups, groupID)
+		}
+	}
+	return result, groupErrorsToError(result.GroupErrors)
+}
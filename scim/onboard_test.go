@@ -0,0 +1,158 @@
+package scim
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnboardRule_Match(t *testing.T) {
+	user := &User{
+		Title:    "Engineer",
+		UserType: "Employee",
+		EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+			Department: "Engineering",
+			Division:   "Platform",
+		},
+	}
+	data := []struct {
+		rule  OnboardRule
+		user  *User
+		exp   bool
+		title string
+	}{
+		{
+			title: "empty rule matches any user",
+			user:  &User{},
+			exp:   true,
+		},
+		{
+			title: "all conditions match",
+			rule:  OnboardRule{Department: "engineering", Division: "Platform", Title: "Engineer", UserType: "Employee"},
+			user:  user,
+			exp:   true,
+		},
+		{
+			title: "department doesn't match",
+			rule:  OnboardRule{Department: "Sales", Title: "Engineer"},
+			user:  user,
+		},
+		{
+			title: "user doesn't have the extension",
+			rule:  OnboardRule{Division: "Platform"},
+			user:  &User{},
+		},
+	}
+	for _, d := range data {
+		require.Equal(t, d.exp, d.rule.Match(d.user), d.title)
+	}
+}
+
+func TestClient_Onboard(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	opts := &OnboardOption{
+		Rules: []OnboardRule{
+			{Department: "Engineering", GroupIDs: []string{"G1", "G2"}},
+			{Department: "Sales", GroupIDs: []string{"G3"}},
+			{UserType: "Employee", GroupIDs: []string{"G1", "G4"}},
+			{GroupIDs: []string{"G4"}},
+		},
+	}
+	user := &User{
+		UserName: "foo",
+		UserType: "Employee",
+		EnterpriseUserSchemaExtension: &EnterpriseUserSchemaExtension{
+			Department: "Engineering",
+		},
+	}
+
+	_, err := client.Onboard(ctx, nil, nil)
+	require.NotNil(t, err)
+
+	// create a new user
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(201).
+		BodyString(`{"id": "U1", "userName": "foo"}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": []}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G2").
+		Reply(200).
+		BodyString(`{"id": "G2", "members": []}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G2").
+		Reply(200).
+		BodyString(`{"id": "G2", "members": [{"value": "U1"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G4").
+		Reply(200).
+		BodyString(`{"id": "G4", "members": []}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G4").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+	result, err := client.Onboard(ctx, user, opts)
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+	require.True(t, result.Created)
+	require.False(t, result.Linked)
+	require.Equal(t, "U1", result.User.ID)
+	require.Equal(t, []string{"G1", "G2"}, result.AddedGroups)
+	// groups repeated by rules aren't skipped groups, and the failed group isn't retried
+	require.Empty(t, result.SkippedGroups)
+	require.Len(t, result.GroupErrors, 1)
+	require.Equal(t, "G4", result.GroupErrors[0].GroupID)
+
+	// link to the existing user
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(409).
+		BodyString(`{"Errors": {"description": "username_taken", "code": 409}}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `userName eq "foo"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1", "userName": "foo", "groups": [{"value": "G1"}, {"value": "G3"}]}]}`)
+	// the user's groups got by GET /Users API are stale
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(200).
+		BodyString(`{"totalResults": 2, "Resources": [{"id": "G1", "members": [{"value": "U1"}]}, {"id": "G2", "members": [{"value": "U1"}]}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G4").
+		Reply(200).
+		BodyString(`{"id": "G4", "members": []}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G4").
+		Reply(200).
+		BodyString(`{"id": "G4", "members": [{"value": "U1"}]}`)
+	result, err = client.Onboard(ctx, user, opts)
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.False(t, result.Created)
+	require.True(t, result.Linked)
+	require.Equal(t, []string{"G4"}, result.AddedGroups)
+	require.Equal(t, []string{"G1", "G2"}, result.SkippedGroups)
+
+	// other errors
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(400).
+		BodyString(`{"Errors": {"description": "invalid_email", "code": 400}}`)
+	result, err = client.Onboard(ctx, user, opts)
+	require.NotNil(t, err)
+	require.Nil(t, result.User)
+}
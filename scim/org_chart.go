@@ -0,0 +1,278 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type (
+	// OrgChart is a reporting tree built from users' EnterpriseUserSchemaExtension.Manager .
+	// OrgChart should be created by the function NewOrgChart .
+	OrgChart struct {
+		ids     []string
+		users   map[string]*User
+		reports map[string][]string
+	}
+
+	// OrphanedManager is a manager who is referred by users but doesn't exist or is deactivated.
+	OrphanedManager struct {
+		ManagerID string `json:"managerId"`
+		// Missing is true if the manager doesn't exist, and false if the manager is deactivated.
+		Missing bool `json:"missing"`
+		// Reports is ids of the manager's direct reports.
+		Reports []string `json:"reports"`
+	}
+
+	orgChartNode struct {
+		ID          string   `json:"id"`
+		DisplayName string   `json:"displayName,omitempty"`
+		Title       string   `json:"title,omitempty"`
+		ManagerID   string   `json:"managerId,omitempty"`
+		Reports     []string `json:"reports"`
+	}
+)
+
+var mermaidIDPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// NewOrgChart builds an org chart from users.
+// To exclude deactivated users, filter users before calling NewOrgChart .
+func NewOrgChart(users []User) *OrgChart {
+	chart := &OrgChart{
+		ids:     make([]string, 0, len(users)),
+		users:   make(map[string]*User, len(users)),
+		reports: map[string][]string{},
+	}
+	for i := range users {
+		user := &users[i]
+		if _, ok := chart.users[user.ID]; !ok {
+			chart.ids = append(chart.ids, user.ID)
+		}
+		chart.users[user.ID] = user
+	}
+	sort.Strings(chart.ids)
+	for _, id := range chart.ids {
+		if mgr := managerID(chart.users[id]); mgr != "" {
+			chart.reports[mgr] = append(chart.reports[mgr], id)
+		}
+	}
+	return chart
+}
+
+// User returns the user whose id is id.
+func (chart *OrgChart) User(id string) (*User, bool) {
+	user, ok := chart.users[id]
+	return user, ok
+}
+
+// ManagementChain returns ids of the user's manager, the manager's manager, and so on.
+// If the chain has a cycle, the chain is cut before the cycle.
+func (chart *OrgChart) ManagementChain(id string) []string {
+	user, ok := chart.users[id]
+	if !ok {
+		return []string{}
+	}
+	return managerChain(user, chart.users)
+}
+
+// DirectReports returns ids of the user's direct reports.
+// The returned ids are sorted.
+func (chart *OrgChart) DirectReports(id string) []string {
+	reports := chart.reports[id]
+	ret := make([]string, len(reports))
+	copy(ret, reports)
+	return ret
+}
+
+// AllReports returns ids of the user's direct and transitive reports.
+// The returned ids are sorted.
+func (chart *OrgChart) AllReports(id string) []string {
+	visited := map[string]struct{}{id: {}}
+	queue := []string{id}
+	ret := []string{}
+	for len(queue) != 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, report := range chart.reports[cur] {
+			if _, ok := visited[report]; ok {
+				continue
+			}
+			visited[report] = struct{}{}
+			ret = append(ret, report)
+			queue = append(queue, report)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// SpanOfControl returns the number of the user's direct reports.
+func (chart *OrgChart) SpanOfControl(id string) int {
+	return len(chart.reports[id])
+}
+
+// Roots returns ids of users who have no manager or whose manager doesn't exist.
+// The returned ids are sorted.
+func (chart *OrgChart) Roots() []string {
+	roots := []string{}
+	for _, id := range chart.ids {
+		if _, ok := chart.users[managerID(chart.users[id])]; !ok {
+			roots = append(roots, id)
+		}
+	}
+	return roots
+}
+
+// Cycles returns reporting cycles such as A reports to B and B reports to A.
+// Each cycle starts with the smallest id and follows managers, and cycles are sorted.
+func (chart *OrgChart) Cycles() [][]string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	states := make(map[string]int, len(chart.ids))
+	cycles := [][]string{}
+	for _, id := range chart.ids {
+		path := []string{}
+		cur := id
+		for {
+			if _, ok := chart.users[cur]; !ok || states[cur] == done {
+				break
+			}
+			if states[cur] == visiting {
+				for i, p := range path {
+					if p == cur {
+						cycles = append(cycles, rotateCycle(path[i:]))
+						break
+					}
+				}
+				break
+			}
+			states[cur] = visiting
+			path = append(path, cur)
+			cur = managerID(chart.users[cur])
+		}
+		for _, p := range path {
+			states[p] = done
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+func rotateCycle(cycle []string) []string {
+	start := 0
+	for i, id := range cycle {
+		if id < cycle[start] {
+			start = i
+		}
+	}
+	ret := make([]string, 0, len(cycle))
+	ret = append(ret, cycle[start:]...)
+	return append(ret, cycle[:start]...)
+}
+
+// OrphanedManagers returns managers who are referred by users but don't exist or are deactivated.
+// The returned managers are sorted by id.
+func (chart *OrgChart) OrphanedManagers() []OrphanedManager {
+	ret := []OrphanedManager{}
+	for mgr := range chart.reports {
+		user, ok := chart.users[mgr]
+		if ok && user.Active {
+			continue
+		}
+		ret = append(ret, OrphanedManager{
+			ManagerID: mgr,
+			Missing:   !ok,
+			Reports:   chart.DirectReports(mgr),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ManagerID < ret[j].ManagerID
+	})
+	return ret
+}
+
+// WriteDOT writes the org chart in Graphviz DOT.
+// Edges are directed from managers to their reports, and managers who don't exist are omitted.
+func (chart *OrgChart) WriteDOT(w io.Writer) error {
+	b := &strings.Builder{}
+	b.WriteString("digraph org {\n")
+	for _, id := range chart.ids {
+		fmt.Fprintf(b, "  %s [label=%s];\n", dotQuote(id), dotQuote(orgChartLabel(chart.users[id], `\n`)))
+	}
+	for _, id := range chart.ids {
+		for _, report := range chart.reports[id] {
+			fmt.Fprintf(b, "  %s -> %s;\n", dotQuote(id), dotQuote(report))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the org chart as a Mermaid flowchart.
+// Edges are directed from managers to their reports, and managers who don't exist are omitted.
+func (chart *OrgChart) WriteMermaid(w io.Writer) error {
+	b := &strings.Builder{}
+	b.WriteString("graph TD\n")
+	for _, id := range chart.ids {
+		label := strings.ReplaceAll(orgChartLabel(chart.users[id], "<br/>"), `"`, "#quot;")
+		fmt.Fprintf(b, "  %s[\"%s\"]\n", mermaidID(id), label)
+	}
+	for _, id := range chart.ids {
+		for _, report := range chart.reports[id] {
+			fmt.Fprintf(b, "  %s --> %s\n", mermaidID(id), mermaidID(report))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes users of the org chart with their managers and direct reports in JSON.
+func (chart *OrgChart) WriteJSON(w io.Writer) error {
+	nodes := make([]orgChartNode, len(chart.ids))
+	for i, id := range chart.ids {
+		user := chart.users[id]
+		nodes[i] = orgChartNode{
+			ID:          id,
+			DisplayName: user.DisplayName,
+			Title:       user.Title,
+			ManagerID:   managerID(user),
+			Reports:     chart.DirectReports(id),
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"users": nodes,
+	})
+}
+
+func orgChartLabel(user *User, sep string) string {
+	label := user.DisplayName
+	if label == "" {
+		label = user.UserName
+	}
+	if label == "" {
+		label = user.ID
+	}
+	if user.Title != "" {
+		label += sep + user.Title
+	}
+	return label
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+func mermaidID(id string) string {
+	return "u_" + mermaidIDPattern.ReplaceAllString(id, "_")
+}
@@ -0,0 +1,82 @@
+package scim
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testOrgChartUser(id, mgr string, active bool) User {
+	user := User{ID: id, DisplayName: id, Active: active}
+	if mgr != "" {
+		user.EnterpriseUserSchemaExtension = &EnterpriseUserSchemaExtension{
+			Manager: &Manager{ManagerID: mgr},
+		}
+	}
+	return user
+}
+
+func testOrgChart() *OrgChart {
+	return NewOrgChart([]User{
+		testOrgChartUser("U1", "", true),
+		testOrgChartUser("U2", "U1", true),
+		testOrgChartUser("U3", "U1", true),
+		testOrgChartUser("U4", "U2", true),
+		testOrgChartUser("U5", "U9", true),
+		testOrgChartUser("U6", "U7", true),
+		testOrgChartUser("U7", "U6", false),
+		testOrgChartUser("U8", "U7", true),
+	})
+}
+
+func TestOrgChart(t *testing.T) {
+	chart := testOrgChart()
+	require.Equal(t, []string{"U2", "U1"}, chart.ManagementChain("U4"))
+	require.Equal(t, []string{"U7"}, chart.ManagementChain("U6"))
+	require.Equal(t, []string{}, chart.ManagementChain("U0"))
+	require.Equal(t, []string{"U2", "U3"}, chart.DirectReports("U1"))
+	require.Equal(t, []string{"U2", "U3", "U4"}, chart.AllReports("U1"))
+	require.Equal(t, []string{"U6", "U8"}, chart.AllReports("U7"))
+	require.Equal(t, 2, chart.SpanOfControl("U1"))
+	require.Equal(t, 0, chart.SpanOfControl("U4"))
+	require.Equal(t, []string{"U1", "U5"}, chart.Roots())
+	require.Equal(t, [][]string{{"U6", "U7"}}, chart.Cycles())
+	require.Equal(t, []OrphanedManager{
+		{ManagerID: "U7", Missing: false, Reports: []string{"U6", "U8"}},
+		{ManagerID: "U9", Missing: true, Reports: []string{"U5"}},
+	}, chart.OrphanedManagers())
+}
+
+func TestOrgChart_Write(t *testing.T) {
+	users := []User{
+		testOrgChartUser("U1", "", true),
+		testOrgChartUser("U2", "U1", true),
+	}
+	users[0].Title = `CEO "Boss"`
+	chart := NewOrgChart(users)
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, chart.WriteDOT(buf))
+	require.Equal(t, `digraph org {
+  "U1" [label="U1\nCEO \"Boss\""];
+  "U2" [label="U2"];
+  "U1" -> "U2";
+}
+`, buf.String())
+
+	buf.Reset()
+	require.Nil(t, chart.WriteMermaid(buf))
+	require.Equal(t, `graph TD
+  u_U1["U1<br/>CEO #quot;Boss#quot;"]
+  u_U2["U2"]
+  u_U1 --> u_U2
+`, buf.String())
+
+	buf.Reset()
+	require.Nil(t, chart.WriteJSON(buf))
+	require.JSONEq(t, `{"users": [
+  {"id": "U1", "displayName": "U1", "title": "CEO \"Boss\"", "reports": ["U2"]},
+  {"id": "U2", "displayName": "U2", "managerId": "U1", "reports": []}
+]}`, buf.String())
+}
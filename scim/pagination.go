@@ -1,6 +1,7 @@
 package scim
 
 import (
+	"context"
 	"net/url"
 	"strconv"
 )
@@ -24,3 +25,50 @@ func setPageToQuery(page *Pagination, query url.Values) {
 		query.Add("startIndex", strconv.Itoa(page.StartIndex))
 	}
 }
+
+const (
+	// maxPageCount is the maximum count of resources per page Slack returns.
+	maxPageCount = 1000
+)
+
+// GetAllUsers calls GET /Users API repeatedly with pagination and returns all users.
+func (c *Client) GetAllUsers(ctx context.Context, filter string) (_ []User, err error) {
+	ctx, span := c.startMethodSpan(ctx, "GetAllUsers")
+	defer func() {
+		span.end(err)
+	}()
+	users := []User{}
+	page := &Pagination{Count: maxPageCount, StartIndex: 1}
+	for {
+		ret, _, err := c.GetUsers(ctx, page, filter)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, ret.Resources...)
+		if len(ret.Resources) == 0 || page.StartIndex+len(ret.Resources) > ret.TotalResults {
+			return users, nil
+		}
+		page.StartIndex += len(ret.Resources)
+	}
+}
+
+// GetAllGroups calls GET /Groups API repeatedly with pagination and returns all groups.
+func (c *Client) GetAllGroups(ctx context.Context, filter string) (_ []Group, err error) {
+	ctx, span := c.startMethodSpan(ctx, "GetAllGroups")
+	defer func() {
+		span.end(err)
+	}()
+	groups := []Group{}
+	page := &Pagination{Count: maxPageCount, StartIndex: 1}
+	for {
+		ret, _, err := c.GetGroups(ctx, page, filter)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, ret.Resources...)
+		if len(ret.Resources) == 0 || page.StartIndex+len(ret.Resources) > ret.TotalResults {
+			return groups, nil
+		}
+		page.StartIndex += len(ret.Resources)
+	}
+}
@@ -1,9 +1,12 @@
 package scim
 
 import (
+	"context"
 	"net/url"
 	"testing"
 
+	"gopkg.in/h2non/gock.v1"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,3 +29,53 @@ func Test_setPageToQuery(t *testing.T) {
 		}
 	}
 }
+
+func TestClient_GetAllUsers(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("startIndex", "1").
+		MatchParam("count", "1000").
+		Reply(200).
+		BodyString(`{"totalResults": 3, "Resources": [{"id": "U1"}, {"id": "U2"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("startIndex", "3").
+		Reply(200).
+		BodyString(`{"totalResults": 3, "Resources": [{"id": "U3"}]}`)
+	users, err := client.GetAllUsers(ctx, "")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Equal(t, []User{{ID: "U1"}, {ID: "U2"}, {ID: "U3"}}, users)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+	_, err = client.GetAllUsers(ctx, "")
+	require.NotNil(t, err)
+}
+
+func TestClient_GetAllGroups(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		MatchParam("startIndex", "1").
+		Reply(200).
+		BodyString(`{"totalResults": 2, "Resources": [{"id": "G1"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		MatchParam("startIndex", "2").
+		Reply(200).
+		BodyString(`{"totalResults": 2, "Resources": [{"id": "G2"}]}`)
+	groups, err := client.GetAllGroups(ctx, "")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Equal(t, []Group{{ID: "G1"}, {ID: "G2"}}, groups)
+}
@@ -0,0 +1,219 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type (
+	// PrometheusOption is an option of NewPrometheusMetrics .
+	PrometheusOption struct {
+		// Namespace is the prefix of metric names. If Namespace is empty, "slack_scim" is used.
+		Namespace string
+		// Buckets are upper bounds of the request duration histogram in seconds.
+		// If Buckets is nil, DefaultPrometheusBuckets is used.
+		Buckets []float64
+	}
+
+	// PrometheusMetrics is a MetricsHook which records metrics with the Prometheus client library.
+	// PrometheusMetrics implements prometheus.Collector, so it can be registered to a prometheus.Registry .
+	// PrometheusMetrics implements http.Handler too, which serves only its own metrics as a /metrics endpoint.
+	// PrometheusMetrics should be created by the function NewPrometheusMetrics .
+	// PrometheusMetrics is safe for concurrent use.
+	//
+	// The following metrics are exported with the namespace prefix.
+	//
+	//   requests_total{operation, status, error_code}             counter
+	//   request_errors_total{operation, status, error_code}       counter of error responses and network errors
+	//   request_duration_seconds{operation}                       histogram
+	//   retries_total{operation, reason}                          counter
+	//   rate_limited_total{operation}                             counter
+	//   rate_limit_retry_after_seconds_total{operation}           counter of Retry-After values of rate limited responses
+	//   directory_users{state}                                    gauge of "active" and "inactive" users
+	//   directory_groups                                          gauge
+	//   directory_group_members                                   gauge of the total number of members of groups
+	//
+	// The client doesn't wait for Retry-After, so rate_limit_retry_after_seconds_total is the wait requested by Slack,
+	// not the time spent waiting.
+	// status is "none" for network errors, and error_code is empty if the response has no Slack error code.
+	// Directory gauges are exported only after PrometheusMetrics.SetDirectory is called.
+	PrometheusMetrics struct {
+		requests        *prometheus.CounterVec
+		errors          *prometheus.CounterVec
+		durations       *prometheus.HistogramVec
+		retries         *prometheus.CounterVec
+		rateLimits      *prometheus.CounterVec
+		retryAfter      *prometheus.CounterVec
+		directoryUsers  *prometheus.Desc
+		directoryGroups *prometheus.Desc
+		groupMembers    *prometheus.Desc
+		directory       *Directory
+		handler         http.Handler
+		mutex           sync.Mutex
+	}
+)
+
+// DefaultPrometheusBuckets is the default buckets of the request duration histogram in seconds.
+var DefaultPrometheusBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewPrometheusMetrics returns empty metrics.
+func NewPrometheusMetrics(opts *PrometheusOption) *PrometheusMetrics {
+	if opts == nil {
+		opts = &PrometheusOption{}
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = "slack_scim"
+	}
+	buckets := opts.Buckets
+	if buckets == nil {
+		buckets = DefaultPrometheusBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	requestLabels := []string{"operation", "status", "error_code"}
+	m := &PrometheusMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "The number of requests.",
+		}, requestLabels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_errors_total",
+			Help:      "The number of error responses and network errors.",
+		}, requestLabels),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "The duration of requests in seconds.",
+			Buckets:   buckets,
+		}, []string{"operation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "The number of retried requests.",
+		}, []string{"operation", "reason"}),
+		rateLimits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_total",
+			Help:      "The number of rate limited responses.",
+		}, []string{"operation"}),
+		retryAfter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_retry_after_seconds_total",
+			Help:      "The total of Retry-After values of rate limited responses. The client doesn't wait for them.",
+		}, []string{"operation"}),
+		directoryUsers: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "directory_users"),
+			"The number of users in the directory.", []string{"state"}, nil),
+		directoryGroups: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "directory_groups"),
+			"The number of groups in the directory.", nil, nil),
+		groupMembers: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "directory_group_members"),
+			"The total number of members of groups in the directory.", nil, nil),
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(m)
+	m.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return m
+}
+
+// ObserveRequest counts the request and observes the duration.
+func (m *PrometheusMetrics) ObserveRequest(ctx context.Context, metrics *RequestMetrics) {
+	status := "none"
+	if metrics.Err == nil {
+		status = strconv.Itoa(metrics.StatusCode)
+	}
+	code := ""
+	if metrics.ErrorCode != 0 {
+		code = strconv.Itoa(metrics.ErrorCode)
+	}
+	m.requests.WithLabelValues(metrics.Operation, status, code).Inc()
+	if metrics.Err != nil || metrics.StatusCode >= 400 {
+		m.errors.WithLabelValues(metrics.Operation, status, code).Inc()
+	}
+	m.durations.WithLabelValues(metrics.Operation).Observe(metrics.Duration.Seconds())
+}
+
+// ObserveRetry counts the retry.
+func (m *PrometheusMetrics) ObserveRetry(ctx context.Context, operation, reason string) {
+	m.retries.WithLabelValues(operation, reason).Inc()
+}
+
+// ObserveRateLimit counts the rate limited response and adds retryAfter to the total of Retry-After values.
+func (m *PrometheusMetrics) ObserveRateLimit(ctx context.Context, operation string, retryAfter time.Duration) {
+	m.rateLimits.WithLabelValues(operation).Inc()
+	m.retryAfter.WithLabelValues(operation).Add(retryAfter.Seconds())
+}
+
+// SetDirectory sets the directory whose gauges are exported.
+// The gauges are computed from the directory at every collection, so a directory kept up to date by
+// Directory.ApplyMutation or reloaded periodically is reflected.
+func (m *PrometheusMetrics) SetDirectory(dir *Directory) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.directory = dir
+}
+
+// Describe sends descriptors of the metrics. Describe implements prometheus.Collector .
+func (m *PrometheusMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.errors.Describe(ch)
+	m.durations.Describe(ch)
+	m.retries.Describe(ch)
+	m.rateLimits.Describe(ch)
+	m.retryAfter.Describe(ch)
+	ch <- m.directoryUsers
+	ch <- m.directoryGroups
+	ch <- m.groupMembers
+}
+
+// Collect sends the metrics. Collect implements prometheus.Collector .
+func (m *PrometheusMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.errors.Collect(ch)
+	m.durations.Collect(ch)
+	m.retries.Collect(ch)
+	m.rateLimits.Collect(ch)
+	m.retryAfter.Collect(ch)
+	m.mutex.Lock()
+	dir := m.directory
+	m.mutex.Unlock()
+	if dir != nil {
+		m.collectDirectory(ch, dir)
+	}
+}
+
+// ServeHTTP serves the metrics in the Prometheus exposition format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.handler.ServeHTTP(w, r)
+}
+
+func (m *PrometheusMetrics) collectDirectory(ch chan<- prometheus.Metric, dir *Directory) {
+	active, inactive := 0, 0
+	for _, user := range dir.Users() {
+		if user.Active {
+			active++
+		} else {
+			inactive++
+		}
+	}
+	groups := dir.Groups()
+	members := 0
+	for _, group := range groups {
+		members += len(group.Members)
+	}
+	ch <- prometheus.MustNewConstMetric(m.directoryUsers, prometheus.GaugeValue, float64(active), "active")
+	ch <- prometheus.MustNewConstMetric(m.directoryUsers, prometheus.GaugeValue, float64(inactive), "inactive")
+	ch <- prometheus.MustNewConstMetric(m.directoryGroups, prometheus.GaugeValue, float64(len(groups)))
+	ch <- prometheus.MustNewConstMetric(m.groupMembers, prometheus.GaugeValue, float64(members))
+}
@@ -0,0 +1,81 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusMetrics_Collect(t *testing.T) {
+	ctx := context.Background()
+	m := NewPrometheusMetrics(&PrometheusOption{Namespace: "test", Buckets: []float64{1, 0.1}})
+	m.ObserveRequest(ctx, &RequestMetrics{Operation: "GetUser", Method: "GET", StatusCode: 200, Duration: 50 * time.Millisecond})
+	m.ObserveRequest(ctx, &RequestMetrics{
+		Operation: "GetUser", Method: "GET", StatusCode: 404, ErrorCode: 404, Duration: 500 * time.Millisecond,
+	})
+	m.ObserveRequest(ctx, &RequestMetrics{Operation: "PatchUser", Method: "PATCH", Duration: 2 * time.Second, Err: errors.New("timeout")})
+	m.ObserveRetry(ctx, "GetUser", RetryReasonTokenRefreshed)
+	m.ObserveRateLimit(ctx, "GetUsers", 30*time.Second)
+	m.ObserveRateLimit(ctx, "GetUsers", 0)
+
+	// the metrics can be registered to a registry
+	registry := prometheus.NewRegistry()
+	require.Nil(t, registry.Register(m))
+
+	require.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(`# HELP test_requests_total The number of requests.
+# TYPE test_requests_total counter
+test_requests_total{error_code="",operation="GetUser",status="200"} 1
+test_requests_total{error_code="404",operation="GetUser",status="404"} 1
+test_requests_total{error_code="",operation="PatchUser",status="none"} 1
+# HELP test_request_errors_total The number of error responses and network errors.
+# TYPE test_request_errors_total counter
+test_request_errors_total{error_code="404",operation="GetUser",status="404"} 1
+test_request_errors_total{error_code="",operation="PatchUser",status="none"} 1
+# HELP test_request_duration_seconds The duration of requests in seconds.
+# TYPE test_request_duration_seconds histogram
+test_request_duration_seconds_bucket{operation="GetUser",le="0.1"} 1
+test_request_duration_seconds_bucket{operation="GetUser",le="1"} 2
+test_request_duration_seconds_bucket{operation="GetUser",le="+Inf"} 2
+test_request_duration_seconds_sum{operation="GetUser"} 0.55
+test_request_duration_seconds_count{operation="GetUser"} 2
+test_request_duration_seconds_bucket{operation="PatchUser",le="0.1"} 0
+test_request_duration_seconds_bucket{operation="PatchUser",le="1"} 0
+test_request_duration_seconds_bucket{operation="PatchUser",le="+Inf"} 1
+test_request_duration_seconds_sum{operation="PatchUser"} 2
+test_request_duration_seconds_count{operation="PatchUser"} 1
+# HELP test_retries_total The number of retried requests.
+# TYPE test_retries_total counter
+test_retries_total{operation="GetUser",reason="token_refreshed"} 1
+# HELP test_rate_limited_total The number of rate limited responses.
+# TYPE test_rate_limited_total counter
+test_rate_limited_total{operation="GetUsers"} 2
+# HELP test_rate_limit_retry_after_seconds_total The total of Retry-After values of rate limited responses. The client doesn't wait for them.
+# TYPE test_rate_limit_retry_after_seconds_total counter
+test_rate_limit_retry_after_seconds_total{operation="GetUsers"} 30
+`)))
+}
+
+func TestPrometheusMetrics_SetDirectory(t *testing.T) {
+	m := NewPrometheusMetrics(nil)
+	m.SetDirectory(NewDirectory(
+		[]User{{ID: "U1", Active: true}, {ID: "U2", Active: true}, {ID: "U3"}},
+		[]Group{{ID: "G1", Members: []Member{{Value: "U1"}, {Value: "U2"}}}, {ID: "G2", Members: []Member{{Value: "U1"}}}},
+	))
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, w.Code)
+	require.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+	body := w.Body.String()
+	require.Contains(t, body, "\nslack_scim_directory_users{state=\"active\"} 2\n")
+	require.Contains(t, body, "\nslack_scim_directory_users{state=\"inactive\"} 1\n")
+	require.Contains(t, body, "\nslack_scim_directory_groups 2\n")
+	require.Contains(t, body, "\nslack_scim_directory_group_members 3\n")
+}
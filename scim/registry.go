@@ -0,0 +1,237 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type (
+	// Registry holds named clients for multiple Slack workspaces and Enterprise Grid organizations.
+	// Registry should be created by the function NewRegistry, NewRegistryFromConfig or LoadRegistry .
+	Registry struct {
+		names   []string
+		clients map[string]*Client
+		mutex   sync.RWMutex
+	}
+
+	// RegistryConfig is a configuration of Registry.
+	RegistryConfig struct {
+		Workspaces []WorkspaceConfig `json:"workspaces"`
+	}
+
+	// WorkspaceConfig is a configuration of a workspace's client.
+	// One of Token, TokenFile and TokenCommand is required.
+	// TokenFile and TokenCommand are used to create FileTokenSource and CommandTokenSource .
+	WorkspaceConfig struct {
+		Name         string   `json:"name"`
+		Endpoint     string   `json:"endpoint,omitempty"`
+		Token        string   `json:"token,omitempty"`
+		TokenFile    string   `json:"tokenFile,omitempty"`
+		TokenCommand []string `json:"tokenCommand,omitempty"`
+	}
+
+	// WorkspaceResult is a result of a function run against a workspace by Registry.FanOut .
+	WorkspaceResult struct {
+		Workspace string
+		Value     interface{}
+		Err       error
+	}
+
+	// WorkspaceError is an error which occurred in a workspace.
+	WorkspaceError struct {
+		Workspace string
+		Err       error
+	}
+
+	// WorkspaceUser is a user found in a workspace.
+	WorkspaceUser struct {
+		Workspace string
+		User      User
+	}
+
+	// WorkspaceUsers is an aggregated result of searching users across workspaces.
+	// Errors has errors of workspaces where the search failed.
+	WorkspaceUsers struct {
+		Users  []WorkspaceUser
+		Errors []WorkspaceError
+	}
+)
+
+// Error returns the error message with the workspace name.
+func (e *WorkspaceError) Error() string {
+	return fmt.Sprintf("workspace %s: %v", e.Workspace, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *WorkspaceError) Unwrap() error {
+	return e.Err
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		clients: map[string]*Client{},
+	}
+}
+
+// NewRegistryFromConfig returns a registry which has clients created from cfg.
+func NewRegistryFromConfig(cfg *RegistryConfig) (*Registry, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("cfg is required")
+	}
+	reg := NewRegistry()
+	for _, ws := range cfg.Workspaces {
+		client, err := ws.newClient()
+		if err != nil {
+			return nil, err
+		}
+		if err := reg.Add(ws.Name, client); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// ReadRegistryConfig reads a JSON configuration of Registry from r.
+func ReadRegistryConfig(r io.Reader) (*RegistryConfig, error) {
+	cfg := &RegistryConfig{}
+	if err := json.NewDecoder(r).Decode(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadRegistry reads a JSON configuration file and returns a registry created from it.
+func LoadRegistry(path string) (*Registry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg, err := ReadRegistryConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the registry configuration %s: %w", path, err)
+	}
+	return NewRegistryFromConfig(cfg)
+}
+
+func (ws *WorkspaceConfig) newClient() (*Client, error) {
+	if ws.Name == "" {
+		return nil, fmt.Errorf("workspace name is required")
+	}
+	var src TokenSource
+	switch {
+	case ws.Token != "":
+		src = NewStaticTokenSource(ws.Token)
+	case ws.TokenFile != "":
+		src = NewFileTokenSource(ws.TokenFile)
+	case len(ws.TokenCommand) != 0:
+		src = NewCommandTokenSource(ws.TokenCommand[0], ws.TokenCommand[1:]...)
+	default:
+		return nil, fmt.Errorf("workspace %s: one of token, tokenFile and tokenCommand is required", ws.Name)
+	}
+	client := NewClientWithTokenSource(src)
+	client.SetEndpoint(ws.Endpoint)
+	return client, nil
+}
+
+// Add adds client to the registry with name.
+// If a client with the same name is already registered, an error is returned.
+func (reg *Registry) Add(name string, client *Client) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if client == nil {
+		return fmt.Errorf("client is required")
+	}
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+	if _, ok := reg.clients[name]; ok {
+		return fmt.Errorf("workspace %s is already registered", name)
+	}
+	reg.names = append(reg.names, name)
+	reg.clients[name] = client
+	return nil
+}
+
+// Get returns the client registered with name.
+func (reg *Registry) Get(name string) (*Client, bool) {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+	client, ok := reg.clients[name]
+	return client, ok
+}
+
+// Names returns the names of registered clients in the order they were added.
+func (reg *Registry) Names() []string {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+	names := make([]string, len(reg.names))
+	copy(names, reg.names)
+	return names
+}
+
+// FanOut calls fn concurrently for all registered clients and returns the results.
+// The results are sorted in the order the clients were added.
+func (reg *Registry) FanOut(
+	ctx context.Context, fn func(ctx context.Context, workspace string, client *Client) (interface{}, error),
+) []WorkspaceResult {
+	names := reg.Names()
+	results := make([]WorkspaceResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		client, _ := reg.Get(name)
+		wg.Add(1)
+		go func(i int, name string, client *Client) {
+			defer wg.Done()
+			v, err := fn(ctx, name, client)
+			results[i] = WorkspaceResult{
+				Workspace: name,
+				Value:     v,
+				Err:       err,
+			}
+		}(i, name, client)
+	}
+	wg.Wait()
+	return results
+}
+
+// FindUsers searches users with filter across all workspaces.
+// All pages of each workspace are got by Client.GetAllUsers .
+// Even if the search fails in some workspaces, users found in other workspaces are returned.
+func (reg *Registry) FindUsers(ctx context.Context, filter string) *WorkspaceUsers {
+	results := reg.FanOut(ctx, func(ctx context.Context, workspace string, client *Client) (interface{}, error) {
+		return client.GetAllUsers(ctx, filter)
+	})
+	ret := &WorkspaceUsers{}
+	for _, result := range results {
+		if result.Err != nil {
+			ret.Errors = append(ret.Errors, WorkspaceError{
+				Workspace: result.Workspace,
+				Err:       result.Err,
+			})
+			continue
+		}
+		for _, user := range result.Value.([]User) {
+			ret.Users = append(ret.Users, WorkspaceUser{
+				Workspace: result.Workspace,
+				User:      user,
+			})
+		}
+	}
+	return ret
+}
+
+// FindUsersByEmail searches users whose email is email across all workspaces.
+func (reg *Registry) FindUsersByEmail(ctx context.Context, email string) *WorkspaceUsers {
+	return reg.FindUsers(ctx, eqFilter("email", email))
+}
+
+// FindUsersByUserName searches users whose userName is userName across all workspaces.
+func (reg *Registry) FindUsersByUserName(ctx context.Context, userName string) *WorkspaceUsers {
+	return reg.FindUsers(ctx, eqFilter("userName", userName))
+}
@@ -0,0 +1,164 @@
+package scim
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRegistryConfig(t *testing.T) {
+	cfg, err := ReadRegistryConfig(strings.NewReader(`{
+  "workspaces": [
+    {"name": "foo", "token": "XXX"},
+    {"name": "bar", "tokenFile": "/tmp/token", "endpoint": "https://example.com/scim/v1"}
+  ]
+}`))
+	require.Nil(t, err)
+	require.Equal(t, &RegistryConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "foo", Token: "XXX"},
+			{Name: "bar", TokenFile: "/tmp/token", Endpoint: "https://example.com/scim/v1"},
+		},
+	}, cfg)
+
+	_, err = ReadRegistryConfig(strings.NewReader(`[`))
+	require.NotNil(t, err)
+}
+
+func TestNewRegistryFromConfig(t *testing.T) {
+	data := []struct {
+		cfg     *RegistryConfig
+		names   []string
+		isError bool
+	}{
+		{
+			isError: true,
+		},
+		{
+			cfg: &RegistryConfig{
+				Workspaces: []WorkspaceConfig{
+					{Name: "foo", Token: "XXX"},
+					{Name: "bar", TokenCommand: []string{"echo", "YYY"}},
+					{Name: "zoo", TokenFile: "/tmp/token"},
+				},
+			},
+			names: []string{"foo", "bar", "zoo"},
+		},
+		{
+			cfg: &RegistryConfig{
+				Workspaces: []WorkspaceConfig{
+					{Name: "foo"},
+				},
+			},
+			isError: true,
+		},
+		{
+			cfg: &RegistryConfig{
+				Workspaces: []WorkspaceConfig{
+					{Token: "XXX"},
+				},
+			},
+			isError: true,
+		},
+		{
+			cfg: &RegistryConfig{
+				Workspaces: []WorkspaceConfig{
+					{Name: "foo", Token: "XXX"},
+					{Name: "foo", Token: "YYY"},
+				},
+			},
+			isError: true,
+		},
+	}
+	for _, d := range data {
+		reg, err := NewRegistryFromConfig(d.cfg)
+		if d.isError {
+			require.NotNil(t, err)
+			continue
+		}
+		require.Nil(t, err)
+		require.Equal(t, d.names, reg.Names())
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "registry.json")
+
+	_, err = LoadRegistry(p)
+	require.NotNil(t, err)
+
+	require.Nil(t, ioutil.WriteFile(p, []byte(`{"workspaces": [{"name": "foo", "token": "XXX"}]}`), 0600))
+	reg, err := LoadRegistry(p)
+	require.Nil(t, err)
+	client, ok := reg.Get("foo")
+	require.True(t, ok)
+	require.Equal(t, DefaultEndpoint, client.endpoint)
+
+	require.Nil(t, ioutil.WriteFile(p, []byte(`{`), 0600))
+	_, err = LoadRegistry(p)
+	require.NotNil(t, err)
+}
+
+func TestRegistry_Add(t *testing.T) {
+	reg := NewRegistry()
+	require.NotNil(t, reg.Add("", NewClient("XXX")))
+	require.NotNil(t, reg.Add("foo", nil))
+	require.Nil(t, reg.Add("foo", NewClient("XXX")))
+	require.NotNil(t, reg.Add("foo", NewClient("XXX")))
+	_, ok := reg.Get("foo")
+	require.True(t, ok)
+	_, ok = reg.Get("bar")
+	require.False(t, ok)
+}
+
+func TestRegistry_FindUsersByEmail(t *testing.T) {
+	defer gock.Off()
+
+	reg := NewRegistry()
+	require.Nil(t, reg.Add("foo", NewClient("XXX").WithEndpoint("https://foo.example.com/scim/v1")))
+	require.Nil(t, reg.Add("bar", NewClient("XXX").WithEndpoint("https://bar.example.com/scim/v1")))
+	require.Nil(t, reg.Add("zoo", NewClient("XXX").WithEndpoint("https://zoo.example.com/scim/v1")))
+
+	// all pages are got
+	gock.New("https://foo.example.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `email eq "foo@example.com"`).
+		MatchParam("startIndex", "1").
+		Reply(200).
+		BodyString(`{"totalResults": 2, "Resources": [{"id": "XXX", "userName": "foo"}]}`)
+	gock.New("https://foo.example.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `email eq "foo@example.com"`).
+		MatchParam("startIndex", "2").
+		Reply(200).
+		BodyString(`{"totalResults": 2, "Resources": [{"id": "YYY", "userName": "foo2"}]}`)
+	gock.New("https://bar.example.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `email eq "foo@example.com"`).
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+	gock.New("https://zoo.example.com").
+		Get("/scim/v1/Users").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+
+	result := reg.FindUsersByEmail(context.Background(), "foo@example.com")
+	require.Equal(t, []WorkspaceUser{
+		{Workspace: "foo", User: User{ID: "XXX", UserName: "foo"}},
+		{Workspace: "foo", User: User{ID: "YYY", UserName: "foo2"}},
+	}, result.Users)
+	require.Len(t, result.Errors, 1)
+	require.Equal(t, "zoo", result.Errors[0].Workspace)
+	require.Equal(t, "workspace zoo: internal error", result.Errors[0].Error())
+	require.True(t, gock.IsDone())
+}
@@ -0,0 +1,270 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// SafetyConfig is a configuration of SafetyPolicy .
+	SafetyConfig struct {
+		// MaxDeactivations is the maximum number of users deactivated while the policy is used.
+		// If MaxDeactivations is zero, the number isn't limited.
+		MaxDeactivations int `json:"maxDeactivations,omitempty"`
+		// MaxDeactivationsPerHour is the maximum number of users deactivated in the last hour.
+		// If MaxDeactivationsPerHour is zero, the number isn't limited.
+		MaxDeactivationsPerHour int `json:"maxDeactivationsPerHour,omitempty"`
+		// ProtectedUserIDs is ids of users such as owners and bots who can't be deactivated or removed from groups.
+		ProtectedUserIDs []string `json:"protectedUserIds,omitempty"`
+		// If AllowEmptyGroup is false, mutations which remove all members of a group are refused.
+		AllowEmptyGroup bool `json:"allowEmptyGroup,omitempty"`
+		// OverrideToken is a token to bypass the policy.
+		// If a context has the token by WithSafetyOverride, mutations with the context aren't checked.
+		// If OverrideToken is empty, the policy can't be bypassed.
+		OverrideToken string `json:"-"`
+	}
+
+	// SafetyPolicy limits destructive mutations before they are sent.
+	// SafetyPolicy should be created by the function NewSafetyPolicy,
+	// and SafetyPolicy.Guard should be set by Client.SetMutationGuard .
+	// SafetyPolicy is safe for concurrent use.
+	SafetyPolicy struct {
+		config        SafetyConfig
+		protected     map[string]struct{}
+		deactivations []time.Time
+		total         int
+		now           func() time.Time
+		mutex         sync.Mutex
+	}
+
+	// DeactivationLimitError is returned when a deactivation exceeds the limit.
+	DeactivationLimitError struct {
+		UserID string
+		Limit  int
+		// Window is the period of the limit. If Window is zero, the limit is for the lifetime of the policy.
+		Window time.Duration
+	}
+
+	// ProtectedUserError is returned when a mutation deactivates a protected user or removes a protected user from a group.
+	// Deleting a group which has protected members is also refused.
+	ProtectedUserError struct {
+		UserID string
+		// GroupID is the id of the group the user is removed from.
+		// If GroupID is empty, the user is deactivated.
+		GroupID string
+	}
+
+	// EmptyGroupError is returned when a mutation removes all members of a group.
+	EmptyGroupError struct {
+		GroupID string
+	}
+
+	safetyOverrideKey struct{}
+)
+
+var (
+	// ErrSafetyViolation is matched with errors returned by SafetyPolicy by errors.Is .
+	ErrSafetyViolation = errors.New("the mutation violates the safety policy")
+)
+
+// Error returns the error message.
+func (e *DeactivationLimitError) Error() string {
+	if e.Window == 0 {
+		return fmt.Sprintf("deactivating the user %s exceeds the limit %d", e.UserID, e.Limit)
+	}
+	return fmt.Sprintf("deactivating the user %s exceeds the limit %d per %s", e.UserID, e.Limit, e.Window)
+}
+
+// Is returns true if target is ErrSafetyViolation .
+func (e *DeactivationLimitError) Is(target error) bool {
+	return target == ErrSafetyViolation
+}
+
+// Error returns the error message.
+func (e *ProtectedUserError) Error() string {
+	if e.GroupID == "" {
+		return fmt.Sprintf("the user %s is protected and can't be deactivated", e.UserID)
+	}
+	return fmt.Sprintf("the user %s is protected and can't be removed from the group %s", e.UserID, e.GroupID)
+}
+
+// Is returns true if target is ErrSafetyViolation .
+func (e *ProtectedUserError) Is(target error) bool {
+	return target == ErrSafetyViolation
+}
+
+// Error returns the error message.
+func (e *EmptyGroupError) Error() string {
+	return fmt.Sprintf("the mutation removes all members of the group %s", e.GroupID)
+}
+
+// Is returns true if target is ErrSafetyViolation .
+func (e *EmptyGroupError) Is(target error) bool {
+	return target == ErrSafetyViolation
+}
+
+// WithSafetyOverride returns a copy of ctx with the override token.
+// Mutations with the returned context bypass SafetyPolicy whose OverrideToken is token.
+func WithSafetyOverride(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, safetyOverrideKey{}, token)
+}
+
+// NewSafetyPolicy returns a safety policy.
+func NewSafetyPolicy(cfg *SafetyConfig) *SafetyPolicy {
+	if cfg == nil {
+		cfg = &SafetyConfig{}
+	}
+	protected := make(map[string]struct{}, len(cfg.ProtectedUserIDs))
+	for _, id := range cfg.ProtectedUserIDs {
+		protected[id] = struct{}{}
+	}
+	return &SafetyPolicy{
+		config:    *cfg,
+		protected: protected,
+		now:       time.Now,
+	}
+}
+
+// Guard checks the mutation. Guard is a MutationGuard .
+// To check group mutations and PUT /Users/{id} API, the current user or group is got by Mutation.PreImage .
+func (policy *SafetyPolicy) Guard(ctx context.Context, mutation *Mutation) error {
+	if policy.config.OverrideToken != "" {
+		if token, ok := ctx.Value(safetyOverrideKey{}).(string); ok && token == policy.config.OverrideToken {
+			return nil
+		}
+	}
+	switch mutation.ResourceType {
+	case ResourceTypeUser:
+		deactivate, err := isDeactivation(ctx, mutation)
+		if err != nil || !deactivate {
+			return err
+		}
+		if _, ok := policy.protected[mutation.ResourceID]; ok {
+			return &ProtectedUserError{UserID: mutation.ResourceID}
+		}
+		return policy.countDeactivation(mutation.ResourceID)
+	case ResourceTypeGroup:
+		return policy.checkGroup(ctx, mutation)
+	}
+	return nil
+}
+
+func isDeactivation(ctx context.Context, mutation *Mutation) (bool, error) {
+	switch mutation.Operation {
+	case MutationDelete:
+		return true, nil
+	case MutationPatch:
+		patch, ok := mutation.Request.(*UserPatch)
+		return ok && patch != nil && patch.Active != nil && !*patch.Active, nil
+	case MutationPut:
+		user, ok := mutation.Request.(*User)
+		if !ok || user == nil || user.Active {
+			return false, nil
+		}
+		preImage, err := mutation.PreImage(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get the user %s: %w", mutation.ResourceID, err)
+		}
+		current, ok := preImage.(*User)
+		return ok && current != nil && current.Active, nil
+	}
+	return false, nil
+}
+
+// countDeactivation checks limits and counts the deactivation.
+// The deactivation is counted even if the request fails afterwards, so the limits are conservative.
+func (policy *SafetyPolicy) countDeactivation(userID string) error {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	if policy.config.MaxDeactivations > 0 && policy.total >= policy.config.MaxDeactivations {
+		return &DeactivationLimitError{
+			UserID: userID,
+			Limit:  policy.config.MaxDeactivations,
+		}
+	}
+	now := policy.now()
+	if limit := policy.config.MaxDeactivationsPerHour; limit > 0 {
+		recent := policy.deactivations[:0]
+		for _, t := range policy.deactivations {
+			if now.Sub(t) < time.Hour {
+				recent = append(recent, t)
+			}
+		}
+		policy.deactivations = recent
+		if len(recent) >= limit {
+			return &DeactivationLimitError{
+				UserID: userID,
+				Limit:  limit,
+				Window: time.Hour,
+			}
+		}
+	}
+	policy.total++
+	policy.deactivations = append(policy.deactivations, now)
+	return nil
+}
+
+func (policy *SafetyPolicy) checkGroup(ctx context.Context, mutation *Mutation) error {
+	if mutation.Operation == MutationCreate {
+		return nil
+	}
+	if len(policy.protected) == 0 && policy.config.AllowEmptyGroup {
+		return nil
+	}
+	preImage, err := mutation.PreImage(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the group %s: %w", mutation.ResourceID, err)
+	}
+	current, ok := preImage.(*Group)
+	if !ok || current == nil {
+		return nil
+	}
+	members := make(map[string]struct{}, len(current.Members))
+	for _, member := range current.Members {
+		members[member.Value] = struct{}{}
+	}
+	remained := make(map[string]struct{}, len(current.Members))
+	switch mutation.Operation {
+	case MutationPatch:
+		patch, ok := mutation.Request.(*Group)
+		if !ok || patch == nil {
+			return nil
+		}
+		for id := range members {
+			remained[id] = struct{}{}
+		}
+		for _, member := range patch.Members {
+			if member.Operation == MemberOperationDelete {
+				delete(remained, member.Value)
+				continue
+			}
+			remained[member.Value] = struct{}{}
+		}
+	case MutationPut:
+		group, ok := mutation.Request.(*Group)
+		if !ok || group == nil {
+			return nil
+		}
+		for _, member := range group.Members {
+			remained[member.Value] = struct{}{}
+		}
+	}
+	for _, member := range current.Members {
+		if _, ok := remained[member.Value]; ok {
+			continue
+		}
+		if _, ok := policy.protected[member.Value]; ok {
+			return &ProtectedUserError{
+				UserID:  member.Value,
+				GroupID: mutation.ResourceID,
+			}
+		}
+	}
+	if mutation.Operation != MutationDelete && !policy.config.AllowEmptyGroup && len(members) != 0 && len(remained) == 0 {
+		return &EmptyGroupError{GroupID: mutation.ResourceID}
+	}
+	return nil
+}
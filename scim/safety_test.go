@@ -0,0 +1,166 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafetyPolicy_deactivation(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	policy := NewSafetyPolicy(&SafetyConfig{
+		MaxDeactivations:        3,
+		MaxDeactivationsPerHour: 2,
+		ProtectedUserIDs:        []string{"U0"},
+		OverrideToken:           "override",
+	})
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy.now = func() time.Time {
+		return now
+	}
+	client := NewClient("XXX").WithMutationGuard(policy.Guard)
+
+	_, err := client.DeleteUser(ctx, "U0")
+	protectedErr := &ProtectedUserError{}
+	require.True(t, errors.As(err, &protectedErr))
+	require.Equal(t, "U0", protectedErr.UserID)
+	require.True(t, errors.Is(err, ErrSafetyViolation))
+
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U1").
+		Reply(204)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U2").
+		Reply(200).
+		BodyString(`{"id": "U2", "active": false}`)
+	_, err = client.DeleteUser(ctx, "U1")
+	require.Nil(t, err)
+	active := false
+	_, _, err = client.PatchUser(ctx, "U2", &UserPatch{Active: &active})
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+
+	// the limit per hour
+	_, err = client.DeleteUser(ctx, "U3")
+	limitErr := &DeactivationLimitError{}
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, time.Hour, limitErr.Window)
+
+	// the override token
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U0").
+		Reply(204)
+	_, err = client.DeleteUser(WithSafetyOverride(ctx, "override"), "U0")
+	require.Nil(t, err)
+	_, err = client.DeleteUser(WithSafetyOverride(ctx, "invalid"), "U0")
+	require.NotNil(t, err)
+
+	// the limit per run
+	now = now.Add(time.Hour)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U3").
+		Reply(204)
+	_, err = client.DeleteUser(ctx, "U3")
+	require.Nil(t, err)
+	_, err = client.DeleteUser(ctx, "U4")
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, time.Duration(0), limitErr.Window)
+	require.True(t, gock.IsDone())
+}
+
+func TestSafetyPolicy_group(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	policy := NewSafetyPolicy(&SafetyConfig{
+		ProtectedUserIDs: []string{"U0"},
+	})
+	client := NewClient("XXX").WithMutationGuard(policy.Guard)
+
+	// a protected user can't be removed
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U0"}, {"value": "U1"}]}`)
+	_, _, err := client.RemoveGroupMembers(ctx, "G1", "U0")
+	protectedErr := &ProtectedUserError{}
+	require.True(t, errors.As(err, &protectedErr))
+	require.Equal(t, "G1", protectedErr.GroupID)
+	require.True(t, gock.IsDone())
+
+	// a group can't be emptied
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G2").
+		Reply(200).
+		BodyString(`{"id": "G2", "members": [{"value": "U1"}]}`)
+	_, _, err = client.PutGroup(ctx, "G2", &Group{DisplayName: "foo"})
+	emptyErr := &EmptyGroupError{}
+	require.True(t, errors.As(err, &emptyErr))
+	require.True(t, gock.IsDone())
+
+	// the other members can be removed
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U0"}, {"value": "U1"}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U0"}]}`)
+	_, _, err = client.RemoveGroupMembers(ctx, "G1", "U1")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+
+	// a group which has a protected member can't be deleted
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U0"}]}`)
+	_, err = client.DeleteGroup(ctx, "G1")
+	require.True(t, errors.As(err, &protectedErr))
+	require.True(t, gock.IsDone())
+}
+
+func TestSafetyPolicy_rawResponse(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	policy := NewSafetyPolicy(&SafetyConfig{
+		ProtectedUserIDs: []string{"U0"},
+	})
+	client := NewClient("XXX").WithMutationGuard(policy.Guard)
+
+	// methods which return the raw response can't bypass the guard
+	resp, err := client.DeleteUserResp(ctx, "U0")
+	protectedErr := &ProtectedUserError{}
+	require.True(t, errors.As(err, &protectedErr))
+	require.Nil(t, resp)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "members": [{"value": "U1"}]}`)
+	resp, err = client.PatchGroupResp(ctx, "G1", &Group{
+		Members: []Member{{Value: "U1", Operation: MemberOperationDelete}},
+	})
+	emptyErr := &EmptyGroupError{}
+	require.True(t, errors.As(err, &emptyErr))
+	require.Nil(t, resp)
+	require.True(t, gock.IsDone())
+
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U1").
+		Reply(204)
+	resp, err = client.DeleteUserResp(ctx, "U1")
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, 204, resp.StatusCode)
+	require.True(t, gock.IsDone())
+}
@@ -4,6 +4,15 @@ import (
 	"encoding/json"
 )
 
+const (
+	// SchemaCore is the URN of SCIM core schema.
+	SchemaCore = "urn:scim:schemas:core:1.0"
+	// SchemaEnterpriseUser is the URN of SCIM Enterprise User Schema Extension.
+	SchemaEnterpriseUser = "urn:scim:schemas:extension:enterprise:1.0"
+	// SchemaSlackGuest is the URN of Slack's guest extension.
+	SchemaSlackGuest = "urn:scim:schemas:extension:slack:guest:1.0"
+)
+
 type (
 	// Schema is a schema.
 	// Slack currently supports schemas for users and groups.
@@ -32,6 +41,7 @@ type (
 	}
 
 	// Meta is containing resource metadata.
+	// Attributes is used to remove attributes by PATCH API.
 	Meta struct {
 		Created      string   `json:"created"`
 		LastModified string   `json:"lastModified"`
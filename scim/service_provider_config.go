@@ -93,5 +93,5 @@ func (c *Client) GetServiceProviderConfig(ctx context.Context) (*ServiceProvider
 // Internally, this method returns the returned values of *http.Client.Do .
 func (c *Client) GetServiceProviderConfigResp(ctx context.Context) (*http.Response, error) {
 	// GET /ServiceProviderConfigs
-	return c.getResp(ctx, "GET", "/ServiceProviderConfigs", nil, nil)
+	return c.getResp(ctx, "GetServiceProviderConfig", "GET", "/ServiceProviderConfigs", nil, nil)
 }
@@ -55,3 +55,43 @@ func (c *Client) SetEndpoint(endpoint string) {
 	}
 	c.endpoint = endpoint
 }
+
+// SetTokenSource sets src to c.
+// If src is nil, an empty token is used.
+func (c *Client) SetTokenSource(src TokenSource) {
+	if src == nil {
+		c.tokenSource = NewStaticTokenSource("")
+		return
+	}
+	c.tokenSource = src
+}
+
+// SetMutationHook sets hook to c.
+// If hook is nil, no hook is called.
+func (c *Client) SetMutationHook(hook MutationHook) {
+	c.mutationHook = hook
+}
+
+// SetMutationGuard sets guard to c.
+// If guard is nil, no guard is called.
+func (c *Client) SetMutationGuard(guard MutationGuard) {
+	c.mutationGuard = guard
+}
+
+// SetMetricsHook sets hook to c.
+// If hook is nil, no metrics are recorded.
+func (c *Client) SetMetricsHook(hook MetricsHook) {
+	c.metricsHook = hook
+}
+
+// SetTracer sets tracer to c.
+// If tracer is nil, no span is created.
+func (c *Client) SetTracer(tracer Tracer) {
+	c.tracer = tracer
+}
+
+// SetRequestLogger sets logger to c.
+// If logger is nil, no request is logged.
+func (c *Client) SetRequestLogger(logger *RequestLogger) {
+	c.requestLogger = logger
+}
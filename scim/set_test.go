@@ -1,6 +1,7 @@
 package scim
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -62,3 +63,63 @@ func TestClient_SetEndpoint(t *testing.T) {
 	c.SetEndpoint(ep)
 	require.Equal(t, ep, c.endpoint)
 }
+
+func TestClient_SetTokenSource(t *testing.T) {
+	c := &Client{}
+
+	c.SetTokenSource(nil)
+	require.NotNil(t, c.tokenSource)
+
+	src := NewStaticTokenSource("token")
+	c.SetTokenSource(src)
+	require.Equal(t, src, c.tokenSource)
+}
+
+func TestClient_SetMutationHook(t *testing.T) {
+	c := &Client{}
+
+	c.SetMutationHook(func(ctx context.Context, mutation *Mutation) {})
+	require.NotNil(t, c.mutationHook)
+
+	c.SetMutationHook(nil)
+	require.Nil(t, c.mutationHook)
+}
+
+func TestClient_SetMutationGuard(t *testing.T) {
+	c := &Client{}
+
+	c.SetMutationGuard(func(ctx context.Context, mutation *Mutation) error {
+		return nil
+	})
+	require.NotNil(t, c.mutationGuard)
+}
+
+func TestClient_SetMetricsHook(t *testing.T) {
+	c := &Client{}
+
+	c.SetMetricsHook(NewPrometheusMetrics(nil))
+	require.NotNil(t, c.metricsHook)
+
+	c.SetMetricsHook(nil)
+	require.Nil(t, c.metricsHook)
+}
+
+func TestClient_SetTracer(t *testing.T) {
+	c := &Client{}
+
+	c.SetTracer(&testTracer{})
+	require.NotNil(t, c.tracer)
+
+	c.SetTracer(nil)
+	require.Nil(t, c.tracer)
+}
+
+func TestClient_SetRequestLogger(t *testing.T) {
+	c := &Client{}
+
+	c.SetRequestLogger(NewRequestLogger(nil, nil))
+	require.NotNil(t, c.requestLogger)
+
+	c.SetRequestLogger(nil)
+	require.Nil(t, c.requestLogger)
+}
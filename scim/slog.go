@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package scim
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger is a Logger which writes logs to *slog.Logger .
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger which writes logs to logger.
+// If logger is nil, slog.Default() is used.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{
+		logger: logger,
+	}
+}
+
+// Log writes the log with fields as attributes.
+func (l *SlogLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...LogField) {
+	lv := slog.Level(level * 4)
+	if !l.logger.Enabled(ctx, lv) {
+		return
+	}
+	attrs := make([]slog.Attr, len(fields))
+	for i, field := range fields {
+		attrs[i] = slog.Any(field.Key, field.Value)
+	}
+	l.logger.LogAttrs(ctx, lv, msg, attrs...)
+}
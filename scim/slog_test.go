@@ -0,0 +1,23 @@
+//go:build go1.21
+// +build go1.21
+
+package scim
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger_Log(t *testing.T) {
+	ctx := context.Background()
+	buf := &bytes.Buffer{}
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	l.Log(ctx, LogLevelDebug, "request body", LogField{Key: "body", Value: "{}"})
+	require.Equal(t, "", buf.String())
+	l.Log(ctx, LogLevelWarn, "error", LogField{Key: "status", Value: 404}, LogField{Key: "operation", Value: "GetUser"})
+	require.Contains(t, buf.String(), "level=WARN msg=error status=404 operation=GetUser\n")
+}
@@ -0,0 +1,146 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// TokenSource provides a Slack API token to Client.
+	// Client calls Token before every request, so the implementation should cache the token if getting it is expensive.
+	// If the API returns 401, Client calls Refresh once and retries the request with the refreshed token.
+	TokenSource interface {
+		Token(ctx context.Context) (string, error)
+		Refresh(ctx context.Context) (string, error)
+	}
+
+	// StaticTokenSource is a TokenSource which always returns the same token.
+	StaticTokenSource struct {
+		token string
+	}
+
+	// FileTokenSource is a TokenSource which reads a token from a file.
+	// The file is read again when its modification time is changed.
+	// Leading and trailing white spaces of the file content are trimmed.
+	FileTokenSource struct {
+		path    string
+		token   string
+		modTime time.Time
+		mutex   sync.Mutex
+	}
+
+	// CommandTokenSource is a TokenSource which gets a token from the standard output of an external command.
+	// The command is run at the first call of Token and when the token is refreshed.
+	// Leading and trailing white spaces of the output are trimmed.
+	CommandTokenSource struct {
+		name   string
+		args   []string
+		token  string
+		loaded bool
+		mutex  sync.Mutex
+	}
+)
+
+// NewStaticTokenSource returns a TokenSource which always returns token.
+func NewStaticTokenSource(token string) *StaticTokenSource {
+	return &StaticTokenSource{token: token}
+}
+
+// Token returns the token.
+func (src *StaticTokenSource) Token(ctx context.Context) (string, error) {
+	return src.token, nil
+}
+
+// Refresh returns the token.
+// The static token can't be rotated, so the returned token is same as Token's one.
+func (src *StaticTokenSource) Refresh(ctx context.Context) (string, error) {
+	return src.token, nil
+}
+
+// NewFileTokenSource returns a TokenSource which reads a token from the file path.
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{path: path}
+}
+
+// Token returns the token read from the file.
+// If the file's modification time isn't changed since the last read, the cached token is returned.
+func (src *FileTokenSource) Token(ctx context.Context) (string, error) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+	fi, err := os.Stat(src.path)
+	if err != nil {
+		return "", err
+	}
+	if src.token != "" && fi.ModTime().Equal(src.modTime) {
+		return src.token, nil
+	}
+	return src.read(fi.ModTime())
+}
+
+// Refresh reads the file again regardless of its modification time and returns the token.
+func (src *FileTokenSource) Refresh(ctx context.Context) (string, error) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+	fi, err := os.Stat(src.path)
+	if err != nil {
+		return "", err
+	}
+	return src.read(fi.ModTime())
+}
+
+func (src *FileTokenSource) read(modTime time.Time) (string, error) {
+	b, err := ioutil.ReadFile(src.path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file is empty: %s", src.path)
+	}
+	src.token = token
+	src.modTime = modTime
+	return token, nil
+}
+
+// NewCommandTokenSource returns a TokenSource which runs the command name with args and uses its output as a token.
+func NewCommandTokenSource(name string, args ...string) *CommandTokenSource {
+	return &CommandTokenSource{name: name, args: args}
+}
+
+// Token returns the cached token.
+// If the command has never been run, Token runs it.
+func (src *CommandTokenSource) Token(ctx context.Context) (string, error) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+	if src.loaded {
+		return src.token, nil
+	}
+	return src.run(ctx)
+}
+
+// Refresh runs the command again and returns the new token.
+func (src *CommandTokenSource) Refresh(ctx context.Context) (string, error) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+	return src.run(ctx)
+}
+
+func (src *CommandTokenSource) run(ctx context.Context) (string, error) {
+	b, err := exec.CommandContext(ctx, src.name, src.args...).Output()
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("command outputs an empty token: %s", src.name)
+	}
+	src.token = token
+	src.loaded = true
+	return token, nil
+}
@@ -0,0 +1,76 @@
+package scim
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticTokenSource(t *testing.T) {
+	ctx := context.Background()
+	src := NewStaticTokenSource("XXX")
+	token, err := src.Token(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "XXX", token)
+	token, err = src.Refresh(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "XXX", token)
+}
+
+func TestFileTokenSource(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "token")
+
+	src := NewFileTokenSource(p)
+	_, err = src.Token(ctx)
+	require.NotNil(t, err)
+
+	require.Nil(t, ioutil.WriteFile(p, []byte("XXX\n"), 0600))
+	token, err := src.Token(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "XXX", token)
+
+	require.Nil(t, ioutil.WriteFile(p, []byte("YYY\n"), 0600))
+	mtime := time.Now().Add(time.Hour)
+	require.Nil(t, os.Chtimes(p, mtime, mtime))
+	token, err = src.Token(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "YYY", token)
+
+	require.Nil(t, ioutil.WriteFile(p, []byte("ZZZ"), 0600))
+	require.Nil(t, os.Chtimes(p, mtime, mtime))
+	token, err = src.Token(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "YYY", token)
+	token, err = src.Refresh(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "ZZZ", token)
+
+	require.Nil(t, ioutil.WriteFile(p, []byte(" \n"), 0600))
+	_, err = src.Refresh(ctx)
+	require.NotNil(t, err)
+}
+
+func TestCommandTokenSource(t *testing.T) {
+	ctx := context.Background()
+	src := NewCommandTokenSource("echo", "XXX")
+	token, err := src.Token(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "XXX", token)
+	token, err = src.Refresh(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "XXX", token)
+
+	_, err = NewCommandTokenSource("true").Token(ctx)
+	require.NotNil(t, err)
+	_, err = NewCommandTokenSource("false").Token(ctx)
+	require.NotNil(t, err)
+}
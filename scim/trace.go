@@ -0,0 +1,304 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AttributeOperation is the span attribute of the client's method name such as "GetUsers".
+	AttributeOperation = "scim.operation"
+	// AttributeResourceType is the span attribute of the resource type such as "User" and "Group".
+	AttributeResourceType = "scim.resource_type"
+	// AttributeResourceID is the span attribute of the id of the user or group.
+	AttributeResourceID = "scim.resource_id"
+	// AttributeCount is the span attribute of the pagination count.
+	AttributeCount = "scim.count"
+	// AttributeStartIndex is the span attribute of the pagination startIndex.
+	AttributeStartIndex = "scim.start_index"
+	// AttributeFilter is the span attribute of the filter whose values are redacted.
+	AttributeFilter = "scim.filter"
+	// AttributeAttempt is the span attribute of the attempt number of the request. The first attempt is 1.
+	AttributeAttempt = "scim.attempt"
+	// AttributeRetryReason is the span attribute of the reason of the retry such as RetryReasonTokenRefreshed .
+	AttributeRetryReason = "scim.retry_reason"
+	// AttributeErrorCode is the span attribute of the code of Slack's error response body.
+	AttributeErrorCode = "scim.error_code"
+	// AttributeHTTPMethod is the span attribute of the HTTP method.
+	AttributeHTTPMethod = "http.method"
+	// AttributeHTTPStatusCode is the span attribute of the HTTP status code.
+	AttributeHTTPStatusCode = "http.status_code"
+)
+
+type (
+	// Tracer starts spans of API calls.
+	// Tracer is an interface so that the client doesn't depend on a tracing library.
+	// To use OpenTelemetry, wrap trace.Tracer with a few lines. See README for an example.
+	//
+	// The client starts a span per method call, named after the method such as "GetUsers",
+	// and a child span per HTTP request named such as "HTTP GET".
+	// Requests which the method sends internally, such as GET requests of Mutation.PreImage and pages of Client.GetAllUsers ,
+	// are nested in the method's span.
+	// If the request is retried, the retry is another child span with AttributeRetryReason .
+	// Spans are started with the context passed to the method, so the trace context propagates from the caller,
+	// and the context of the child span is passed to http.Client so that spans of the HTTP transport are nested.
+	Tracer interface {
+		Start(ctx context.Context, name string) (context.Context, Span)
+	}
+
+	// Span is a span started by Tracer .
+	Span interface {
+		SetAttributes(attrs ...SpanAttribute)
+		// SetError marks the span as failed. SetError is called with a network error or an error response.
+		SetError(err error)
+		End()
+	}
+
+	// SpanAttribute is a key value pair of a span.
+	// Value is a string, an int, or a bool.
+	// Values which may contain personal information, such as values of filters, are redacted.
+	SpanAttribute struct {
+		Key   string
+		Value interface{}
+	}
+
+	// methodSpan is the span of the client's method which may send multiple requests.
+	methodSpan struct {
+		operation string
+		span      Span
+		// reused is true if the request of the method reuses the span.
+		reused bool
+	}
+
+	methodSpanKey struct{}
+
+	// redactedError is an error whose message has the request URL redacted.
+	// err is the *url.Error whose URL is redacted.
+	redactedError struct {
+		msg string
+		err error
+	}
+
+	nopSpan struct{}
+)
+
+func (nopSpan) SetAttributes(attrs ...SpanAttribute) {}
+
+func (nopSpan) SetError(err error) {}
+
+func (nopSpan) End() {}
+
+// startSpan starts a span. If the client has no tracer, a no-op span is returned.
+func (c *Client) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return c.tracer.Start(ctx, name)
+}
+
+// startMethodSpan starts the span of the client's method which may send multiple requests,
+// such as GET requests of Mutation.PreImage and the follow-up request of Client.PatchGroup .
+// Requests sent in the method are nested in the span, and the request of the method itself reuses the span.
+// The span should be ended by methodSpan.end . If the client has no tracer, nil is returned.
+func (c *Client) startMethodSpan(ctx context.Context, operation string) (context.Context, *methodSpan) {
+	if c.tracer == nil {
+		return ctx, nil
+	}
+	ctx, span := c.tracer.Start(ctx, operation)
+	span.SetAttributes(SpanAttribute{Key: AttributeOperation, Value: operation})
+	ms := &methodSpan{
+		operation: operation,
+		span:      span,
+	}
+	return context.WithValue(ctx, methodSpanKey{}, ms), ms
+}
+
+// end ends the span. If err isn't nil, the span is marked as failed.
+func (ms *methodSpan) end(err error) {
+	if ms == nil {
+		return
+	}
+	if err != nil {
+		ms.span.SetError(redactURLError(err))
+	}
+	ms.span.End()
+}
+
+// startOperationSpan starts the span of the request with attributes of the resource and the query.
+// If the request is sent by the method whose span is started by startMethodSpan, the method's span is reused,
+// and the returned bool is false, which means the span shouldn't be ended.
+func (c *Client) startOperationSpan(
+	ctx context.Context, operation, method, path string, query url.Values,
+) (context.Context, Span, bool) {
+	if c.tracer == nil {
+		return ctx, nopSpan{}, false
+	}
+	var span Span
+	owned := true
+	if ms, ok := ctx.Value(methodSpanKey{}).(*methodSpan); ok && ms.operation == operation && !ms.reused {
+		ms.reused = true
+		span = ms.span
+		owned = false
+	} else {
+		ctx, span = c.tracer.Start(ctx, operation)
+	}
+	attrs := []SpanAttribute{
+		{Key: AttributeOperation, Value: operation},
+		{Key: AttributeHTTPMethod, Value: method},
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch segments[0] {
+	case "Users":
+		attrs = append(attrs, SpanAttribute{Key: AttributeResourceType, Value: ResourceTypeUser})
+	case "Groups":
+		attrs = append(attrs, SpanAttribute{Key: AttributeResourceType, Value: ResourceTypeGroup})
+	case "Schemas":
+		attrs = append(attrs, SpanAttribute{Key: AttributeResourceType, Value: "Schema"})
+	case "ServiceProviderConfigs":
+		attrs = append(attrs, SpanAttribute{Key: AttributeResourceType, Value: "ServiceProviderConfig"})
+	}
+	if len(segments) == 2 && segments[0] != "Schemas" {
+		attrs = append(attrs, SpanAttribute{Key: AttributeResourceID, Value: segments[1]})
+	}
+	if v, err := strconv.Atoi(query.Get("count")); err == nil {
+		attrs = append(attrs, SpanAttribute{Key: AttributeCount, Value: v})
+	}
+	if v, err := strconv.Atoi(query.Get("startIndex")); err == nil {
+		attrs = append(attrs, SpanAttribute{Key: AttributeStartIndex, Value: v})
+	}
+	if filter := query.Get("filter"); filter != "" {
+		attrs = append(attrs, SpanAttribute{Key: AttributeFilter, Value: RedactFilter(filter)})
+	}
+	span.SetAttributes(attrs...)
+	return ctx, span, owned
+}
+
+// tracedDo sends the request in a child span.
+// attempt is the attempt number starting with 1, and retryReason is the reason of the retry if attempt is not 1.
+func (c *Client) tracedDo(
+	ctx context.Context, operation, method, endpoint string, body []byte, token string, attempt int, retryReason string,
+) (*http.Response, error) {
+	if c.tracer == nil {
+		return c.observedDo(ctx, operation, method, endpoint, body, token)
+	}
+	ctx, span := c.tracer.Start(ctx, "HTTP "+method)
+	attrs := []SpanAttribute{
+		{Key: AttributeHTTPMethod, Value: method},
+		{Key: AttributeAttempt, Value: attempt},
+	}
+	if retryReason != "" {
+		attrs = append(attrs, SpanAttribute{Key: AttributeRetryReason, Value: retryReason})
+	}
+	span.SetAttributes(attrs...)
+	resp, err := c.observedDo(ctx, operation, method, endpoint, body, token)
+	setSpanResult(span, resp, err, c.isError)
+	span.End()
+	return resp, err
+}
+
+// setSpanResult sets the result of the request to the span.
+func setSpanResult(span Span, resp *http.Response, err error, isError IsError) {
+	if err != nil {
+		span.SetError(redactURLError(err))
+		return
+	}
+	if resp == nil {
+		return
+	}
+	span.SetAttributes(SpanAttribute{Key: AttributeHTTPStatusCode, Value: resp.StatusCode})
+	if !isError(resp) {
+		return
+	}
+	if code := peekErrorCode(resp); code != 0 {
+		span.SetAttributes(SpanAttribute{Key: AttributeErrorCode, Value: code})
+	}
+	span.SetError(fmt.Errorf("the API returned the error response: %s", resp.Status))
+}
+
+// RedactFilter replaces string values of the filter with AuditRedacted,
+// so that the filter can be recorded without personal information such as emails.
+// For example, `userName eq "alice"` is redacted to `userName eq "REDACTED"` .
+func RedactFilter(filter string) string {
+	b := &strings.Builder{}
+	inString := false
+	escaped := false
+	for _, r := range filter {
+		if !inString {
+			b.WriteRune(r)
+			if r == '"' {
+				inString = true
+				b.WriteString(AuditRedacted)
+			}
+			continue
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inString = false
+			b.WriteRune(r)
+		}
+	}
+	if inString {
+		b.WriteRune('"')
+	}
+	return b.String()
+}
+
+// redactURLError returns a copy of err whose request URL has the filter redacted by RedactFilter .
+// Errors of *http.Client.Do are *url.Error, whose message contains the full request URL including the filter.
+// If err doesn't wrap *url.Error, err is returned as is.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	redacted := &url.Error{
+		Op:  urlErr.Op,
+		URL: redactURL(urlErr.URL),
+		Err: urlErr.Err,
+	}
+	if err == error(urlErr) {
+		return redacted
+	}
+	return &redactedError{
+		msg: strings.ReplaceAll(err.Error(), urlErr.Error(), redacted.Error()),
+		err: redacted,
+	}
+}
+
+// Error returns the redacted message.
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the redacted *url.Error, so that the original URL can't be got by errors.As .
+// The cause of *url.Error such as context.DeadlineExceeded is kept.
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactURL redacts the filter of the URL by RedactFilter .
+// If the URL can't be parsed, the query is removed.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		if i := strings.Index(rawURL, "?"); i != -1 {
+			return rawURL[:i]
+		}
+		return rawURL
+	}
+	query := u.Query()
+	if filter := query.Get("filter"); filter != "" {
+		query.Set("filter", RedactFilter(filter))
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
@@ -0,0 +1,262 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (tracer *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &testSpan{name: name, attrs: map[string]interface{}{}}
+	if parent, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		span.parent = parent.name
+	}
+	tracer.spans = append(tracer.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (span *testSpan) SetAttributes(attrs ...SpanAttribute) {
+	for _, attr := range attrs {
+		span.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (span *testSpan) SetError(err error) {
+	span.err = err
+}
+
+func (span *testSpan) End() {
+	span.ended = true
+}
+
+func TestClient_tracer(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.WithValue(context.Background(), testSpanKey{}, &testSpan{name: "root"})
+	tracer := &testTracer{}
+	client := NewClientWithTokenSource(&testTokenSource{tokens: []string{"old", "new"}}).WithTracer(tracer)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `userName eq "alice"`).
+		MatchHeader("Authorization", "Bearer old").
+		Reply(401).
+		BodyString(`{"Errors": {"description": "invalid_authentication", "code": 401}}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchHeader("Authorization", "Bearer new").
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+	_, _, err := client.GetUsers(ctx, &Pagination{Count: 10, StartIndex: 11}, `userName eq "alice"`)
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Len(t, tracer.spans, 3)
+
+	op := tracer.spans[0]
+	require.Equal(t, "GetUsers", op.name)
+	require.Equal(t, "root", op.parent)
+	require.True(t, op.ended)
+	require.Nil(t, op.err)
+	require.Equal(t, map[string]interface{}{
+		AttributeOperation:      "GetUsers",
+		AttributeHTTPMethod:     "GET",
+		AttributeResourceType:   ResourceTypeUser,
+		AttributeCount:          10,
+		AttributeStartIndex:     11,
+		AttributeFilter:         `userName eq "REDACTED"`,
+		AttributeHTTPStatusCode: 200,
+	}, op.attrs)
+
+	first := tracer.spans[1]
+	require.Equal(t, "HTTP GET", first.name)
+	require.Equal(t, "GetUsers", first.parent)
+	require.Equal(t, 1, first.attrs[AttributeAttempt])
+	require.Equal(t, 401, first.attrs[AttributeHTTPStatusCode])
+	require.Equal(t, 401, first.attrs[AttributeErrorCode])
+	require.NotNil(t, first.err)
+
+	retry := tracer.spans[2]
+	require.Equal(t, "GetUsers", retry.parent)
+	require.Equal(t, 2, retry.attrs[AttributeAttempt])
+	require.Equal(t, RetryReasonTokenRefreshed, retry.attrs[AttributeRetryReason])
+	require.Nil(t, retry.err)
+
+	tracer.spans = nil
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Groups/G1").
+		Reply(404).
+		BodyString(`{"Errors": {"description": "not_found", "code": 404}}`)
+	_, err = client.DeleteGroup(ctx, "G1")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "not_found")
+	require.Len(t, tracer.spans, 2)
+	require.Equal(t, "DeleteGroup", tracer.spans[0].name)
+	require.Equal(t, ResourceTypeGroup, tracer.spans[0].attrs[AttributeResourceType])
+	require.Equal(t, "G1", tracer.spans[0].attrs[AttributeResourceID])
+	require.Equal(t, 404, tracer.spans[0].attrs[AttributeErrorCode])
+	require.NotNil(t, tracer.spans[0].err)
+}
+
+func TestRedactFilter(t *testing.T) {
+	data := []struct {
+		title  string
+		filter string
+		exp    string
+	}{
+		{"empty", "", ""},
+		{"no string", "active eq true", "active eq true"},
+		{"string", `userName eq "alice"`, `userName eq "REDACTED"`},
+		{"escaped quote", `userName eq "a\"b" and active eq true`, `userName eq "REDACTED" and active eq true`},
+		{"multiple", `emails.value co "a" or title sw "b"`, `emails.value co "REDACTED" or title sw "REDACTED"`},
+		{"unterminated", `userName eq "alice`, `userName eq "REDACTED"`},
+	}
+	for _, d := range data {
+		t.Run(d.title, func(t *testing.T) {
+			require.Equal(t, d.exp, RedactFilter(d.filter))
+		})
+	}
+}
+
+func Test_redactURLError(t *testing.T) {
+	cause := errors.New("connection reset")
+	urlErr := &url.Error{
+		Op:  "Get",
+		URL: `https://api.slack.com/scim/v1/Users?filter=userName+eq+%22alice%22`,
+		Err: cause,
+	}
+	err := redactURLError(fmt.Errorf("failed to get users: %w", urlErr))
+	require.NotContains(t, err.Error(), "alice")
+	require.Contains(t, err.Error(), "failed to get users")
+	// the original URL can't be got by errors.As
+	var e *url.Error
+	require.True(t, errors.As(err, &e))
+	require.NotContains(t, e.URL, "alice")
+	require.True(t, errors.Is(err, cause))
+
+	e, ok := redactURLError(urlErr).(*url.Error)
+	require.True(t, ok)
+	require.NotContains(t, e.URL, "alice")
+
+	require.Equal(t, cause, redactURLError(cause))
+}
+
+func TestClient_tracer_networkError(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	tracer := &testTracer{}
+	hook := &testMetricsHook{}
+	client := NewClient("XXX").WithTracer(tracer).WithMetricsHook(hook)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		ReplyError(errors.New("connection reset"))
+	_, _, err := client.GetUsers(ctx, nil, `userName eq "alice@example.com"`)
+	require.NotNil(t, err)
+	require.True(t, gock.IsDone())
+
+	require.Len(t, tracer.spans, 2)
+	for _, span := range tracer.spans {
+		require.NotNil(t, span.err)
+		require.NotContains(t, span.err.Error(), "alice")
+		require.Contains(t, span.err.Error(), "REDACTED")
+		require.Contains(t, span.err.Error(), "connection reset")
+	}
+	require.Len(t, hook.requests, 1)
+	require.NotContains(t, hook.requests[0].Err.Error(), "alice")
+	require.Contains(t, hook.requests[0].Err.Error(), "connection reset")
+}
+
+func TestClient_tracer_nested(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	tracer := &testTracer{}
+	client := NewClient("XXX").WithTracer(tracer).WithMutationGuard(func(ctx context.Context, mutation *Mutation) error {
+		_, err := mutation.PreImage(ctx)
+		return err
+	})
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users/U1").
+		Reply(200).
+		BodyString(`{"id": "U1", "userName": "alice"}`)
+	gock.New("https://api.slack.com").
+		Delete("/scim/v1/Users/U1").
+		Reply(204)
+	_, err := client.DeleteUser(ctx, "U1")
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	// the request of DeleteUser reuses the span of the method, and the request of the guard is nested in it
+	require.Len(t, tracer.spans, 4)
+	require.Equal(t, "DeleteUser", tracer.spans[0].name)
+	require.Equal(t, "", tracer.spans[0].parent)
+	require.Equal(t, ResourceTypeUser, tracer.spans[0].attrs[AttributeResourceType])
+	require.Equal(t, "GetUser", tracer.spans[1].name)
+	require.Equal(t, "DeleteUser", tracer.spans[1].parent)
+	require.Equal(t, "HTTP GET", tracer.spans[2].name)
+	require.Equal(t, "GetUser", tracer.spans[2].parent)
+	require.Equal(t, "HTTP DELETE", tracer.spans[3].name)
+	require.Equal(t, "DeleteUser", tracer.spans[3].parent)
+	for _, span := range tracer.spans {
+		require.True(t, span.ended)
+	}
+
+	tracer.spans = nil
+	client.SetMutationGuard(nil)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		Reply(204)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups/G1").
+		Reply(200).
+		BodyString(`{"id": "G1", "displayName": "admins"}`)
+	_, _, err = client.PatchGroup(ctx, "G1", &Group{DisplayName: "admins"})
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Len(t, tracer.spans, 4)
+	require.Equal(t, "PatchGroup", tracer.spans[0].name)
+	require.Equal(t, "HTTP PATCH", tracer.spans[1].name)
+	require.Equal(t, "PatchGroup", tracer.spans[1].parent)
+	require.Equal(t, "GetGroup", tracer.spans[2].name)
+	require.Equal(t, "PatchGroup", tracer.spans[2].parent)
+
+	tracer.spans = nil
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("startIndex", "1").
+		Reply(200).
+		BodyString(`{"totalResults": 2, "itemsPerPage": 1, "startIndex": 1, "Resources": [{"id": "U1"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("startIndex", "2").
+		Reply(200).
+		BodyString(`{"totalResults": 2, "itemsPerPage": 1, "startIndex": 2, "Resources": [{"id": "U2"}]}`)
+	users, err := client.GetAllUsers(ctx, "")
+	require.Nil(t, err)
+	require.Len(t, users, 2)
+	require.True(t, gock.IsDone())
+	require.Len(t, tracer.spans, 5)
+	require.Equal(t, "GetAllUsers", tracer.spans[0].name)
+	require.Equal(t, "GetUsers", tracer.spans[1].name)
+	require.Equal(t, "GetAllUsers", tracer.spans[1].parent)
+	require.Equal(t, "GetUsers", tracer.spans[3].name)
+	require.Equal(t, "GetAllUsers", tracer.spans[3].parent)
+}
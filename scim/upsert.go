@@ -0,0 +1,200 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MatchByUserName matches users by userName.
+	MatchByUserName = "userName"
+	// MatchByPrimaryEmail matches users by the primary email.
+	MatchByPrimaryEmail = "email"
+	// MatchByExternalID matches users and groups by externalId.
+	MatchByExternalID = "externalId"
+	// MatchByDisplayName matches groups by displayName.
+	MatchByDisplayName = "displayName"
+
+	// UpsertCreated means the resource is created.
+	UpsertCreated = "created"
+	// UpsertUpdated means the resource is updated.
+	UpsertUpdated = "updated"
+	// UpsertUnchanged means the resource already exists and it has no difference.
+	UpsertUnchanged = "unchanged"
+)
+
+type (
+	// UpsertOption is an option of Client.UpsertUser and Client.UpsertGroup .
+	UpsertOption struct {
+		// MatchKey is an attribute to find the existing resource.
+		// MatchByUserName, MatchByPrimaryEmail and MatchByExternalID are available for users,
+		// and MatchByDisplayName and MatchByExternalID are available for groups.
+		// The default is MatchByUserName for users and MatchByDisplayName for groups.
+		MatchKey string
+	}
+
+	// UserUpsertResult is a result of Client.UpsertUser .
+	UserUpsertResult struct {
+		// Action is UpsertCreated, UpsertUpdated or UpsertUnchanged .
+		Action string
+		User   *User
+		// Patch is a patch applied to the existing user.
+		Patch *UserPatch
+	}
+
+	// GroupUpsertResult is a result of Client.UpsertGroup .
+	GroupUpsertResult struct {
+		// Action is UpsertCreated, UpsertUpdated or UpsertUnchanged .
+		Action string
+		Group  *Group
+		// Patch is a patch applied to the existing group.
+		Patch *Group
+	}
+)
+
+// UpsertUser creates the user if the user matching with opts.MatchKey doesn't exist,
+// and otherwise updates the existing user by PATCH /Users/{id} API with differences computed by DiffUser .
+func (c *Client) UpsertUser(ctx context.Context, user *User, opts *UpsertOption) (_ *UserUpsertResult, err error) {
+	ctx, span := c.startMethodSpan(ctx, "UpsertUser")
+	defer func() {
+		span.end(err)
+	}()
+	if user == nil {
+		return nil, fmt.Errorf("user is required")
+	}
+	if opts == nil {
+		opts = &UpsertOption{}
+	}
+	key := opts.MatchKey
+	if key == "" {
+		key = MatchByUserName
+	}
+	value, err := userMatchValue(user, key)
+	if err != nil {
+		return nil, err
+	}
+	current, _, err := c.findUser(ctx, key, value)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		created, _, err := c.CreateUser(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the user: %w", err)
+		}
+		return &UserUpsertResult{
+			Action: UpsertCreated,
+			User:   created,
+		}, nil
+	}
+	patch := DiffUser(current, user)
+	if patch == nil {
+		return &UserUpsertResult{
+			Action: UpsertUnchanged,
+			User:   current,
+		}, nil
+	}
+	updated, _, err := c.PatchUser(ctx, current.ID, patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update the user %s: %w", current.ID, err)
+	}
+	return &UserUpsertResult{
+		Action: UpsertUpdated,
+		User:   updated,
+		Patch:  patch,
+	}, nil
+}
+
+// UpsertGroup creates the group if the group matching with opts.MatchKey doesn't exist,
+// and otherwise updates the existing group by PATCH /Groups/{id} API with differences computed by DiffGroup .
+func (c *Client) UpsertGroup(ctx context.Context, group *Group, opts *UpsertOption) (_ *GroupUpsertResult, err error) {
+	ctx, span := c.startMethodSpan(ctx, "UpsertGroup")
+	defer func() {
+		span.end(err)
+	}()
+	if group == nil {
+		return nil, fmt.Errorf("group is required")
+	}
+	if opts == nil {
+		opts = &UpsertOption{}
+	}
+	key := opts.MatchKey
+	if key == "" {
+		key = MatchByDisplayName
+	}
+	var value string
+	switch key {
+	case MatchByDisplayName:
+		value = group.DisplayName
+	case MatchByExternalID:
+		value = group.ExternalID
+	default:
+		return nil, fmt.Errorf("invalid match key of groups: %s", key)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("the group's %s is required", key)
+	}
+	current, _, err := c.findGroup(ctx, key, value)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		created, _, err := c.CreateGroup(ctx, group)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the group: %w", err)
+		}
+		return &GroupUpsertResult{
+			Action: UpsertCreated,
+			Group:  created,
+		}, nil
+	}
+	patch := DiffGroup(current, group)
+	if patch == nil {
+		return &GroupUpsertResult{
+			Action: UpsertUnchanged,
+			Group:  current,
+		}, nil
+	}
+	updated, _, err := c.patchGroup(ctx, current.ID, patch, newGroupPatch(patch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update the group %s: %w", current.ID, err)
+	}
+	return &GroupUpsertResult{
+		Action: UpsertUpdated,
+		Group:  updated,
+		Patch:  patch,
+	}, nil
+}
+
+func userMatchValue(user *User, key string) (string, error) {
+	var value string
+	switch key {
+	case MatchByUserName:
+		value = user.UserName
+	case MatchByExternalID:
+		value = user.ExternalID
+	case MatchByPrimaryEmail:
+		value = user.PrimaryEmail()
+	default:
+		return "", fmt.Errorf("invalid match key of users: %s", key)
+	}
+	if value == "" {
+		return "", fmt.Errorf("the user's %s is required", key)
+	}
+	return value, nil
+}
+
+// PrimaryEmail returns the primary email's value.
+// If the user has no primary email, the first email is returned.
+func (user *User) PrimaryEmail() string {
+	for _, email := range user.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(user.Emails) != 0 {
+		return user.Emails[0].Value
+	}
+	return ""
+}
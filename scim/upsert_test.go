@@ -0,0 +1,166 @@
+package scim
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_PrimaryEmail(t *testing.T) {
+	require.Equal(t, "", (&User{}).PrimaryEmail())
+	require.Equal(t, "foo@example.com", (&User{
+		Emails: []Email{{Value: "foo@example.com"}, {Value: "bar@example.com"}},
+	}).PrimaryEmail())
+	require.Equal(t, "bar@example.com", (&User{
+		Emails: []Email{{Value: "foo@example.com"}, {Value: "bar@example.com", Primary: true}},
+	}).PrimaryEmail())
+}
+
+func TestClient_UpsertUser(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	user := &User{
+		UserName: "foo",
+		Title:    "Engineer",
+		Emails:   []Email{{Value: "foo@example.com", Primary: true}},
+	}
+
+	_, err := client.UpsertUser(ctx, nil, nil)
+	require.NotNil(t, err)
+	_, err = client.UpsertUser(ctx, user, &UpsertOption{MatchKey: "displayName"})
+	require.NotNil(t, err)
+	_, err = client.UpsertUser(ctx, user, &UpsertOption{MatchKey: MatchByExternalID})
+	require.NotNil(t, err)
+
+	// created
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `userName eq "foo"`).
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Users").
+		Reply(201).
+		BodyString(`{"id": "U1", "userName": "foo"}`)
+	result, err := client.UpsertUser(ctx, user, nil)
+	require.Nil(t, err)
+	require.Equal(t, UpsertCreated, result.Action)
+	require.Equal(t, "U1", result.User.ID)
+
+	// unchanged
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `email eq "foo@example.com"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [
+  {"id": "U1", "userName": "foo", "title": "Engineer", "emails": [{"value": "foo@example.com", "primary": true}]}
+]}`)
+	result, err = client.UpsertUser(ctx, user, &UpsertOption{MatchKey: MatchByPrimaryEmail})
+	require.Nil(t, err)
+	require.Equal(t, UpsertUnchanged, result.Action)
+	require.Nil(t, result.Patch)
+
+	// updated
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `userName eq "foo"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [
+  {"id": "U1", "userName": "foo", "title": "Manager", "emails": [{"value": "foo@example.com", "primary": true}]}
+]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Users/U1").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore},
+			"title":   "Engineer",
+		}).
+		Reply(200).
+		BodyString(`{"id": "U1", "userName": "foo", "title": "Engineer"}`)
+	result, err = client.UpsertUser(ctx, user, nil)
+	require.Nil(t, err)
+	require.Equal(t, UpsertUpdated, result.Action)
+	require.Equal(t, "Engineer", result.User.Title)
+	require.True(t, gock.IsDone())
+
+	// the search fails
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(500).
+		BodyString(`{"Errors": {"description": "internal error", "code": 500}}`)
+	_, err = client.UpsertUser(ctx, user, nil)
+	require.NotNil(t, err)
+}
+
+func TestClient_UpsertGroup(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	group := &Group{
+		DisplayName: "foo",
+		ExternalID:  "E1",
+		Members:     []Member{{Value: "U1"}},
+	}
+
+	_, err := client.UpsertGroup(ctx, nil, nil)
+	require.NotNil(t, err)
+	_, err = client.UpsertGroup(ctx, group, &UpsertOption{MatchKey: MatchByUserName})
+	require.NotNil(t, err)
+	_, err = client.UpsertGroup(ctx, &Group{}, nil)
+	require.NotNil(t, err)
+
+	// created
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		MatchParam("filter", `displayName eq "foo"`).
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+	gock.New("https://api.slack.com").
+		Post("/scim/v1/Groups").
+		Reply(201).
+		BodyString(`{"id": "G1", "displayName": "foo"}`)
+	result, err := client.UpsertGroup(ctx, group, nil)
+	require.Nil(t, err)
+	require.Equal(t, UpsertCreated, result.Action)
+	require.Equal(t, "G1", result.Group.ID)
+
+	// unchanged
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		MatchParam("filter", `externalId eq "E1"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "G1", "displayName": "foo", "members": [{"value": "U1"}]}]}`)
+	result, err = client.UpsertGroup(ctx, group, &UpsertOption{MatchKey: MatchByExternalID})
+	require.Nil(t, err)
+	require.Equal(t, UpsertUnchanged, result.Action)
+
+	// updated
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		MatchParam("filter", `displayName eq "foo"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "G1", "displayName": "foo", "members": [{"value": "U2"}]}]}`)
+	gock.New("https://api.slack.com").
+		Patch("/scim/v1/Groups/G1").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"schemas": []string{SchemaCore},
+			"members": []map[string]string{
+				{"value": "U1", "display": ""},
+				{"value": "U2", "display": "", "operation": "delete"},
+			},
+		}).
+		Reply(200).
+		BodyString(`{"id": "G1", "displayName": "foo", "members": [{"value": "U1"}]}`)
+	result, err = client.UpsertGroup(ctx, group, nil)
+	require.Nil(t, err)
+	require.Equal(t, UpsertUpdated, result.Action)
+	require.NotNil(t, result.Patch)
+	require.True(t, gock.IsDone())
+}
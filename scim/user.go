@@ -35,6 +35,12 @@ type (
 		Groups                        []Group                        `json:"groups,omitempty"`
 		Schemas                       []string                       `json:"schemas"`
 		EnterpriseUserSchemaExtension *EnterpriseUserSchemaExtension `json:"urn:scim:schemas:extension:enterprise:1.0,omitempty"`
+		SlackGuestUserSchemaExtension *SlackGuestUserSchemaExtension `json:"urn:scim:schemas:extension:slack:guest:1.0,omitempty"`
+		// Extra has attributes which aren't defined in User such as unknown schema extensions.
+		// Extra is kept as raw JSON so that the attributes aren't lost when the user is decoded and encoded again.
+		Extra map[string]json.RawMessage `json:"-"`
+		// Extensions has typed schema extensions registered by RegisterUserExtension .
+		Extensions map[string]interface{} `json:"-"`
 	}
 
 	// EnterpriseUserSchemaExtension is SCIM Enterprise User Schema Extension.
@@ -47,6 +53,15 @@ type (
 		Manager        *Manager `json:"manager,omitempty"`
 	}
 
+	// SlackGuestUserSchemaExtension is Slack's guest extension.
+	// The user who has this extension is a multi-channel or single-channel guest.
+	// Type is GuestTypeMultiChannel or GuestTypeSingleChannel .
+	// Expiration is a date time formatted in RFC3339 when the guest account is deactivated.
+	SlackGuestUserSchemaExtension struct {
+		Type       string `json:"type,omitempty"`
+		Expiration string `json:"expiration,omitempty"`
+	}
+
 	// Manager is a user's manager.
 	Manager struct {
 		ManagerID   string `json:"managerId,omitempty"`
@@ -123,7 +138,7 @@ func (c *Client) GetUsersResp(ctx context.Context, page *Pagination, filter stri
 		query.Add("filter", filter)
 	}
 	setPageToQuery(page, query)
-	return c.getResp(ctx, "GET", "/Users", nil, query)
+	return c.getResp(ctx, "GetUsers", "GET", "/Users", nil, query)
 }
 
 // GetUsers calls GET /Users API and returns users.
@@ -149,7 +164,7 @@ func (c *Client) GetUserResp(ctx context.Context, id string) (*http.Response, er
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	return c.getResp(ctx, "GET", fmt.Sprintf("/Users/%s", id), nil, nil)
+	return c.getResp(ctx, "GetUser", "GET", fmt.Sprintf("/Users/%s", id), nil, nil)
 }
 
 // GetUser calls GET /Users/{id} API and returns a user.
@@ -170,31 +185,62 @@ func (c *Client) GetUser(
 // CreateUserResp calls POST /Users API and returns a HTTP response.
 // If the returned error is nil, the returned response isn't nil and you have to close the response body.
 // Internally, this method returns the returned values of *http.Client.Do .
+// The mutation guard is called before the request is sent, and if the guard returns an error the request isn't sent.
 func (c *Client) CreateUserResp(ctx context.Context, user *User) (*http.Response, error) {
+	ctx, mutation := c.newMutation(ctx, MutationCreate, ResourceTypeUser, "", user)
+	return c.guardedResp(ctx, mutation, func(ctx context.Context) (*http.Response, error) {
+		return c.createUserResp(ctx, user)
+	})
+}
+
+// createUserResp sends the request without the mutation guard.
+func (c *Client) createUserResp(ctx context.Context, user *User) (*http.Response, error) {
 	// POST /Users
 	if user == nil {
 		return nil, fmt.Errorf("user is required")
 	}
-	return c.getResp(ctx, "POST", "/Users", user, nil)
+	return c.getResp(ctx, "CreateUser", "POST", "/Users", user, nil)
 }
 
 // CreateUser calls POST /Users API and returns a created user.
 // The returned response body is closed.
 func (c *Client) CreateUser(ctx context.Context, user *User) (*User, *http.Response, error) {
 	// POST /Users
-	resp, err := c.CreateUserResp(ctx, user)
+	ctx, mutation := c.newMutation(ctx, MutationCreate, ResourceTypeUser, "", user)
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.createUserResp(ctx, user)
 	if err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
 		return nil, resp, err
 	}
 	defer resp.Body.Close()
 	u := &User{}
-	return u, resp, c.parseResponse(resp, u)
+	if err := c.parseResponse(resp, u); err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return u, resp, err
+	}
+	mutation.ResourceID = u.ID
+	mutation.Result = u
+	c.afterMutation(ctx, mutation, resp, nil)
+	return u, resp, nil
 }
 
 // PatchUserResp calls PATCH /Users/{id} API and returns a HTTP response.
 // If the returned error is nil, the returned response isn't nil and you have to close the response body.
 // Internally, this method returns the returned values of *http.Client.Do .
+// The mutation guard is called before the request is sent, and if the guard returns an error the request isn't sent.
 func (c *Client) PatchUserResp(ctx context.Context, id string, user *UserPatch) (*http.Response, error) {
+	ctx, mutation := c.newMutation(ctx, MutationPatch, ResourceTypeUser, id, user)
+	return c.guardedResp(ctx, mutation, func(ctx context.Context) (*http.Response, error) {
+		return c.patchUserResp(ctx, id, user, user)
+	})
+}
+
+// patchUserResp sends the request whose body is body without the mutation guard.
+// body is user or another request body which is equivalent to user.
+func (c *Client) patchUserResp(ctx context.Context, id string, user *UserPatch, body interface{}) (*http.Response, error) {
 	// PATCH /Users/{id}
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
@@ -202,26 +248,52 @@ func (c *Client) PatchUserResp(ctx context.Context, id string, user *UserPatch)
 	if user == nil {
 		return nil, fmt.Errorf("user is required")
 	}
-	return c.getResp(ctx, "PATCH", fmt.Sprintf("/Users/%s", id), user, nil)
+	return c.getResp(ctx, "PatchUser", "PATCH", fmt.Sprintf("/Users/%s", id), body, nil)
 }
 
 // PatchUser calls PATCH /Users/{id} API and returns a updated user.
 // The returned response body is closed.
 func (c *Client) PatchUser(ctx context.Context, id string, user *UserPatch) (*User, *http.Response, error) {
+	return c.patchUser(ctx, id, user, user)
+}
+
+// patchUser is Client.PatchUser whose request body is body.
+// body is user or another request body which is equivalent to user.
+func (c *Client) patchUser(ctx context.Context, id string, user *UserPatch, body interface{}) (*User, *http.Response, error) {
 	// PATCH /Users/{id}
-	resp, err := c.PatchUserResp(ctx, id, user)
+	ctx, mutation := c.newMutation(ctx, MutationPatch, ResourceTypeUser, id, user)
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.patchUserResp(ctx, id, user, body)
 	if err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
 		return nil, resp, err
 	}
 	defer resp.Body.Close()
 	u := &User{}
-	return u, resp, c.parseResponse(resp, u)
+	if err := c.parseResponse(resp, u); err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return u, resp, err
+	}
+	mutation.Result = u
+	c.afterMutation(ctx, mutation, resp, nil)
+	return u, resp, nil
 }
 
 // PutUserResp calls PUT /Users/{id} API and returns a HTTP response.
 // If the returned error is nil, the returned response isn't nil and you have to close the response body.
 // Internally, this method returns the returned values of *http.Client.Do .
+// The mutation guard is called before the request is sent, and if the guard returns an error the request isn't sent.
 func (c *Client) PutUserResp(ctx context.Context, id string, user *User) (*http.Response, error) {
+	ctx, mutation := c.newMutation(ctx, MutationPut, ResourceTypeUser, id, user)
+	return c.guardedResp(ctx, mutation, func(ctx context.Context) (*http.Response, error) {
+		return c.putUserResp(ctx, id, user)
+	})
+}
+
+// putUserResp sends the request without the mutation guard.
+func (c *Client) putUserResp(ctx context.Context, id string, user *User) (*http.Response, error) {
 	// PUT /Users/{id}
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
@@ -229,43 +301,73 @@ func (c *Client) PutUserResp(ctx context.Context, id string, user *User) (*http.
 	if user == nil {
 		return nil, fmt.Errorf("user is required")
 	}
-	return c.getResp(ctx, "PUT", fmt.Sprintf("/Users/%s", id), user, nil)
+	return c.getResp(ctx, "PutUser", "PUT", fmt.Sprintf("/Users/%s", id), user, nil)
 }
 
 // PutUser calls PUT /Users/{id} API and returns a updated user.
 // The returned response body is closed.
 func (c *Client) PutUser(ctx context.Context, id string, user *User) (*User, *http.Response, error) {
 	// PUT /Users/{id}
-	resp, err := c.PutUserResp(ctx, id, user)
+	ctx, mutation := c.newMutation(ctx, MutationPut, ResourceTypeUser, id, user)
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.putUserResp(ctx, id, user)
 	if err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
 		return nil, resp, err
 	}
 	defer resp.Body.Close()
 	u := &User{}
-	return u, resp, c.parseResponse(resp, u)
+	if err := c.parseResponse(resp, u); err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return u, resp, err
+	}
+	mutation.Result = u
+	c.afterMutation(ctx, mutation, resp, nil)
+	return u, resp, nil
 }
 
 // DeleteUserResp calls DELETE /Users/{id} API and returns a HTTP response.
 // If the returned error is nil, the returned response isn't nil and you have to close the response body.
 // Internally, this method returns the returned values of *http.Client.Do .
+// The mutation guard is called before the request is sent, and if the guard returns an error the request isn't sent.
 func (c *Client) DeleteUserResp(ctx context.Context, id string) (*http.Response, error) {
+	ctx, mutation := c.newMutation(ctx, MutationDelete, ResourceTypeUser, id, nil)
+	return c.guardedResp(ctx, mutation, func(ctx context.Context) (*http.Response, error) {
+		return c.deleteUserResp(ctx, id)
+	})
+}
+
+// deleteUserResp sends the request without the mutation guard.
+func (c *Client) deleteUserResp(ctx context.Context, id string) (*http.Response, error) {
 	// DELETE /Users/{id}
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	return c.getResp(ctx, "DELETE", fmt.Sprintf("/Users/%s", id), nil, nil)
+	return c.getResp(ctx, "DeleteUser", "DELETE", fmt.Sprintf("/Users/%s", id), nil, nil)
 }
 
 // DeleteUser calls DELETE /Users/{id} API.
 // The returned response body is closed.
 func (c *Client) DeleteUser(ctx context.Context, id string) (*http.Response, error) {
 	// DELETE /Users/{id}
-	resp, err := c.DeleteUserResp(ctx, id)
+	ctx, mutation := c.newMutation(ctx, MutationDelete, ResourceTypeUser, id, nil)
+	if err := c.beforeMutation(ctx, mutation); err != nil {
+		return nil, err
+	}
+	resp, err := c.deleteUserResp(ctx, id)
 	if err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
 		return resp, err
 	}
 	defer resp.Body.Close()
-	return resp, c.parseResponse(resp, nil)
+	if err := c.parseResponse(resp, nil); err != nil {
+		c.afterMutation(ctx, mutation, resp, err)
+		return resp, err
+	}
+	c.afterMutation(ctx, mutation, resp, nil)
+	return resp, nil
 }
 
 // UnmarshalJSON implements json.Unmarshaler .
@@ -280,6 +382,12 @@ func (user *User) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(b, &a); err != nil {
 		return err
 	}
+	extra, extensions, err := decodeExtraAttributes(b, userKnownAttributes, userExtensions)
+	if err != nil {
+		return err
+	}
+	user.Extra = extra
+	user.Extensions = extensions
 	if len(a.Groups) == 0 {
 		return nil
 	}
@@ -316,5 +424,26 @@ func (user *User) MarshalJSON() ([]byte, error) {
 		Groups: list,
 		alias:  (*alias)(user),
 	}
-	return json.Marshal(a)
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return encodeExtraAttributes(b, userKnownAttributes, user.Extra, user.Extensions)
+}
+
+// DecodeExtension decodes the attribute urn kept in Extra or Extensions to v.
+// If the user doesn't have the attribute, the second returned value is false.
+func (user *User) DecodeExtension(urn string, v interface{}) (bool, error) {
+	return decodeExtension(user.Extra, user.Extensions, urn, v)
+}
+
+// SetExtension sets v to Extensions[urn] .
+// The schema urn is added to Schemas.
+func (user *User) SetExtension(urn string, v interface{}) {
+	if user.Extensions == nil {
+		user.Extensions = map[string]interface{}{}
+	}
+	user.Extensions[urn] = v
+	delete(user.Extra, urn)
+	user.Schemas = appendSchema(user.Schemas, urn)
 }
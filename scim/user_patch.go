@@ -26,6 +26,7 @@ type (
 		Groups                        *[]Group                            `json:"groups,omitempty"`
 		Schemas                       []string                            `json:"schemas"`
 		EnterpriseUserSchemaExtension *EnterpriseUserSchemaExtensionPatch `json:"urn:scim:schemas:extension:enterprise:1.0,omitempty"`
+		SlackGuestUserSchemaExtension *SlackGuestUserSchemaExtensionPatch `json:"urn:scim:schemas:extension:slack:guest:1.0,omitempty"`
 	}
 
 	// EnterpriseUserSchemaExtensionPatch is a SCIM Enterprise User Schema Extension for PATH user API's request.
@@ -38,6 +39,12 @@ type (
 		Manager        *Manager `json:"manager,omitempty"`
 	}
 
+	// SlackGuestUserSchemaExtensionPatch is a Slack guest extension for PATCH user API's request.
+	SlackGuestUserSchemaExtensionPatch struct {
+		Type       *string `json:"type,omitempty"`
+		Expiration *string `json:"expiration,omitempty"`
+	}
+
 	// NamePatch is a user name patch for PATCH user API's request.
 	NamePatch struct {
 		FamilyName      *string `json:"familyName,omitempty"`
@@ -10,7 +10,7 @@ import (
 // Internally, this method returns the returned values of *http.Client.Do .
 func (c *Client) GetUserSchemaResp(ctx context.Context) (*http.Response, error) {
 	// GET /Schemas/Users
-	return c.getResp(ctx, "GET", "/Schemas/Users", nil, nil)
+	return c.getResp(ctx, "GetUserSchema", "GET", "/Schemas/Users", nil, nil)
 }
 
 // GetUserSchema calls GET /Schemas/Users API and returns a user schema.
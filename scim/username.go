@@ -0,0 +1,267 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultUserNameMaxLength is the default maximum length of generated userNames.
+	DefaultUserNameMaxLength = 21
+	// DefaultUserNameMaxNumber is the default maximum number of numbered variants such as "alice.smith2".
+	DefaultUserNameMaxNumber = 99
+	// DefaultUserNameRandomAttempts is the default number of random variants tried after numbered variants.
+	DefaultUserNameRandomAttempts = 10
+)
+
+type (
+	// UserNameChecker checks whether a userName isn't used.
+	// Client and Directory implement UserNameChecker.
+	UserNameChecker interface {
+		UserNameAvailable(ctx context.Context, userName string) (bool, error)
+	}
+
+	// UserNameCheckerFunc is a function which implements UserNameChecker.
+	UserNameCheckerFunc func(ctx context.Context, userName string) (bool, error)
+
+	// UserNameGeneratorConfig is a configuration of UserNameGenerator .
+	UserNameGeneratorConfig struct {
+		// Patterns are templates of candidates tried in order.
+		// The placeholders {given} and {family} are replaced with Name.GivenName and Name.FamilyName,
+		// and {g} and {f} are replaced with their first letters.
+		// A pattern is skipped if its placeholders are empty.
+		// If Patterns is empty, DefaultUserNamePatterns is used.
+		Patterns []string `json:"patterns,omitempty"`
+		// MaxLength is the maximum length of userNames. If MaxLength is zero, DefaultUserNameMaxLength is used.
+		MaxLength int `json:"maxLength,omitempty"`
+		// MaxNumber is the maximum number of numbered variants. If MaxNumber is zero, DefaultUserNameMaxNumber is used.
+		MaxNumber int `json:"maxNumber,omitempty"`
+		// RandomAttempts is the number of variants with random suffixes tried after numbered variants.
+		// If RandomAttempts is zero, DefaultUserNameRandomAttempts is used.
+		RandomAttempts int `json:"randomAttempts,omitempty"`
+		// Seed is the seed of random suffixes. The same user and seed generate the same candidates.
+		Seed int64 `json:"seed,omitempty"`
+	}
+
+	// UserNameGenerator generates unique userNames from users' names.
+	// UserNameGenerator should be created by the function NewUserNameGenerator .
+	// Generated userNames are reserved, so a generator never returns the same userName twice.
+	// UserNameGenerator is safe for concurrent use.
+	UserNameGenerator struct {
+		config   UserNameGeneratorConfig
+		checker  UserNameChecker
+		reserved map[string]struct{}
+		mutex    sync.Mutex
+	}
+)
+
+var (
+	// DefaultUserNamePatterns is the default patterns of UserNameGenerator .
+	DefaultUserNamePatterns = []string{"{given}.{family}", "{g}{family}", "{given}{f}", "{given}"}
+
+	// ErrUserNameExhausted is returned when all candidates are used.
+	ErrUserNameExhausted = errors.New("all candidates of the userName are used")
+
+	userNameTransliterator = strings.NewReplacer(
+		"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+		"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+		"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+		"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+		"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss", "ł", "l",
+	)
+)
+
+// UserNameAvailable calls the function.
+func (fn UserNameCheckerFunc) UserNameAvailable(ctx context.Context, userName string) (bool, error) {
+	return fn(ctx, userName)
+}
+
+// UserNameAvailable calls GET /Users API with the filter `userName eq "{userName}"` and returns true if no user is found.
+func (c *Client) UserNameAvailable(ctx context.Context, userName string) (bool, error) {
+	users, _, err := c.GetUsers(ctx, nil, eqFilter("userName", userName))
+	if err != nil {
+		return false, err
+	}
+	return len(users.Resources) == 0, nil
+}
+
+// UserNameAvailable returns true if no user in the directory has the userName.
+func (dir *Directory) UserNameAvailable(ctx context.Context, userName string) (bool, error) {
+	_, ok := dir.UserByUserName(userName)
+	return !ok, nil
+}
+
+// NewUserNameGenerator returns a generator which checks candidates by checker.
+func NewUserNameGenerator(checker UserNameChecker, cfg *UserNameGeneratorConfig) *UserNameGenerator {
+	if cfg == nil {
+		cfg = &UserNameGeneratorConfig{}
+	}
+	config := *cfg
+	if len(config.Patterns) == 0 {
+		config.Patterns = DefaultUserNamePatterns
+	}
+	if config.MaxLength <= 0 {
+		config.MaxLength = DefaultUserNameMaxLength
+	}
+	if config.MaxNumber <= 0 {
+		config.MaxNumber = DefaultUserNameMaxNumber
+	}
+	if config.RandomAttempts <= 0 {
+		config.RandomAttempts = DefaultUserNameRandomAttempts
+	}
+	return &UserNameGenerator{
+		config:   config,
+		checker:  checker,
+		reserved: map[string]struct{}{},
+	}
+}
+
+// SanitizeUserName converts s to a userName which Slack accepts.
+// Letters are lowercased and common accented letters are transliterated,
+// spaces are replaced with periods, and characters other than a-z, 0-9, ".", "-" and "_" are removed.
+// Leading and trailing punctuations are removed, and the result is cut to maxLength if maxLength is positive.
+func SanitizeUserName(s string, maxLength int) string {
+	s = userNameTransliterator.Replace(strings.ToLower(s))
+	b := strings.Builder{}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('.')
+		}
+	}
+	s = strings.Trim(b.String(), ".-_")
+	if maxLength > 0 && len(s) > maxLength {
+		s = strings.TrimRight(s[:maxLength], ".-_")
+	}
+	return s
+}
+
+// Candidates returns candidates of the user's userName by the patterns in order, without numbered and random variants.
+// Duplicated candidates are removed.
+func (gen *UserNameGenerator) Candidates(user *User) []string {
+	var given, family string
+	if user.Name != nil {
+		given = user.Name.GivenName
+		family = user.Name.FamilyName
+	}
+	parts := map[string]string{
+		"{given}":  SanitizeUserName(given, 0),
+		"{family}": SanitizeUserName(family, 0),
+	}
+	parts["{g}"] = firstLetter(parts["{given}"])
+	parts["{f}"] = firstLetter(parts["{family}"])
+
+	candidates := []string{}
+	seen := map[string]struct{}{}
+	for _, pattern := range gen.config.Patterns {
+		candidate, ok := renderUserNamePattern(pattern, parts)
+		if !ok {
+			continue
+		}
+		candidate = SanitizeUserName(candidate, gen.config.MaxLength)
+		if candidate == "" {
+			continue
+		}
+		if _, ok := seen[candidate]; ok {
+			continue
+		}
+		seen[candidate] = struct{}{}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+func firstLetter(s string) string {
+	if s == "" {
+		return ""
+	}
+	return s[:1]
+}
+
+// renderUserNamePattern replaces placeholders of pattern. If a placeholder in pattern is empty, false is returned.
+func renderUserNamePattern(pattern string, parts map[string]string) (string, bool) {
+	for placeholder, value := range parts {
+		if !strings.Contains(pattern, placeholder) {
+			continue
+		}
+		if value == "" {
+			return "", false
+		}
+		pattern = strings.ReplaceAll(pattern, placeholder, value)
+	}
+	return pattern, true
+}
+
+// Generate returns an available userName of the user and reserves it.
+// Candidates are tried in order, then numbered variants of the first candidate such as "alice.smith2",
+// and then variants with random suffixes generated from Seed.
+// If no candidate is available, ErrUserNameExhausted is returned.
+func (gen *UserNameGenerator) Generate(ctx context.Context, user *User) (string, error) {
+	if user == nil {
+		return "", fmt.Errorf("user is required")
+	}
+	candidates := gen.Candidates(user)
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("the user has no name to generate the userName")
+	}
+	base := candidates[0]
+	for i := 2; i <= gen.config.MaxNumber; i++ {
+		candidates = append(candidates, numberedUserName(base, strconv.Itoa(i), gen.config.MaxLength))
+	}
+	rnd := rand.New(rand.NewSource(gen.config.Seed))
+	for i := 0; i < gen.config.RandomAttempts; i++ {
+		candidates = append(candidates, numberedUserName(base, strconv.Itoa(1000+rnd.Intn(9000)), gen.config.MaxLength))
+	}
+
+	for _, candidate := range candidates {
+		ok, err := gen.reserve(ctx, candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to check the userName %s: %w", candidate, err)
+		}
+		if ok {
+			return candidate, nil
+		}
+	}
+	return "", ErrUserNameExhausted
+}
+
+// numberedUserName appends suffix to base, cutting base so that the result isn't longer than maxLength.
+func numberedUserName(base, suffix string, maxLength int) string {
+	if n := maxLength - len(suffix); len(base) > n {
+		if n < 0 {
+			n = 0
+		}
+		base = strings.TrimRight(base[:n], ".-_")
+	}
+	return base + suffix
+}
+
+// reserve checks the candidate and reserves it if it is available.
+func (gen *UserNameGenerator) reserve(ctx context.Context, candidate string) (bool, error) {
+	gen.mutex.Lock()
+	_, reserved := gen.reserved[candidate]
+	gen.mutex.Unlock()
+	if reserved {
+		return false, nil
+	}
+	if gen.checker != nil {
+		ok, err := gen.checker.UserNameAvailable(ctx, candidate)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	gen.mutex.Lock()
+	defer gen.mutex.Unlock()
+	if _, ok := gen.reserved[candidate]; ok {
+		return false, nil
+	}
+	gen.reserved[candidate] = struct{}{}
+	return true, nil
+}
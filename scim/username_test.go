@@ -0,0 +1,139 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeUserName(t *testing.T) {
+	data := []struct {
+		src       string
+		maxLength int
+		exp       string
+	}{
+		{src: "Alice", exp: "alice"},
+		{src: "José María", exp: "jose.maria"},
+		{src: "O'Brien-Smith", exp: "obrien-smith"},
+		{src: "Groß", exp: "gross"},
+		{src: "._alice_.", exp: "alice"},
+		{src: "山田", exp: ""},
+		{src: "abcdefghij", maxLength: 5, exp: "abcde"},
+		{src: "abcd.efgh", maxLength: 5, exp: "abcd"},
+	}
+	for _, d := range data {
+		require.Equal(t, d.exp, SanitizeUserName(d.src, d.maxLength), d.src)
+	}
+}
+
+func TestUserNameGenerator_Candidates(t *testing.T) {
+	gen := NewUserNameGenerator(nil, nil)
+	require.Equal(t, []string{"alice.smith", "asmith", "alices", "alice"}, gen.Candidates(&User{
+		Name: &Name{GivenName: "Alice", FamilyName: "Smith"},
+	}))
+	require.Equal(t, []string{"alice"}, gen.Candidates(&User{
+		Name: &Name{GivenName: "Alice"},
+	}))
+	require.Equal(t, []string{}, gen.Candidates(&User{}))
+
+	gen = NewUserNameGenerator(nil, &UserNameGeneratorConfig{
+		Patterns:  []string{"{family}_{given}", "{family}"},
+		MaxLength: 8,
+	})
+	require.Equal(t, []string{"smith_al", "smith"}, gen.Candidates(&User{
+		Name: &Name{GivenName: "Alice", FamilyName: "Smith"},
+	}))
+}
+
+func TestUserNameGenerator_Generate(t *testing.T) {
+	ctx := context.Background()
+	dir := NewDirectory([]User{
+		{ID: "U1", UserName: "alice.smith"},
+		{ID: "U2", UserName: "asmith"},
+	}, nil)
+	gen := NewUserNameGenerator(dir, &UserNameGeneratorConfig{
+		Patterns:       []string{"{given}.{family}", "{g}{family}"},
+		MaxNumber:      3,
+		RandomAttempts: 2,
+		Seed:           1,
+	})
+	alice := &User{Name: &Name{GivenName: "Alice", FamilyName: "Smith"}}
+	names := []string{}
+	for i := 0; i < 4; i++ {
+		name, err := gen.Generate(ctx, alice)
+		require.Nil(t, err)
+		names = append(names, name)
+	}
+	require.Equal(t, "alice.smith2", names[0])
+	require.Equal(t, "alice.smith3", names[1])
+	require.Regexp(t, `^alice\.smith\d{4}$`, names[2])
+	require.Regexp(t, `^alice\.smith\d{4}$`, names[3])
+	_, err := gen.Generate(ctx, alice)
+	require.True(t, errors.Is(err, ErrUserNameExhausted))
+
+	// deterministic for the seed
+	gen2 := NewUserNameGenerator(dir, &UserNameGeneratorConfig{
+		Patterns:       []string{"{given}.{family}", "{g}{family}"},
+		MaxNumber:      3,
+		RandomAttempts: 2,
+		Seed:           1,
+	})
+	for _, exp := range names {
+		name, err := gen2.Generate(ctx, alice)
+		require.Nil(t, err)
+		require.Equal(t, exp, name)
+	}
+
+	_, err = gen.Generate(ctx, nil)
+	require.NotNil(t, err)
+	_, err = gen.Generate(ctx, &User{})
+	require.NotNil(t, err)
+
+	gen = NewUserNameGenerator(UserNameCheckerFunc(func(ctx context.Context, userName string) (bool, error) {
+		return false, fmt.Errorf("unavailable")
+	}), nil)
+	_, err = gen.Generate(ctx, alice)
+	require.NotNil(t, err)
+
+	// the suffix is kept within MaxLength
+	gen = NewUserNameGenerator(UserNameCheckerFunc(func(ctx context.Context, userName string) (bool, error) {
+		return userName != "christopher.p", nil
+	}), &UserNameGeneratorConfig{
+		Patterns:  []string{"{given}.{family}"},
+		MaxLength: 13,
+	})
+	name, err := gen.Generate(ctx, &User{Name: &Name{GivenName: "Christopher", FamilyName: "Parker"}})
+	require.Nil(t, err)
+	require.Equal(t, "christopher2", name)
+}
+
+func TestClient_UserNameAvailable(t *testing.T) {
+	defer gock.Off()
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `userName eq "alice"`).
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1", "userName": "alice"}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		MatchParam("filter", `userName eq "alice2"`).
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+
+	gen := NewUserNameGenerator(client, &UserNameGeneratorConfig{
+		Patterns: []string{"{given}"},
+	})
+	name, err := gen.Generate(ctx, &User{Name: &Name{GivenName: "Alice"}})
+	require.Nil(t, err)
+	require.Equal(t, "alice2", name)
+	require.True(t, gock.IsDone())
+}
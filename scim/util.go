@@ -1,8 +1,11 @@
 package scim
 
 import (
+	"bufio"
 	"encoding/json"
+	"io"
 	"net/http"
+	"reflect"
 )
 
 // IsErrorDefault is a default function for client to judge the request is successful or not by the response.
@@ -28,3 +31,35 @@ func ParseErrorRespDefault(resp *http.Response) error {
 	}
 	return a.Errors
 }
+
+// isEmptyBody returns true if the response has no body.
+// The response body is replaced so that the body can be read from the beginning.
+func isEmptyBody(resp *http.Response) (bool, error) {
+	if resp.StatusCode == http.StatusNoContent || resp.Body == nil || resp.Body == http.NoBody {
+		return true, nil
+	}
+	br := bufio.NewReader(resp.Body)
+	if _, err := br.Peek(1); err != nil {
+		if err == io.EOF {
+			return true, nil
+		}
+		return false, err
+	}
+	resp.Body = &struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: br,
+		Closer: resp.Body,
+	}
+	return false, nil
+}
+
+// isNil returns true if v is nil or a nil pointer.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
@@ -72,3 +72,40 @@ func TestParseErrorRespDefault(t *testing.T) {
 		require.Equal(t, d.exp, ParseErrorRespDefault(resp))
 	}
 }
+
+func TestIsEmptyBody(t *testing.T) {
+	data := []struct {
+		statusCode int
+		body       string
+		exp        bool
+	}{
+		{
+			statusCode: 204,
+			exp:        true,
+		},
+		{
+			statusCode: 200,
+			body:       "",
+			exp:        true,
+		},
+		{
+			statusCode: 200,
+			body:       `{"id": "foo"}`,
+			exp:        false,
+		},
+	}
+	for _, d := range data {
+		resp := &http.Response{
+			StatusCode: d.statusCode,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(d.body)),
+		}
+		empty, err := isEmptyBody(resp)
+		require.Nil(t, err)
+		require.Equal(t, d.exp, empty)
+		if !empty {
+			b, err := ioutil.ReadAll(resp.Body)
+			require.Nil(t, err)
+			require.Equal(t, d.body, string(b))
+		}
+	}
+}
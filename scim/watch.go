@@ -0,0 +1,540 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EventUserCreated is the type of UserCreated.
+	EventUserCreated = "UserCreated"
+	// EventUserDeactivated is the type of UserDeactivated.
+	EventUserDeactivated = "UserDeactivated"
+	// EventUserReactivated is the type of UserReactivated.
+	EventUserReactivated = "UserReactivated"
+	// EventUserRemoved is the type of UserRemoved.
+	EventUserRemoved = "UserRemoved"
+	// EventUserAttributeChanged is the type of UserAttributeChanged.
+	EventUserAttributeChanged = "UserAttributeChanged"
+	// EventGroupCreated is the type of GroupCreated.
+	EventGroupCreated = "GroupCreated"
+	// EventGroupRemoved is the type of GroupRemoved.
+	EventGroupRemoved = "GroupRemoved"
+	// EventGroupAttributeChanged is the type of GroupAttributeChanged.
+	EventGroupAttributeChanged = "GroupAttributeChanged"
+	// EventGroupMembershipChanged is the type of GroupMembershipChanged.
+	EventGroupMembershipChanged = "GroupMembershipChanged"
+
+	// DefaultWatchInterval is the default interval of Watcher .
+	DefaultWatchInterval = 5 * time.Minute
+)
+
+type (
+	// WatchEvent is an event emitted by Watcher.
+	// The concrete type is one of *UserCreated, *UserDeactivated, *UserReactivated, *UserRemoved, *UserAttributeChanged,
+	// *GroupCreated, *GroupRemoved, *GroupAttributeChanged and *GroupMembershipChanged .
+	WatchEvent interface {
+		EventType() string
+	}
+
+	// WatchHandler handles an event.
+	WatchHandler func(ctx context.Context, event WatchEvent)
+
+	// UserCreated is emitted when a user appears.
+	UserCreated struct {
+		User *User
+	}
+
+	// UserDeactivated is emitted when a user is deactivated.
+	UserDeactivated struct {
+		User *User
+	}
+
+	// UserReactivated is emitted when a deactivated user is activated.
+	UserReactivated struct {
+		User *User
+	}
+
+	// UserRemoved is emitted when a user disappears from the list.
+	UserRemoved struct {
+		UserID string
+	}
+
+	// UserAttributeChanged is emitted for each changed attribute of a user.
+	// Field is an attribute path of LookupAttribute such as "title" and "name.givenName".
+	// Multi-valued attributes such as "emails" are compared as a whole.
+	// Old is nil if the attribute is added, and New is nil if the attribute is removed.
+	UserAttributeChanged struct {
+		UserID string
+		Field  string
+		Old    interface{}
+		New    interface{}
+	}
+
+	// GroupCreated is emitted when a group appears.
+	GroupCreated struct {
+		Group *Group
+	}
+
+	// GroupRemoved is emitted when a group disappears.
+	GroupRemoved struct {
+		GroupID string
+	}
+
+	// GroupAttributeChanged is emitted when displayName or externalId of a group is changed.
+	GroupAttributeChanged struct {
+		GroupID string
+		Field   string
+		Old     interface{}
+		New     interface{}
+	}
+
+	// GroupMembershipChanged is emitted when members are added to or removed from a group.
+	GroupMembershipChanged struct {
+		GroupID string
+		// Added and Removed are sorted ids of users.
+		Added   []string
+		Removed []string
+	}
+
+	// WatchState is a snapshot of users and groups compared by Watcher.
+	// WatchState is encoded to JSON to persist it between runs.
+	WatchState struct {
+		Time   time.Time `json:"time"`
+		Users  []User    `json:"users"`
+		Groups []Group   `json:"groups"`
+	}
+
+	// WatcherOption is an option of NewWatcher .
+	WatcherOption struct {
+		// Interval is the interval of polling. If Interval is zero, DefaultWatchInterval is used.
+		Interval time.Duration
+		// StatePath is a path of the file to persist the state.
+		// If StatePath isn't empty, the state is loaded when the watcher is created and saved after each poll.
+		StatePath string
+		// If IgnoreGroups is true, groups aren't listed.
+		IgnoreGroups bool
+		// OnError is called when a poll fails in Watcher.Run . The watcher continues polling.
+		OnError func(err error)
+	}
+
+	// Watcher polls users and groups and emits events of their changes to subscribers.
+	// Slack's SCIM API has no webhooks, so changes are detected by comparing lists with the previous state.
+	// The first poll without a previous state records the state without events.
+	// Watcher should be created by the function NewWatcher .
+	Watcher struct {
+		client   *Client
+		opts     WatcherOption
+		state    *WatchState
+		handlers []WatchHandler
+		now      func() time.Time
+		mutex    sync.Mutex
+	}
+)
+
+// EventType returns EventUserCreated .
+func (e *UserCreated) EventType() string { return EventUserCreated }
+
+// EventType returns EventUserDeactivated .
+func (e *UserDeactivated) EventType() string { return EventUserDeactivated }
+
+// EventType returns EventUserReactivated .
+func (e *UserReactivated) EventType() string { return EventUserReactivated }
+
+// EventType returns EventUserRemoved .
+func (e *UserRemoved) EventType() string { return EventUserRemoved }
+
+// EventType returns EventUserAttributeChanged .
+func (e *UserAttributeChanged) EventType() string { return EventUserAttributeChanged }
+
+// EventType returns EventGroupCreated .
+func (e *GroupCreated) EventType() string { return EventGroupCreated }
+
+// EventType returns EventGroupRemoved .
+func (e *GroupRemoved) EventType() string { return EventGroupRemoved }
+
+// EventType returns EventGroupAttributeChanged .
+func (e *GroupAttributeChanged) EventType() string { return EventGroupAttributeChanged }
+
+// EventType returns EventGroupMembershipChanged .
+func (e *GroupMembershipChanged) EventType() string { return EventGroupMembershipChanged }
+
+// ReadWatchState reads a JSON state from r.
+func ReadWatchState(r io.Reader) (*WatchState, error) {
+	state := &WatchState{}
+	if err := json.NewDecoder(r).Decode(state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// WriteJSON writes the state in JSON.
+func (state *WatchState) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(state)
+}
+
+// DiffWatchStates compares the states before and after and returns events of the changes.
+// Users are compared before groups, and events are sorted by ids.
+// If both old and new users have the same Meta.LastModified, the user is regarded as unchanged.
+func DiffWatchStates(before, after *WatchState) ([]WatchEvent, error) {
+	events := []WatchEvent{}
+	oldUsers := make(map[string]*User, len(before.Users))
+	for i := range before.Users {
+		oldUsers[before.Users[i].ID] = &before.Users[i]
+	}
+	newUsers := make(map[string]*User, len(after.Users))
+	for i := range after.Users {
+		user := &after.Users[i]
+		newUsers[user.ID] = user
+	}
+	for _, id := range sortedUserIDs(newUsers) {
+		user := newUsers[id]
+		prev, ok := oldUsers[id]
+		if !ok {
+			events = append(events, &UserCreated{User: user})
+			continue
+		}
+		if lastModified(prev.Meta) != "" && lastModified(prev.Meta) == lastModified(user.Meta) {
+			continue
+		}
+		changes, err := diffUserAttributes(prev, user)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, changes...)
+		switch {
+		case prev.Active && !user.Active:
+			events = append(events, &UserDeactivated{User: user})
+		case !prev.Active && user.Active:
+			events = append(events, &UserReactivated{User: user})
+		}
+	}
+	for _, id := range sortedUserIDs(oldUsers) {
+		if _, ok := newUsers[id]; !ok {
+			events = append(events, &UserRemoved{UserID: id})
+		}
+	}
+	return append(events, diffWatchGroups(before.Groups, after.Groups)...), nil
+}
+
+func sortedUserIDs(users map[string]*User) []string {
+	ids := make([]string, 0, len(users))
+	for id := range users {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func lastModified(meta *Meta) string {
+	if meta == nil {
+		return ""
+	}
+	return meta.LastModified
+}
+
+// watchIgnoredAttributes are attributes which aren't reported by UserAttributeChanged.
+// active is reported by UserDeactivated and UserReactivated, and groups are reported by GroupMembershipChanged.
+var watchIgnoredAttributes = map[string]struct{}{
+	"active": {},
+	"groups": {},
+	"meta":   {},
+}
+
+func diffUserAttributes(before, after *User) ([]WatchEvent, error) {
+	oldAttrs, err := UserAttributes(before)
+	if err != nil {
+		return nil, err
+	}
+	newAttrs, err := UserAttributes(after)
+	if err != nil {
+		return nil, err
+	}
+	oldFields := map[string]interface{}{}
+	flattenAttributes("", oldAttrs, oldFields)
+	newFields := map[string]interface{}{}
+	flattenAttributes("", newAttrs, newFields)
+	names := []string{}
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	events := []WatchEvent{}
+	for _, name := range names {
+		o, n := oldFields[name], newFields[name]
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		events = append(events, &UserAttributeChanged{
+			UserID: after.ID,
+			Field:  name,
+			Old:    o,
+			New:    n,
+		})
+	}
+	return events, nil
+}
+
+// flattenAttributes flattens nested complex attributes to dot separated paths.
+// Multi-valued attributes aren't flattened.
+func flattenAttributes(prefix string, attrs map[string]interface{}, fields map[string]interface{}) {
+	for k, v := range attrs {
+		if prefix == "" {
+			if _, ok := watchIgnoredAttributes[strings.ToLower(k)]; ok {
+				continue
+			}
+		}
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			flattenAttributes(name, m, fields)
+			continue
+		}
+		fields[name] = v
+	}
+}
+
+func diffWatchGroups(before, after []Group) []WatchEvent {
+	events := []WatchEvent{}
+	oldGroups := make(map[string]*Group, len(before))
+	for i := range before {
+		oldGroups[before[i].ID] = &before[i]
+	}
+	newGroups := make(map[string]*Group, len(after))
+	ids := make([]string, 0, len(after))
+	for i := range after {
+		newGroups[after[i].ID] = &after[i]
+		ids = append(ids, after[i].ID)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		group := newGroups[id]
+		prev, ok := oldGroups[id]
+		if !ok {
+			events = append(events, &GroupCreated{Group: group})
+			continue
+		}
+		if prev.DisplayName != group.DisplayName {
+			events = append(events, &GroupAttributeChanged{
+				GroupID: id, Field: "displayName", Old: prev.DisplayName, New: group.DisplayName,
+			})
+		}
+		if prev.ExternalID != group.ExternalID {
+			events = append(events, &GroupAttributeChanged{
+				GroupID: id, Field: "externalId", Old: prev.ExternalID, New: group.ExternalID,
+			})
+		}
+		if added, removed := diffMembers(prev.Members, group.Members); len(added) != 0 || len(removed) != 0 {
+			events = append(events, &GroupMembershipChanged{
+				GroupID: id,
+				Added:   added,
+				Removed: removed,
+			})
+		}
+	}
+	removed := []string{}
+	for id := range oldGroups {
+		if _, ok := newGroups[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		events = append(events, &GroupRemoved{GroupID: id})
+	}
+	return events
+}
+
+// diffMembers returns sorted ids of members who are added and removed.
+func diffMembers(before, after []Member) ([]string, []string) {
+	oldIDs := make(map[string]struct{}, len(before))
+	for _, member := range before {
+		oldIDs[member.Value] = struct{}{}
+	}
+	newIDs := make(map[string]struct{}, len(after))
+	for _, member := range after {
+		newIDs[member.Value] = struct{}{}
+	}
+	added := []string{}
+	for id := range newIDs {
+		if _, ok := oldIDs[id]; !ok {
+			added = append(added, id)
+		}
+	}
+	removed := []string{}
+	for id := range oldIDs {
+		if _, ok := newIDs[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// NewWatcher returns a watcher. If opts.StatePath exists, the state is loaded from the file.
+func NewWatcher(client *Client, opts *WatcherOption) (*Watcher, error) {
+	if client == nil {
+		return nil, fmt.Errorf("client is required")
+	}
+	if opts == nil {
+		opts = &WatcherOption{}
+	}
+	watcher := &Watcher{
+		client: client,
+		opts:   *opts,
+		now:    time.Now,
+	}
+	if watcher.opts.Interval <= 0 {
+		watcher.opts.Interval = DefaultWatchInterval
+	}
+	if opts.StatePath == "" {
+		return watcher, nil
+	}
+	f, err := os.Open(opts.StatePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return watcher, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	state, err := ReadWatchState(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state %s: %w", opts.StatePath, err)
+	}
+	watcher.state = state
+	return watcher, nil
+}
+
+// Subscribe adds the handler. Handlers are called in order of subscription for each event.
+func (watcher *Watcher) Subscribe(handler WatchHandler) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	watcher.handlers = append(watcher.handlers, handler)
+}
+
+// SubscribeChannel subscribes a channel of events.
+// Sending events blocks the watcher until they are received or ctx of the poll is canceled.
+func (watcher *Watcher) SubscribeChannel(ch chan<- WatchEvent) {
+	watcher.Subscribe(func(ctx context.Context, event WatchEvent) {
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// State returns the current state. If no poll has been done, nil is returned.
+func (watcher *Watcher) State() *WatchState {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	return watcher.state
+}
+
+// Poll lists users and groups once, emits events of changes from the previous state to subscribers,
+// and saves the state.
+// The returned events are the events emitted in this poll.
+// The state is saved after all events are dispatched, so events are delivered at least once:
+// if the state can't be saved, or ctx is canceled while events are dispatched,
+// the state isn't updated and the events are emitted again by the next poll.
+func (watcher *Watcher) Poll(ctx context.Context) ([]WatchEvent, error) {
+	users, err := watcher.client.GetAllUsers(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	groups := []Group{}
+	if !watcher.opts.IgnoreGroups {
+		groups, err = watcher.client.GetAllGroups(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get groups: %w", err)
+		}
+	}
+	state := &WatchState{
+		Time:   watcher.now().UTC(),
+		Users:  users,
+		Groups: groups,
+	}
+
+	watcher.mutex.Lock()
+	prev := watcher.state
+	handlers := make([]WatchHandler, len(watcher.handlers))
+	copy(handlers, watcher.handlers)
+	watcher.mutex.Unlock()
+
+	events := []WatchEvent{}
+	if prev != nil {
+		events, err = DiffWatchStates(prev, state)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the state is saved after events are dispatched, so events aren't lost even if the process crashes while dispatching.
+	for _, event := range events {
+		for _, handler := range handlers {
+			handler(ctx, event)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return events, fmt.Errorf("the poll is canceled while events are dispatched: %w", err)
+	}
+	if watcher.opts.StatePath != "" {
+		if err := writeWatchState(watcher.opts.StatePath, state); err != nil {
+			return events, fmt.Errorf("failed to save the state: %w", err)
+		}
+	}
+	watcher.mutex.Lock()
+	watcher.state = state
+	watcher.mutex.Unlock()
+	return events, nil
+}
+
+// writeWatchState writes the state to a temporary file and renames it, so the file isn't broken by a crash.
+func writeWatchState(path string, state *WatchState) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := state.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Run polls at the interval until ctx is canceled, and returns the error of ctx.
+// The first poll is done immediately.
+// Errors of polls are passed to WatcherOption.OnError, and the watcher continues polling.
+func (watcher *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(watcher.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := watcher.Poll(ctx); err != nil && ctx.Err() == nil && watcher.opts.OnError != nil {
+			watcher.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
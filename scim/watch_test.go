@@ -0,0 +1,195 @@
+package scim
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffWatchStates(t *testing.T) {
+	before := &WatchState{
+		Users: []User{
+			{ID: "U1", UserName: "alice", Active: true, Title: "Engineer", Meta: &Meta{LastModified: "2020-01-01"}},
+			{ID: "U2", UserName: "bob", Active: true, Name: &Name{GivenName: "Bob"}},
+			{ID: "U3", UserName: "carol", Active: true, Title: "Manager", Meta: &Meta{LastModified: "2020-01-01"}},
+			{ID: "U4", UserName: "dave"},
+		},
+		Groups: []Group{
+			{ID: "G1", DisplayName: "eng", Members: []Member{{Value: "U1"}, {Value: "U2"}}},
+			{ID: "G2", DisplayName: "sales"},
+		},
+	}
+	after := &WatchState{
+		Users: []User{
+			// unchanged because lastModified is the same
+			{ID: "U1", UserName: "alice", Active: true, Title: "Senior Engineer", Meta: &Meta{LastModified: "2020-01-01"}},
+			{ID: "U2", UserName: "bob", Name: &Name{GivenName: "Robert"}, Title: "Engineer"},
+			{ID: "U4", UserName: "dave", Active: true},
+			{ID: "U5", UserName: "eve", Active: true},
+		},
+		Groups: []Group{
+			{ID: "G1", DisplayName: "engineering", Members: []Member{{Value: "U1"}, {Value: "U5"}}},
+			{ID: "G3", DisplayName: "ops"},
+		},
+	}
+	events, err := DiffWatchStates(before, after)
+	require.Nil(t, err)
+	require.Equal(t, []WatchEvent{
+		&UserAttributeChanged{UserID: "U2", Field: "name.givenName", Old: "Bob", New: "Robert"},
+		&UserAttributeChanged{UserID: "U2", Field: "title", Old: nil, New: "Engineer"},
+		&UserDeactivated{User: &after.Users[1]},
+		&UserReactivated{User: &after.Users[2]},
+		&UserCreated{User: &after.Users[3]},
+		&UserRemoved{UserID: "U3"},
+		&GroupAttributeChanged{GroupID: "G1", Field: "displayName", Old: "eng", New: "engineering"},
+		&GroupMembershipChanged{GroupID: "G1", Added: []string{"U5"}, Removed: []string{"U2"}},
+		&GroupCreated{Group: &after.Groups[1]},
+		&GroupRemoved{GroupID: "G2"},
+	}, events)
+	require.Equal(t, EventUserAttributeChanged, events[0].EventType())
+	require.Equal(t, EventGroupRemoved, events[9].EventType())
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	ctx := context.Background()
+	client := NewClient("XXX")
+	watcher, err := NewWatcher(client, &WatcherOption{StatePath: path})
+	require.Nil(t, err)
+	require.Nil(t, watcher.State())
+	received := []WatchEvent{}
+	watcher.Subscribe(func(ctx context.Context, event WatchEvent) {
+		received = append(received, event)
+	})
+	ch := make(chan WatchEvent, 10)
+	watcher.SubscribeChannel(ch)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1", "active": true}]}`)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Groups").
+		Reply(200).
+		BodyString(`{"totalResults": 0, "Resources": []}`)
+	// the first poll records the state without events
+	events, err := watcher.Poll(ctx)
+	require.Nil(t, err)
+	require.Empty(t, events)
+	require.True(t, gock.IsDone())
+
+	// the state is loaded from the file
+	watcher, err = NewWatcher(client, &WatcherOption{StatePath: path, IgnoreGroups: true})
+	require.Nil(t, err)
+	require.Len(t, watcher.State().Users, 1)
+	watcher.Subscribe(func(ctx context.Context, event WatchEvent) {
+		received = append(received, event)
+	})
+	watcher.SubscribeChannel(ch)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1"}]}`)
+	events, err = watcher.Poll(ctx)
+	require.Nil(t, err)
+	require.True(t, gock.IsDone())
+	require.Len(t, events, 1)
+	require.Equal(t, EventUserDeactivated, events[0].EventType())
+	require.Equal(t, events, received)
+	require.Equal(t, events[0], <-ch)
+
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(500)
+	_, err = watcher.Poll(ctx)
+	require.NotNil(t, err)
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("foo"), 0600))
+	_, err = NewWatcher(client, &WatcherOption{StatePath: path})
+	require.NotNil(t, err)
+	_, err = NewWatcher(nil, nil)
+	require.NotNil(t, err)
+}
+
+func TestWatcher_Poll_atLeastOnce(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	client := NewClient("XXX")
+	watcher, err := NewWatcher(client, &WatcherOption{StatePath: path, IgnoreGroups: true})
+	require.Nil(t, err)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1", "active": true}]}`)
+	_, err = watcher.Poll(context.Background())
+	require.Nil(t, err)
+
+	// the process is stopped while the event is dispatched
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher.Subscribe(func(ctx context.Context, event WatchEvent) {
+		cancel()
+	})
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1"}]}`)
+	events, err := watcher.Poll(ctx)
+	require.NotNil(t, err)
+	require.Len(t, events, 1)
+	require.True(t, gock.IsDone())
+
+	// the state isn't saved, so the event is emitted again
+	watcher, err = NewWatcher(client, &WatcherOption{StatePath: path, IgnoreGroups: true})
+	require.Nil(t, err)
+	require.True(t, watcher.State().Users[0].Active)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(200).
+		BodyString(`{"totalResults": 1, "Resources": [{"id": "U1"}]}`)
+	events, err = watcher.Poll(context.Background())
+	require.Nil(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, EventUserDeactivated, events[0].EventType())
+	require.False(t, watcher.State().Users[0].Active)
+}
+
+func TestWatcher_Run(t *testing.T) {
+	defer gock.Off()
+
+	client := NewClient("XXX")
+	errs := []error{}
+	watcher, err := NewWatcher(client, &WatcherOption{
+		Interval:     time.Hour,
+		IgnoreGroups: true,
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	require.Nil(t, err)
+	gock.New("https://api.slack.com").
+		Get("/scim/v1/Users").
+		Reply(500)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, watcher.Run(ctx))
+	require.Len(t, errs, 1)
+}
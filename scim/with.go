@@ -7,11 +7,16 @@ import (
 func (c *Client) copy() *Client {
 	return &Client{
 		endpoint:       c.endpoint,
-		token:          c.token,
+		tokenSource:    c.tokenSource,
 		httpClient:     c.httpClient,
 		isError:        c.isError,
 		parseResp:      c.parseResp,
 		parseErrorResp: c.parseErrorResp,
+		mutationHook:   c.mutationHook,
+		mutationGuard:  c.mutationGuard,
+		metricsHook:    c.metricsHook,
+		tracer:         c.tracer,
+		requestLogger:  c.requestLogger,
 	}
 }
 
@@ -71,3 +76,54 @@ func (c *Client) WithEndpoint(endpoint string) *Client {
 	cl.endpoint = endpoint
 	return cl
 }
+
+// WithTokenSource returns a shallow copy of c with its tokenSource changed to src.
+// If src is nil, an empty token is used.
+func (c *Client) WithTokenSource(src TokenSource) *Client {
+	if src == nil {
+		src = NewStaticTokenSource("")
+	}
+	cl := c.copy()
+	cl.tokenSource = src
+	return cl
+}
+
+// WithMutationHook returns a shallow copy of c with its mutationHook changed to hook.
+// If hook is nil, no hook is called.
+func (c *Client) WithMutationHook(hook MutationHook) *Client {
+	cl := c.copy()
+	cl.mutationHook = hook
+	return cl
+}
+
+// WithMutationGuard returns a shallow copy of c with its mutationGuard changed to guard.
+// If guard is nil, no guard is called.
+func (c *Client) WithMutationGuard(guard MutationGuard) *Client {
+	cl := c.copy()
+	cl.mutationGuard = guard
+	return cl
+}
+
+// WithMetricsHook returns a shallow copy of c with its metricsHook changed to hook.
+// If hook is nil, no metrics are recorded.
+func (c *Client) WithMetricsHook(hook MetricsHook) *Client {
+	cl := c.copy()
+	cl.metricsHook = hook
+	return cl
+}
+
+// WithTracer returns a shallow copy of c with its tracer changed to tracer.
+// If tracer is nil, no span is created.
+func (c *Client) WithTracer(tracer Tracer) *Client {
+	cl := c.copy()
+	cl.tracer = tracer
+	return cl
+}
+
+// WithRequestLogger returns a shallow copy of c with its requestLogger changed to logger.
+// If logger is nil, no request is logged.
+func (c *Client) WithRequestLogger(logger *RequestLogger) *Client {
+	cl := c.copy()
+	cl.requestLogger = logger
+	return cl
+}
@@ -1,6 +1,7 @@
 package scim
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -10,16 +11,16 @@ import (
 
 func TestClient_copy(t *testing.T) {
 	c := &Client{
-		endpoint: "endpoint",
-		token:    "token",
+		endpoint:    "endpoint",
+		tokenSource: NewStaticTokenSource("token"),
 	}
 
 	c2 := c.copy()
 	require.Equal(t, c, c2)
 	c2.endpoint = "change"
-	c2.token = "change"
+	c2.tokenSource = NewStaticTokenSource("change")
 	require.NotEqual(t, c2.endpoint, c.endpoint)
-	require.NotEqual(t, c2.token, c.token)
+	require.NotEqual(t, c2.tokenSource, c.tokenSource)
 }
 
 func TestClient_WithHTTPClient(t *testing.T) {
@@ -69,3 +70,56 @@ func TestClient_WithEndpoint(t *testing.T) {
 	require.Equal(t, "", c.endpoint)
 	require.NotNil(t, ep, c2.endpoint)
 }
+
+func TestClient_WithTokenSource(t *testing.T) {
+	c := &Client{}
+
+	c2 := c.WithTokenSource(nil)
+	require.Nil(t, c.tokenSource)
+	require.NotNil(t, c2.tokenSource)
+	src := NewStaticTokenSource("token")
+	c3 := c.WithTokenSource(src)
+	require.Equal(t, src, c3.tokenSource)
+}
+
+func TestClient_WithMutationHook(t *testing.T) {
+	c := &Client{}
+
+	c2 := c.WithMutationHook(func(ctx context.Context, mutation *Mutation) {})
+	require.Nil(t, c.mutationHook)
+	require.NotNil(t, c2.mutationHook)
+}
+
+func TestClient_WithMutationGuard(t *testing.T) {
+	c := &Client{}
+
+	c2 := c.WithMutationGuard(func(ctx context.Context, mutation *Mutation) error {
+		return nil
+	})
+	require.Nil(t, c.mutationGuard)
+	require.NotNil(t, c2.mutationGuard)
+}
+
+func TestClient_WithMetricsHook(t *testing.T) {
+	c := &Client{}
+
+	c2 := c.WithMetricsHook(NewPrometheusMetrics(nil))
+	require.Nil(t, c.metricsHook)
+	require.NotNil(t, c2.metricsHook)
+}
+
+func TestClient_WithTracer(t *testing.T) {
+	c := &Client{}
+
+	c2 := c.WithTracer(&testTracer{})
+	require.Nil(t, c.tracer)
+	require.NotNil(t, c2.tracer)
+}
+
+func TestClient_WithRequestLogger(t *testing.T) {
+	c := &Client{}
+
+	c2 := c.WithRequestLogger(NewRequestLogger(nil, nil))
+	require.Nil(t, c.requestLogger)
+	require.NotNil(t, c2.requestLogger)
+}